@@ -3,7 +3,6 @@ package cardano
 import (
 	"github.com/echovl/bech32"
 	"github.com/qredo/cardano-go/crypto"
-	"golang.org/x/crypto/blake2b"
 )
 
 type Network byte
@@ -37,19 +36,61 @@ func DecodeAddress(data []byte) (Address, Address, error) {
 	return Address(mainnet), Address(testnet), nil
 }
 
+// addressHashSize is the size in bytes of the key or script hash of an
+// address credential.
+const addressHashSize = 28
+
+// Address header types, see CIP-19.
+const (
+	baseKeyKeyHeader       byte = 0x00
+	baseScriptKeyHeader    byte = 0x10
+	enterpriseKeyHeader    byte = 0x60
+	enterpriseScriptHeader byte = 0x70
+	stakeKeyHeader         byte = 0xe0
+)
+
 func NewEnterpriseAddress(xvk crypto.ExtendedVerificationKey, network Network) Address {
-	addressBytes := make([]byte, 29)
-	header := 0x60 | (byte(network) & 0xFF)
-	hash, err := blake2b.New(224/8, nil)
+	return newAddress(enterpriseKeyHeader, network, keyHash(xvk))
+}
+
+// NewScriptEnterpriseAddress returns an address without staking rights whose
+// funds are locked by the given script.
+func NewScriptEnterpriseAddress(script PlutusScript, network Network) Address {
+	return newAddress(enterpriseScriptHeader, network, script.Hash())
+}
+
+// NewBaseAddress returns an address paying to the payment key and delegating
+// to the stake key.
+func NewBaseAddress(xvk, stakeXvk crypto.ExtendedVerificationKey, network Network) Address {
+	return newAddress(baseKeyKeyHeader, network, keyHash(xvk), keyHash(stakeXvk))
+}
+
+// NewScriptBaseAddress returns an address whose funds are locked by the given
+// script and delegated using the stake key.
+func NewScriptBaseAddress(script PlutusScript, stakeXvk crypto.ExtendedVerificationKey, network Network) Address {
+	return newAddress(baseScriptKeyHeader, network, script.Hash(), keyHash(stakeXvk))
+}
+
+// NewStakeAddress returns the reward address of the stake key, which
+// receives its staking rewards.
+func NewStakeAddress(stakeXvk crypto.ExtendedVerificationKey, network Network) Address {
+	hrp := "stake"
+	if network == Testnet {
+		hrp = "stake_test"
+	}
+	addressBytes := append([]byte{stakeKeyHeader | (byte(network) & 0x0F)}, keyHash(stakeXvk)...)
+	address, err := bech32.EncodeFromBase256(hrp, addressBytes)
 	if err != nil {
 		panic(err)
 	}
+	return Address(address)
+}
 
-	hash.Write(xvk[:32])
-	paymentHash := hash.Sum(nil)
-
-	addressBytes[0] = header
-	copy(addressBytes[1:], paymentHash)
+func newAddress(header byte, network Network, hashes ...[]byte) Address {
+	addressBytes := []byte{header | (byte(network) & 0x0F)}
+	for _, hash := range hashes {
+		addressBytes = append(addressBytes, hash...)
+	}
 
 	hrp := getHrp(network)
 	address, err := bech32.EncodeFromBase256(hrp, addressBytes)
@@ -0,0 +1,220 @@
+package cardano
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// MultiAsset maps hex encoded policy IDs to hex encoded asset names and
+// their quantities.
+type MultiAsset map[string]map[string]uint64
+
+// NewMultiAsset returns a MultiAsset holding a single asset.
+func NewMultiAsset(policyID, assetName string, quantity uint64) MultiAsset {
+	return MultiAsset{policyID: {assetName: quantity}}
+}
+
+// Quantity returns the quantity of the given asset.
+func (ma MultiAsset) Quantity(policyID, assetName string) uint64 {
+	return ma[policyID][assetName]
+}
+
+// Add returns the sum of both multi assets.
+func (ma MultiAsset) Add(other MultiAsset) MultiAsset {
+	sum := ma.Copy()
+	for policyID, assets := range other {
+		for assetName, quantity := range assets {
+			sum.set(policyID, assetName, sum.Quantity(policyID, assetName)+quantity)
+		}
+	}
+	return sum
+}
+
+// Sub returns the difference of both multi assets. It returns false if any
+// quantity of other is greater than the one in ma.
+func (ma MultiAsset) Sub(other MultiAsset) (MultiAsset, bool) {
+	diff := ma.Copy()
+	for policyID, assets := range other {
+		for assetName, quantity := range assets {
+			current := diff.Quantity(policyID, assetName)
+			if quantity > current {
+				return nil, false
+			}
+			diff.set(policyID, assetName, current-quantity)
+		}
+	}
+	return diff, true
+}
+
+// Equal reports whether both multi assets hold the same quantities.
+func (ma MultiAsset) Equal(other MultiAsset) bool {
+	if _, ok := ma.Sub(other); !ok {
+		return false
+	}
+	diff, ok := other.Sub(ma)
+	return ok && diff.IsEmpty()
+}
+
+// IsEmpty reports whether the multi asset holds no assets.
+func (ma MultiAsset) IsEmpty() bool {
+	return len(ma) == 0
+}
+
+// Copy returns a deep copy of the multi asset.
+func (ma MultiAsset) Copy() MultiAsset {
+	cp := MultiAsset{}
+	for policyID, assets := range ma {
+		for assetName, quantity := range assets {
+			cp.set(policyID, assetName, quantity)
+		}
+	}
+	return cp
+}
+
+// set sets the quantity of an asset, removing it if the quantity is zero.
+func (ma MultiAsset) set(policyID, assetName string, quantity uint64) {
+	if quantity == 0 {
+		delete(ma[policyID], assetName)
+		if len(ma[policyID]) == 0 {
+			delete(ma, policyID)
+		}
+		return
+	}
+	if ma[policyID] == nil {
+		ma[policyID] = map[string]uint64{}
+	}
+	ma[policyID][assetName] = quantity
+}
+
+func (ma MultiAsset) MarshalCBOR() ([]byte, error) {
+	policyIDs := sortedKeys(ma)
+	out := cborHeader(cborMajorMap, uint64(len(policyIDs)))
+	for _, policyID := range policyIDs {
+		key, err := marshalHexKey(policyID)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, key...)
+
+		assets := ma[policyID]
+		assetNames := make([]string, 0, len(assets))
+		for assetName := range assets {
+			assetNames = append(assetNames, assetName)
+		}
+		sort.Strings(assetNames)
+		out = append(out, cborHeader(cborMajorMap, uint64(len(assetNames)))...)
+		for _, assetName := range assetNames {
+			key, err := marshalHexKey(assetName)
+			if err != nil {
+				return nil, err
+			}
+			value, err := cbor.Marshal(assets[assetName])
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, key...)
+			out = append(out, value...)
+		}
+	}
+	return out, nil
+}
+
+func (ma *MultiAsset) UnmarshalCBOR(data []byte) error {
+	*ma = MultiAsset{}
+	return decodeCborMap(data, func(key, value cbor.RawMessage) error {
+		var policyID []byte
+		if err := cbor.Unmarshal(key, &policyID); err != nil {
+			return err
+		}
+		return decodeCborMap(value, func(key, value cbor.RawMessage) error {
+			var assetName []byte
+			if err := cbor.Unmarshal(key, &assetName); err != nil {
+				return err
+			}
+			var quantity uint64
+			if err := cbor.Unmarshal(value, &quantity); err != nil {
+				return err
+			}
+			ma.set(hex.EncodeToString(policyID), hex.EncodeToString(assetName), quantity)
+			return nil
+		})
+	})
+}
+
+// Value is an amount of lovelace and native assets.
+type Value struct {
+	Coin   uint64
+	Assets MultiAsset
+}
+
+// UnmarshalCBOR decodes a value encoded either as a coin or as a
+// [coin, multiasset] pair.
+func (v *Value) UnmarshalCBOR(data []byte) error {
+	*v = Value{}
+	if cborMajor(data) != cborMajorArray {
+		return cbor.Unmarshal(data, &v.Coin)
+	}
+	value := struct {
+		_      struct{} `cbor:",toarray"`
+		Coin   uint64
+		Assets MultiAsset
+	}{}
+	if err := cbor.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	v.Coin = value.Coin
+	if !value.Assets.IsEmpty() {
+		v.Assets = value.Assets
+	}
+	return nil
+}
+
+func (v Value) String() string {
+	if v.Assets.IsEmpty() {
+		return fmt.Sprintf("%v lovelace", v.Coin)
+	}
+	return fmt.Sprintf("%v lovelace and assets %v", v.Coin, map[string]map[string]uint64(v.Assets))
+}
+
+// Add returns the sum of both values.
+func (v Value) Add(other Value) Value {
+	return Value{Coin: v.Coin + other.Coin, Assets: v.Assets.Add(other.Assets)}
+}
+
+// Sub returns the difference of both values. It returns false if other is
+// not contained in v.
+func (v Value) Sub(other Value) (Value, bool) {
+	if other.Coin > v.Coin {
+		return Value{}, false
+	}
+	assets, ok := v.Assets.Sub(other.Assets)
+	if !ok {
+		return Value{}, false
+	}
+	return Value{Coin: v.Coin - other.Coin, Assets: assets}, true
+}
+
+// Equal reports whether both values hold the same amounts.
+func (v Value) Equal(other Value) bool {
+	return v.Coin == other.Coin && v.Assets.Equal(other.Assets)
+}
+
+func marshalHexKey(key string) ([]byte, error) {
+	bytes, err := hex.DecodeString(key)
+	if err != nil {
+		return nil, err
+	}
+	return cbor.Marshal(bytes)
+}
+
+func sortedKeys(ma MultiAsset) []string {
+	keys := make([]string, 0, len(ma))
+	for key := range ma {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,70 @@
+package cardano
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNotSupported is returned by a ChainBackend that can't answer a query.
+var ErrNotSupported = errors.New("operation not supported by the backend")
+
+// ChainBackend provides access to the blockchain. The Client uses cardano-cli
+// as its default backend, other implementations can be set with WithNode.
+type ChainBackend interface {
+	// QueryUtxos returns the unspent outputs locked by the address.
+	QueryUtxos(ctx context.Context, addr Address) ([]Utxo, error)
+	// QueryTip returns the tip of the chain.
+	QueryTip(ctx context.Context) (NodeTip, error)
+	// QueryProtocolParams returns the current protocol parameters.
+	QueryProtocolParams(ctx context.Context) (ProtocolParams, error)
+	// QueryTxStatus returns whether the transaction is included in the chain.
+	QueryTxStatus(ctx context.Context, txId TransactionID) (TxStatus, error)
+	// QueryRewardAccount returns the state of the reward account of the stake address.
+	QueryRewardAccount(ctx context.Context, stakeAddr Address) (RewardAccount, error)
+	// SubmitTx submits the transaction to the network and returns its id.
+	SubmitTx(ctx context.Context, tx Transaction) (TransactionID, error)
+}
+
+// NetworkBackend is implemented by the backends bound to a network, like a
+// node socket, whose wallets must then be of the same network.
+type NetworkBackend interface {
+	// Network returns the network of the backend.
+	Network() Network
+}
+
+// AddressUsageQuerier is implemented by the backends that can tell whether an
+// address ever appeared on chain, even if its outputs were all spent.
+type AddressUsageQuerier interface {
+	// QueryAddressUsed returns whether a transaction ever paid to or spent
+	// from the address.
+	QueryAddressUsed(ctx context.Context, addr Address) (bool, error)
+}
+
+type Utxo struct {
+	Address Address
+	TxId    TransactionID
+	Amount  uint64
+	Index   uint64
+	Assets  MultiAsset
+}
+
+type NodeTip struct {
+	Epoch uint64
+	Block uint64
+	Slot  uint64
+}
+
+// TxStatus is the inclusion status of a transaction.
+type TxStatus struct {
+	Confirmed bool
+	Block     uint64 // block number including the transaction, if confirmed
+	Slot      uint64
+}
+
+// RewardAccount is the state of the reward account of a stake address.
+type RewardAccount struct {
+	Address    Address
+	Registered bool
+	Balance    uint64 // withdrawable rewards in lovelace
+	Delegation string // bech32 pool id, empty if not delegated
+}
@@ -0,0 +1,362 @@
+package cardano
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Blockfrost API base URLs.
+const (
+	BlockfrostMainnet = "https://cardano-mainnet.blockfrost.io/api/v0"
+	BlockfrostPreprod = "https://cardano-preprod.blockfrost.io/api/v0"
+	BlockfrostPreview = "https://cardano-preview.blockfrost.io/api/v0"
+)
+
+const (
+	blockfrostPageSize   = 100
+	blockfrostMaxRetries = 5
+	blockfrostBackoff    = 500 * time.Millisecond
+)
+
+// BlockfrostError is an error response of the Blockfrost API.
+type BlockfrostError struct {
+	StatusCode int    `json:"status_code"`
+	Err        string `json:"error"`
+	Message    string `json:"message"`
+}
+
+func (e *BlockfrostError) Error() string {
+	return fmt.Sprintf("blockfrost: %v %v: %v", e.StatusCode, e.Err, e.Message)
+}
+
+// BlockfrostOption configures a Blockfrost backend.
+type BlockfrostOption func(*Blockfrost)
+
+// WithBlockfrostURL sets the base URL of the API, BlockfrostMainnet by default.
+func WithBlockfrostURL(baseURL string) BlockfrostOption {
+	return func(bf *Blockfrost) {
+		bf.baseURL = baseURL
+	}
+}
+
+// WithBlockfrostHTTPClient sets the http client used to call the API.
+func WithBlockfrostHTTPClient(client *http.Client) BlockfrostOption {
+	return func(bf *Blockfrost) {
+		bf.client = client
+	}
+}
+
+// WithBlockfrostRetries sets how many times a rate limited request is retried
+// and the initial backoff, which doubles on every retry.
+func WithBlockfrostRetries(maxRetries int, backoff time.Duration) BlockfrostOption {
+	return func(bf *Blockfrost) {
+		bf.maxRetries = maxRetries
+		bf.backoff = backoff
+	}
+}
+
+// Blockfrost is a ChainBackend using the Blockfrost REST API.
+type Blockfrost struct {
+	baseURL    string
+	projectID  string
+	client     *http.Client
+	maxRetries int
+	backoff    time.Duration
+}
+
+// NewBlockfrost creates a Blockfrost backend authenticated with the project ID.
+func NewBlockfrost(projectID string, opts ...BlockfrostOption) *Blockfrost {
+	bf := &Blockfrost{
+		baseURL:    BlockfrostMainnet,
+		projectID:  projectID,
+		client:     http.DefaultClient,
+		maxRetries: blockfrostMaxRetries,
+		backoff:    blockfrostBackoff,
+	}
+	for _, opt := range opts {
+		opt(bf)
+	}
+	return bf
+}
+
+type blockfrostAmount struct {
+	Unit     string `json:"unit"`
+	Quantity string `json:"quantity"`
+}
+
+type blockfrostUtxo struct {
+	TxHash      string             `json:"tx_hash"`
+	OutputIndex uint64             `json:"output_index"`
+	Amount      []blockfrostAmount `json:"amount"`
+}
+
+type blockfrostBlock struct {
+	Height uint64 `json:"height"`
+	Slot   uint64 `json:"slot"`
+	Epoch  uint64 `json:"epoch"`
+}
+
+type blockfrostParams struct {
+	MinFeeA          uint64      `json:"min_fee_a"`
+	MinFeeB          uint64      `json:"min_fee_b"`
+	MaxTxSize        uint64      `json:"max_tx_size"`
+	KeyDeposit       string      `json:"key_deposit"`
+	PoolDeposit      string      `json:"pool_deposit"`
+	MinUtxo          string      `json:"min_utxo"`
+	CoinsPerUtxoSize *string     `json:"coins_per_utxo_size"`
+	PriceMem         json.Number `json:"price_mem"`
+	PriceStep        json.Number `json:"price_step"`
+	// CostModelsRaw are the cost models as arrays of parameters, unlike the
+	// named parameters of cost_models.
+	CostModelsRaw map[string]json.RawMessage `json:"cost_models_raw"`
+}
+
+type blockfrostTx struct {
+	BlockHeight uint64 `json:"block_height"`
+	Slot        uint64 `json:"slot"`
+}
+
+type blockfrostAccount struct {
+	StakeAddress       string  `json:"stake_address"`
+	Active             bool    `json:"active"`
+	WithdrawableAmount string  `json:"withdrawable_amount"`
+	PoolID             *string `json:"pool_id"`
+}
+
+// QueryUtxos returns the unspent outputs locked by the address, fetching
+// every page.
+func (bf *Blockfrost) QueryUtxos(ctx context.Context, addr Address) ([]Utxo, error) {
+	utxos := []Utxo{}
+	for page := 1; ; page++ {
+		bfUtxos := []blockfrostUtxo{}
+		path := fmt.Sprintf("/addresses/%v/utxos?page=%v&count=%v", addr, page, blockfrostPageSize)
+		err := bf.do(ctx, http.MethodGet, path, "", nil, &bfUtxos)
+		if isNotFound(err) {
+			return utxos, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for _, bfUtxo := range bfUtxos {
+			utxo := Utxo{
+				Address: addr,
+				TxId:    TransactionID(bfUtxo.TxHash),
+				Index:   bfUtxo.OutputIndex,
+			}
+			for _, amount := range bfUtxo.Amount {
+				if err := addUnit(&utxo, amount.Unit, amount.Quantity); err != nil {
+					return nil, err
+				}
+			}
+			utxos = append(utxos, utxo)
+		}
+		if len(bfUtxos) < blockfrostPageSize {
+			return utxos, nil
+		}
+	}
+}
+
+// QueryAddressUsed returns whether the address has any transaction.
+func (bf *Blockfrost) QueryAddressUsed(ctx context.Context, addr Address) (bool, error) {
+	txs := []json.RawMessage{}
+	err := bf.do(ctx, http.MethodGet, fmt.Sprintf("/addresses/%v/transactions?count=1", addr), "", nil, &txs)
+	if isNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return len(txs) > 0, nil
+}
+
+// QueryTip returns the latest block.
+func (bf *Blockfrost) QueryTip(ctx context.Context) (NodeTip, error) {
+	block := blockfrostBlock{}
+	if err := bf.do(ctx, http.MethodGet, "/blocks/latest", "", nil, &block); err != nil {
+		return NodeTip{}, err
+	}
+	return NodeTip{Epoch: block.Epoch, Block: block.Height, Slot: block.Slot}, nil
+}
+
+// QueryProtocolParams returns the protocol parameters of the latest epoch.
+func (bf *Blockfrost) QueryProtocolParams(ctx context.Context) (ProtocolParams, error) {
+	params := blockfrostParams{}
+	if err := bf.do(ctx, http.MethodGet, "/epochs/latest/parameters", "", nil, &params); err != nil {
+		return ProtocolParams{}, err
+	}
+
+	protocol := ProtocolParams{
+		MinFeeA:   params.MinFeeA,
+		MinFeeB:   params.MinFeeB,
+		MaxTxSize: params.MaxTxSize,
+	}
+	var err error
+	if protocol.KeyDeposit, err = parseQuantity(params.KeyDeposit); err != nil {
+		return ProtocolParams{}, err
+	}
+	if protocol.PoolDeposit, err = parseQuantity(params.PoolDeposit); err != nil {
+		return ProtocolParams{}, err
+	}
+	if params.CoinsPerUtxoSize != nil {
+		if protocol.CoinsPerUTxOByte, err = parseQuantity(*params.CoinsPerUtxoSize); err != nil {
+			return ProtocolParams{}, err
+		}
+	} else if protocol.MinimumUtxoValue, err = parseQuantity(params.MinUtxo); err != nil {
+		return ProtocolParams{}, err
+	}
+	if protocol.PriceMem, err = parseRational(params.PriceMem.String()); err != nil {
+		return ProtocolParams{}, err
+	}
+	if protocol.PriceStep, err = parseRational(params.PriceStep.String()); err != nil {
+		return ProtocolParams{}, err
+	}
+	protocol.CostModels = parseCostModels(params.CostModelsRaw)
+	return protocol, nil
+}
+
+// QueryTxStatus returns the block including the transaction, if any.
+func (bf *Blockfrost) QueryTxStatus(ctx context.Context, txId TransactionID) (TxStatus, error) {
+	tx := blockfrostTx{}
+	err := bf.do(ctx, http.MethodGet, "/txs/"+string(txId), "", nil, &tx)
+	if isNotFound(err) {
+		return TxStatus{}, nil
+	}
+	if err != nil {
+		return TxStatus{}, err
+	}
+	return TxStatus{Confirmed: true, Block: tx.BlockHeight, Slot: tx.Slot}, nil
+}
+
+// QueryRewardAccount returns the state of the reward account of the stake
+// address.
+func (bf *Blockfrost) QueryRewardAccount(ctx context.Context, stakeAddr Address) (RewardAccount, error) {
+	account := blockfrostAccount{}
+	err := bf.do(ctx, http.MethodGet, "/accounts/"+string(stakeAddr), "", nil, &account)
+	if isNotFound(err) {
+		return RewardAccount{Address: stakeAddr}, nil
+	}
+	if err != nil {
+		return RewardAccount{}, err
+	}
+
+	balance, err := parseQuantity(account.WithdrawableAmount)
+	if err != nil {
+		return RewardAccount{}, err
+	}
+	rewardAccount := RewardAccount{Address: stakeAddr, Registered: account.Active, Balance: balance}
+	if account.PoolID != nil {
+		rewardAccount.Delegation = *account.PoolID
+	}
+	return rewardAccount, nil
+}
+
+// SubmitTx submits the cbor encoded transaction.
+func (bf *Blockfrost) SubmitTx(ctx context.Context, tx Transaction) (TransactionID, error) {
+	var txId TransactionID
+	if err := bf.do(ctx, http.MethodPost, "/tx/submit", "application/cbor", tx.Bytes(), &txId); err != nil {
+		if bfErr, ok := err.(*BlockfrostError); ok && bfErr.StatusCode == http.StatusBadRequest {
+			return "", submitError(bfErr, bfErr.Message)
+		}
+		return "", err
+	}
+	return txId, nil
+}
+
+// do sends a request to the API and decodes the json response into out,
+// retrying with an exponential backoff while rate limited.
+func (bf *Blockfrost) do(ctx context.Context, method, path, contentType string, body []byte, out interface{}) error {
+	backoff := bf.backoff
+	for retry := 0; ; retry++ {
+		var reader io.Reader
+		if body != nil {
+			reader = bytes.NewReader(body)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, bf.baseURL+path, reader)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("project_id", bf.projectID)
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+
+		resp, err := bf.client.Do(req)
+		if err != nil {
+			return err
+		}
+		respBody, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests && retry < bf.maxRetries {
+			wait := backoff
+			if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+				wait = time.Duration(seconds) * time.Second
+			}
+			if err := sleepContext(ctx, wait); err != nil {
+				return err
+			}
+			backoff *= 2
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			bfErr := &BlockfrostError{}
+			if err := json.Unmarshal(respBody, bfErr); err != nil || bfErr.StatusCode == 0 {
+				bfErr = &BlockfrostError{StatusCode: resp.StatusCode, Err: http.StatusText(resp.StatusCode), Message: string(respBody)}
+			}
+			return bfErr
+		}
+		return json.Unmarshal(respBody, out)
+	}
+}
+
+func isNotFound(err error) bool {
+	bfErr, ok := err.(*BlockfrostError)
+	return ok && bfErr.StatusCode == http.StatusNotFound
+}
+
+// addUnit adds a quantity of lovelace or of the asset identified by its
+// policy ID followed by its asset name to the utxo.
+func addUnit(utxo *Utxo, unit, quantity string) error {
+	amount, err := parseQuantity(quantity)
+	if err != nil {
+		return err
+	}
+	if unit == "lovelace" {
+		utxo.Amount += amount
+		return nil
+	}
+	if len(unit) < 56 {
+		return fmt.Errorf("invalid asset unit %q", unit)
+	}
+	utxo.Assets = utxo.Assets.Add(NewMultiAsset(unit[:56], unit[56:], amount))
+	return nil
+}
+
+func parseQuantity(s string) (uint64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return ParseUint64(s)
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
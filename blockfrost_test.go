@@ -0,0 +1,225 @@
+package cardano
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const blockfrostTestAddr = Address("addr_test1vqgjd0t02q9yglcjwdc8dht9tz6gkfpqqm7evs5csrklakcqmwv40")
+
+func newBlockfrostTestServer(t *testing.T, handler http.HandlerFunc) *Blockfrost {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.Header.Get("project_id"), "test-project"; got != want {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"status_code": 403, "error": "Forbidden", "message": "Invalid project token."}`)
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return NewBlockfrost("test-project", WithBlockfrostURL(server.URL), WithBlockfrostRetries(2, time.Millisecond))
+}
+
+func TestBlockfrostQueryUtxos(t *testing.T) {
+	policyID := "1d7f33bd23d85e1a25d87d86fac4f199c3197a2f7afeb662a0f34e1e"
+	bf := newBlockfrostTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.URL.Path, "/addresses/"+string(blockfrostTestAddr)+"/utxos"; got != want {
+			t.Errorf("got path %v want %v", got, want)
+		}
+		// The first page is full, the second one is not
+		switch r.URL.Query().Get("page") {
+		case "1":
+			fmt.Fprint(w, "[")
+			for i := 0; i < blockfrostPageSize; i++ {
+				if i > 0 {
+					fmt.Fprint(w, ",")
+				}
+				fmt.Fprintf(w, `{"tx_hash": "%064x", "output_index": %v, "amount": [{"unit": "lovelace", "quantity": "1000000"}]}`, 1, i)
+			}
+			fmt.Fprint(w, "]")
+		case "2":
+			fmt.Fprintf(w, `[{"tx_hash": "%064x", "output_index": 0, "amount": [{"unit": "lovelace", "quantity": "2000000"}, {"unit": "%v776f726c64", "quantity": "12"}]}]`, 2, policyID)
+		default:
+			fmt.Fprint(w, "[]")
+		}
+	})
+
+	utxos, err := bf.QueryUtxos(context.Background(), blockfrostTestAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(utxos), blockfrostPageSize+1; got != want {
+		t.Fatalf("got %v utxos want %v", got, want)
+	}
+	last := utxos[len(utxos)-1]
+	if got, want := last.Amount, uint64(2000000); got != want {
+		t.Errorf("got amount %v want %v", got, want)
+	}
+	if got, want := last.Assets.Quantity(policyID, "776f726c64"), uint64(12); got != want {
+		t.Errorf("got asset quantity %v want %v", got, want)
+	}
+	if got, want := last.TxId, TransactionID(fmt.Sprintf("%064x", 2)); got != want {
+		t.Errorf("got tx id %v want %v", got, want)
+	}
+}
+
+func TestBlockfrostQueryUtxosUnknownAddress(t *testing.T) {
+	bf := newBlockfrostTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"status_code": 404, "error": "Not Found", "message": "The requested component has not been found."}`)
+	})
+
+	utxos, err := bf.QueryUtxos(context.Background(), blockfrostTestAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(utxos) != 0 {
+		t.Errorf("got %v utxos want none", len(utxos))
+	}
+}
+
+func TestBlockfrostQueryAddressUsed(t *testing.T) {
+	bf := newBlockfrostTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.URL.Path, "/addresses/"+string(blockfrostTestAddr)+"/transactions"; got != want {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"status_code": 404, "error": "Not Found", "message": "The requested component has not been found."}`)
+			return
+		}
+		fmt.Fprintf(w, `[{"tx_hash": "%064x", "tx_index": 0, "block_height": 100, "block_time": 1650000000}]`, 1)
+	})
+
+	if used, err := bf.QueryAddressUsed(context.Background(), blockfrostTestAddr); err != nil || !used {
+		t.Errorf("got used %v, %v want true", used, err)
+	}
+	if used, err := bf.QueryAddressUsed(context.Background(), "addr_test1unknown"); err != nil || used {
+		t.Errorf("got used %v, %v want false", used, err)
+	}
+}
+
+func TestBlockfrostRateLimit(t *testing.T) {
+	calls := 0
+	bf := newBlockfrostTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"status_code": 429, "error": "Project Over Limit", "message": "Usage is over limit."}`)
+			return
+		}
+		fmt.Fprint(w, `{"height": 15243593, "slot": 412162133, "epoch": 425}`)
+	})
+
+	tip, err := bf.QueryTip(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := tip, (NodeTip{Epoch: 425, Block: 15243593, Slot: 412162133}); got != want {
+		t.Errorf("got tip %+v want %+v", got, want)
+	}
+
+	// Retries are exhausted after the third rate limited response
+	calls = -10
+	var bfErr *BlockfrostError
+	if _, err := bf.QueryTip(context.Background()); !errors.As(err, &bfErr) || bfErr.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("got error %v want rate limit error", err)
+	}
+}
+
+func TestBlockfrostQueryProtocolParams(t *testing.T) {
+	bf := newBlockfrostTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{
+			"epoch": 425, "min_fee_a": 44, "min_fee_b": 155381, "max_tx_size": 16384,
+			"key_deposit": "2000000", "pool_deposit": "500000000", "min_utxo": "4310",
+			"price_mem": 0.0577, "price_step": 0.0000721, "coins_per_utxo_size": "4310",
+			"cost_models": {"PlutusV2": {"addInteger-cpu-arguments-intercept": 205665}},
+			"cost_models_raw": {"PlutusV1": [205665, -1], "PlutusV2": [205665, 812]}
+		}`)
+	})
+
+	protocol, err := bf.QueryProtocolParams(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ProtocolParams{
+		MinFeeA:          44,
+		MinFeeB:          155381,
+		MaxTxSize:        16384,
+		KeyDeposit:       2000000,
+		PoolDeposit:      500000000,
+		CoinsPerUTxOByte: 4310,
+		PriceMem:         Rational{Num: 577, Den: 10000},
+		PriceStep:        Rational{Num: 721, Den: 10000000},
+		CostModels:       map[PlutusVersion][]int64{PlutusV1: {205665, -1}, PlutusV2: {205665, 812}},
+	}
+	if !reflect.DeepEqual(protocol, want) {
+		t.Errorf("got %+v want %+v", protocol, want)
+	}
+}
+
+func TestBlockfrostSubmitTx(t *testing.T) {
+	tx := Transaction{Body: TransactionBody{Inputs: []TransactionInput{{ID: make([]byte, 32)}}, Fee: 170000, Ttl: 100}}
+	bf := newBlockfrostTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.Header.Get("Content-Type"), "application/cbor"; got != want {
+			t.Errorf("got content type %v want %v", got, want)
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		if got, want := hex.EncodeToString(body), tx.CborHex(); got != want {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"status_code": 400, "error": "Bad Request", "message": "transaction submit error"}`)
+			return
+		}
+		fmt.Fprintf(w, `"%v"`, tx.ID())
+	})
+
+	txId, err := bf.SubmitTx(context.Background(), tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := txId, tx.ID(); got != want {
+		t.Errorf("got tx id %v want %v", got, want)
+	}
+
+	tx.Body.Fee++
+	bf = newBlockfrostTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"status_code": 400, "error": "Bad Request", "message": "transaction submit error"}`)
+	})
+	var bfErr *BlockfrostError
+	if _, err := bf.SubmitTx(context.Background(), tx); !errors.As(err, &bfErr) || bfErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("got error %v want bad request", err)
+	}
+
+	bf = newBlockfrostTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"status_code": 400, "error": "Bad Request", "message": "\"transaction submit error ShelleyTxValidationError ShelleyBasedEraBabbage (ApplyTxError [UtxowFailure (UtxoFailure (AlonzoInBabbageUtxoPredFailure (FeeTooSmallUTxO (Coin 171000) (Coin 170000))))])\""}`)
+	})
+	_, err = bf.SubmitTx(context.Background(), tx)
+	var feeErr *FeeTooSmallError
+	if !errors.As(err, &feeErr) || feeErr.Fee != 170000 || feeErr.MinFee != 171000 {
+		t.Errorf("got error %v want fee too small", err)
+	}
+	if !errors.As(err, &bfErr) {
+		t.Errorf("got error %v want %T", err, bfErr)
+	}
+}
+
+func TestBlockfrostQueryRewardAccount(t *testing.T) {
+	bf := newBlockfrostTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"stake_address": "stake_test1uqfu74w3wh4gfzu8m6e7j987h4lq9r3t7ef5gaw497uu85qsqfy27", "active": true, "withdrawable_amount": "31337", "pool_id": "pool1pu5jlj4q9w9jlxeu370a3c9myx47md5j5m2str0naunn2q3lkdy"}`)
+	})
+
+	account, err := bf.QueryRewardAccount(context.Background(), "stake_test1uqfu74w3wh4gfzu8m6e7j987h4lq9r3t7ef5gaw497uu85qsqfy27")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !account.Registered || account.Balance != 31337 || account.Delegation != "pool1pu5jlj4q9w9jlxeu370a3c9myx47md5j5m2str0naunn2q3lkdy" {
+		t.Errorf("got account %+v", account)
+	}
+}
@@ -1,6 +1,9 @@
 package cardano
 
-import "time"
+import (
+	"reflect"
+	"time"
+)
 
 const (
 	shelleyStartTimestamp = 1596491091
@@ -46,7 +49,7 @@ func (builder TXBodyBuilder) Build(receiver Address, pickedUtxos []Utxo, amount
 		Outputs: outputs,
 		Ttl:     builder.ttl(),
 	}
-	if err := body.addFee(inputAmount, change, builder.protocol()); err != nil {
+	if err := body.addFee(inputAmount, change, builder.protocol(), TransactionWitnessSet{}, witnessCount(pickedUtxos), nil); err != nil {
 		return nil, err
 	}
 
@@ -61,7 +64,7 @@ func (builder TXBodyBuilder) ttl() uint64 {
 }
 
 func (builder TXBodyBuilder) protocol() ProtocolParams {
-	if builder.Protocol == (ProtocolParams{}) {
+	if reflect.DeepEqual(builder.Protocol, ProtocolParams{}) {
 		return ShelleyProtocol
 	}
 	return builder.Protocol
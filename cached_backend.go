@@ -0,0 +1,240 @@
+package cardano
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// CachedQuery is a query type of a CachedBackend.
+type CachedQuery int
+
+const (
+	CacheTip CachedQuery = iota
+	CacheProtocolParams
+	CacheUtxos
+	CacheTxStatus
+	CacheRewardAccount
+)
+
+const cachedBackendTimeout = 30 * time.Second
+
+var cachedBackendTTLs = map[CachedQuery]time.Duration{
+	CacheTip:            5 * time.Second,
+	CacheProtocolParams: 10 * time.Minute,
+	CacheUtxos:          20 * time.Second,
+	CacheTxStatus:       0,
+	CacheRewardAccount:  time.Minute,
+}
+
+// CachedBackendOption configures a CachedBackend.
+type CachedBackendOption func(*CachedBackend)
+
+// WithCacheTTL sets how long the answers to a query type are cached, 0
+// disabling the cache for the type. The defaults are 5 seconds for the tip,
+// 10 minutes for the protocol parameters, 20 seconds for UTxOs, a minute for
+// reward accounts and no caching for transaction statuses.
+func WithCacheTTL(query CachedQuery, ttl time.Duration) CachedBackendOption {
+	return func(c *CachedBackend) {
+		c.ttls[query] = ttl
+	}
+}
+
+// WithCacheTimeout sets how long a query shared by concurrent callers may run,
+// 30 seconds by default. Shared queries don't run on the context of any
+// caller, so that a caller giving up doesn't fail the others.
+func WithCacheTimeout(timeout time.Duration) CachedBackendOption {
+	return func(c *CachedBackend) {
+		c.timeout = timeout
+	}
+}
+
+// CachedBackend is a ChainBackend caching the answers of another backend.
+// Concurrent identical queries are collapsed into a single one, even for the
+// query types that are not cached. Submitting a transaction invalidates the
+// cached UTxOs and reward accounts.
+type CachedBackend struct {
+	backend ChainBackend
+	ttls    map[CachedQuery]time.Duration
+	timeout time.Duration
+	now     func() time.Time
+
+	mu         sync.Mutex
+	entries    map[cacheKey]cacheEntry
+	calls      map[cacheKey]*cacheCall
+	generation uint64 // incremented on invalidation
+}
+
+type cacheKey struct {
+	query CachedQuery
+	arg   string
+}
+
+type cacheEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// cacheCall is a query in flight, shared by the identical queries sent in the
+// meantime.
+type cacheCall struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// NewCachedBackend creates a CachedBackend of the backend.
+func NewCachedBackend(backend ChainBackend, opts ...CachedBackendOption) *CachedBackend {
+	c := &CachedBackend{
+		backend: backend,
+		ttls:    map[CachedQuery]time.Duration{},
+		timeout: cachedBackendTimeout,
+		now:     time.Now,
+		entries: map[cacheKey]cacheEntry{},
+		calls:   map[cacheKey]*cacheCall{},
+	}
+	for query, ttl := range cachedBackendTTLs {
+		c.ttls[query] = ttl
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Invalidate drops every cached answer.
+func (c *CachedBackend) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = map[cacheKey]cacheEntry{}
+	c.generation++
+}
+
+// QueryUtxos returns the unspent outputs locked by the address.
+func (c *CachedBackend) QueryUtxos(ctx context.Context, addr Address) ([]Utxo, error) {
+	value, err := c.do(ctx, cacheKey{CacheUtxos, string(addr)}, func(ctx context.Context) (interface{}, error) {
+		return c.backend.QueryUtxos(ctx, addr)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append([]Utxo{}, value.([]Utxo)...), nil
+}
+
+// QueryTip returns the tip of the chain.
+func (c *CachedBackend) QueryTip(ctx context.Context) (NodeTip, error) {
+	value, err := c.do(ctx, cacheKey{query: CacheTip}, func(ctx context.Context) (interface{}, error) {
+		return c.backend.QueryTip(ctx)
+	})
+	if err != nil {
+		return NodeTip{}, err
+	}
+	return value.(NodeTip), nil
+}
+
+// QueryProtocolParams returns the current protocol parameters.
+func (c *CachedBackend) QueryProtocolParams(ctx context.Context) (ProtocolParams, error) {
+	value, err := c.do(ctx, cacheKey{query: CacheProtocolParams}, func(ctx context.Context) (interface{}, error) {
+		return c.backend.QueryProtocolParams(ctx)
+	})
+	if err != nil {
+		return ProtocolParams{}, err
+	}
+	return value.(ProtocolParams), nil
+}
+
+// QueryAddressUsed returns whether the address was ever used, without caching
+// the answer. It returns ErrNotSupported if the backend can't tell.
+func (c *CachedBackend) QueryAddressUsed(ctx context.Context, addr Address) (bool, error) {
+	querier, ok := c.backend.(AddressUsageQuerier)
+	if !ok {
+		return false, ErrNotSupported
+	}
+	return querier.QueryAddressUsed(ctx, addr)
+}
+
+// QueryTxStatus returns whether the transaction is included in the chain.
+func (c *CachedBackend) QueryTxStatus(ctx context.Context, txId TransactionID) (TxStatus, error) {
+	value, err := c.do(ctx, cacheKey{CacheTxStatus, string(txId)}, func(ctx context.Context) (interface{}, error) {
+		return c.backend.QueryTxStatus(ctx, txId)
+	})
+	if err != nil {
+		return TxStatus{}, err
+	}
+	return value.(TxStatus), nil
+}
+
+// QueryRewardAccount returns the state of the reward account of the stake
+// address.
+func (c *CachedBackend) QueryRewardAccount(ctx context.Context, stakeAddr Address) (RewardAccount, error) {
+	value, err := c.do(ctx, cacheKey{CacheRewardAccount, string(stakeAddr)}, func(ctx context.Context) (interface{}, error) {
+		return c.backend.QueryRewardAccount(ctx, stakeAddr)
+	})
+	if err != nil {
+		return RewardAccount{}, err
+	}
+	return value.(RewardAccount), nil
+}
+
+// SubmitTx submits the transaction to the backend and invalidates the cached
+// UTxOs and reward accounts.
+func (c *CachedBackend) SubmitTx(ctx context.Context, tx Transaction) (TransactionID, error) {
+	txId, err := c.backend.SubmitTx(ctx, tx)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.entries {
+		if key.query == CacheUtxos || key.query == CacheRewardAccount {
+			delete(c.entries, key)
+		}
+	}
+	c.generation++
+	return txId, nil
+}
+
+// do returns the cached answer of the query, or calls fn, sharing the call
+// with the identical queries sent until it returns. The call runs on its own
+// context bounded by the backend timeout, each caller only waiting for it
+// until its context is done. Answers of calls that ran while the cache was
+// invalidated are not cached.
+func (c *CachedBackend) do(ctx context.Context, key cacheKey, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	c.mu.Lock()
+	if entry, ok := c.entries[key]; ok {
+		if c.now().Before(entry.expires) {
+			c.mu.Unlock()
+			return entry.value, nil
+		}
+		delete(c.entries, key)
+	}
+	call, ok := c.calls[key]
+	if !ok {
+		call = &cacheCall{done: make(chan struct{})}
+		c.calls[key] = call
+		go c.run(key, call, c.generation, fn)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.value, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *CachedBackend) run(key cacheKey, call *cacheCall, generation uint64, fn func(ctx context.Context) (interface{}, error)) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	call.value, call.err = fn(ctx)
+
+	c.mu.Lock()
+	delete(c.calls, key)
+	if ttl := c.ttls[key.query]; call.err == nil && ttl > 0 && generation == c.generation {
+		c.entries[key] = cacheEntry{value: call.value, expires: c.now().Add(ttl)}
+	}
+	c.mu.Unlock()
+	close(call.done)
+}
@@ -0,0 +1,172 @@
+package cardano
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCachedBackend(t *testing.T) {
+	tx := Transaction{Body: TransactionBody{Inputs: []TransactionInput{{ID: make([]byte, 32)}}, Fee: 170000, Ttl: 100}}
+	tests := []struct {
+		name      string
+		opts      []CachedBackendOption
+		query     func(c *CachedBackend) error
+		between   func(c *CachedBackend, now *time.Time)
+		wantCalls int32
+	}{
+		{
+			name:      "tip cached",
+			query:     func(c *CachedBackend) error { _, err := c.QueryTip(context.Background()); return err },
+			wantCalls: 1,
+		},
+		{
+			name:      "tip expired",
+			query:     func(c *CachedBackend) error { _, err := c.QueryTip(context.Background()); return err },
+			between:   func(c *CachedBackend, now *time.Time) { *now = now.Add(5 * time.Second) },
+			wantCalls: 2,
+		},
+		{
+			name:      "protocol params cached",
+			query:     func(c *CachedBackend) error { _, err := c.QueryProtocolParams(context.Background()); return err },
+			between:   func(c *CachedBackend, now *time.Time) { *now = now.Add(5 * time.Minute) },
+			wantCalls: 1,
+		},
+		{
+			name:  "utxos invalidated on submit",
+			query: func(c *CachedBackend) error { _, err := c.QueryUtxos(context.Background(), ""); return err },
+			between: func(c *CachedBackend, now *time.Time) {
+				if _, err := c.SubmitTx(context.Background(), tx); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantCalls: 3,
+		},
+		{
+			name:  "tip kept on submit",
+			query: func(c *CachedBackend) error { _, err := c.QueryTip(context.Background()); return err },
+			between: func(c *CachedBackend, now *time.Time) {
+				if _, err := c.SubmitTx(context.Background(), tx); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantCalls: 2,
+		},
+		{
+			name:      "caching disabled",
+			opts:      []CachedBackendOption{WithCacheTTL(CacheRewardAccount, 0)},
+			query:     func(c *CachedBackend) error { _, err := c.QueryRewardAccount(context.Background(), ""); return err },
+			wantCalls: 2,
+		},
+		{
+			name:      "invalidated",
+			query:     func(c *CachedBackend) error { _, err := c.QueryTip(context.Background()); return err },
+			between:   func(c *CachedBackend, now *time.Time) { c.Invalidate() },
+			wantCalls: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &fakeBackend{}
+			cached := NewCachedBackend(backend, tt.opts...)
+			now := time.Unix(0, 0)
+			cached.now = func() time.Time { return now }
+
+			if err := tt.query(cached); err != nil {
+				t.Fatal(err)
+			}
+			if tt.between != nil {
+				tt.between(cached, &now)
+			}
+			if err := tt.query(cached); err != nil {
+				t.Fatal(err)
+			}
+			if got, want := atomic.LoadInt32(&backend.calls), tt.wantCalls; got != want {
+				t.Errorf("got %v backend calls want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestCachedBackendErrors(t *testing.T) {
+	backend := &fakeBackend{err: errors.New("backend down")}
+	cached := NewCachedBackend(backend)
+
+	if _, err := cached.QueryTip(context.Background()); err == nil {
+		t.Fatal("got no error")
+	}
+	backend.err = nil
+	if _, err := cached.QueryTip(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := atomic.LoadInt32(&backend.calls), int32(2); got != want {
+		t.Errorf("got %v backend calls want %v", got, want)
+	}
+}
+
+func TestCachedBackendConcurrentQueries(t *testing.T) {
+	backend := &fakeBackend{tip: NodeTip{Block: 1}, delay: 50 * time.Millisecond}
+	cached := NewCachedBackend(backend, WithCacheTTL(CacheTip, 0))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tip, err := cached.QueryTip(context.Background())
+			if err != nil || tip.Block != 1 {
+				t.Errorf("got tip %+v, %v", tip, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got, want := atomic.LoadInt32(&backend.calls), int32(1); got != want {
+		t.Errorf("got %v backend calls want %v", got, want)
+	}
+}
+
+func TestCachedBackendFirstCallerCanceled(t *testing.T) {
+	backend := &fakeBackend{tip: NodeTip{Block: 1}, delay: 50 * time.Millisecond}
+	cached := NewCachedBackend(backend, WithCacheTTL(CacheTip, 0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := cached.QueryTip(ctx)
+		first <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	second := make(chan error, 1)
+	go func() {
+		tip, err := cached.QueryTip(context.Background())
+		if err == nil && tip.Block != 1 {
+			err = errors.New("wrong tip")
+		}
+		second <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Errorf("got first caller error %v want %v", err, context.Canceled)
+	}
+	if err := <-second; err != nil {
+		t.Errorf("got second caller error %v", err)
+	}
+	if got, want := atomic.LoadInt32(&backend.calls), int32(1); got != want {
+		t.Errorf("got %v backend calls want %v", got, want)
+	}
+}
+
+func TestCachedBackendTimeout(t *testing.T) {
+	backend := &fakeBackend{delay: time.Second}
+	cached := NewCachedBackend(backend, WithCacheTimeout(10*time.Millisecond))
+
+	if _, err := cached.QueryTip(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v want %v", err, context.DeadlineExceeded)
+	}
+}
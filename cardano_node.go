@@ -2,9 +2,9 @@ package cardano
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"os"
 	"os/exec"
 	"strings"
@@ -14,25 +14,6 @@ const (
 	testnetMagic = 1097911063
 )
 
-type cardanoNode interface {
-	QueryUtxos(Address) ([]Utxo, error)
-	QueryTip() (NodeTip, error)
-	SubmitTx(Transaction) error
-}
-
-type Utxo struct {
-	Address Address
-	TxId    TransactionID
-	Amount  uint64
-	Index   uint64
-}
-
-type NodeTip struct {
-	Epoch uint64
-	Block uint64
-	Slot  uint64
-}
-
 type cardanoCli struct {
 	socketPath string
 }
@@ -45,6 +26,28 @@ type cardanoCliTip struct {
 	Era   string
 }
 
+type cardanoCliProtocolParams struct {
+	TxFeePerByte        uint64 `json:"txFeePerByte"`
+	TxFeeFixed          uint64 `json:"txFeeFixed"`
+	MaxTxSize           uint64 `json:"maxTxSize"`
+	StakeAddressDeposit uint64 `json:"stakeAddressDeposit"`
+	StakePoolDeposit    uint64 `json:"stakePoolDeposit"`
+	MinUTxOValue        uint64 `json:"minUTxOValue"`
+	UTxOCostPerByte     uint64 `json:"utxoCostPerByte"`
+	ExecutionUnitPrices struct {
+		PriceMemory json.Number `json:"priceMemory"`
+		PriceSteps  json.Number `json:"priceSteps"`
+	} `json:"executionUnitPrices"`
+	CostModels map[string]json.RawMessage `json:"costModels"`
+}
+
+type cardanoCliStakeAddressInfo struct {
+	Address              string `json:"address"`
+	Delegation           string `json:"delegation"`
+	StakeDelegation      string `json:"stakeDelegation"`
+	RewardAccountBalance uint64 `json:"rewardAccountBalance"`
+}
+
 type cardanoCliTx struct {
 	Type        string `json:"type"`
 	Description string `json:"description"`
@@ -55,9 +58,14 @@ func newCli() *cardanoCli {
 	return &cardanoCli{}
 }
 
+// Network returns the testnet, the only network the cli is called with.
+func (cli *cardanoCli) Network() Network {
+	return Testnet
+}
+
 //TODO: add ability to use mainnet and testnet
-func (cli *cardanoCli) QueryUtxos(address Address) ([]Utxo, error) {
-	out, err := runCommand("cardano-cli", "query", "utxo", "--address", string(address), "--testnet-magic", "1097911063")
+func (cli *cardanoCli) QueryUtxos(ctx context.Context, address Address) ([]Utxo, error) {
+	out, err := runCommand(ctx, "cardano-cli", "query", "utxo", "--address", string(address), "--testnet-magic", "1097911063")
 	if err != nil {
 		return nil, err
 	}
@@ -99,8 +107,8 @@ func (cli *cardanoCli) QueryUtxos(address Address) ([]Utxo, error) {
 }
 
 //TODO: add ability to use mainnet and testnet
-func (cli *cardanoCli) QueryTip() (NodeTip, error) {
-	out, err := runCommand("cardano-cli", "query", "tip", "--testnet-magic", "1097911063")
+func (cli *cardanoCli) QueryTip(ctx context.Context) (NodeTip, error) {
+	out, err := runCommand(ctx, "cardano-cli", "query", "tip", "--testnet-magic", "1097911063")
 	if err != nil {
 		return NodeTip{}, err
 	}
@@ -119,8 +127,73 @@ func (cli *cardanoCli) QueryTip() (NodeTip, error) {
 }
 
 //TODO: add ability to use mainnet and testnet
-func (cli *cardanoCli) SubmitTx(tx Transaction) error {
-	const txFileName = "txsigned.temp"
+func (cli *cardanoCli) QueryProtocolParams(ctx context.Context) (ProtocolParams, error) {
+	out, err := runCommand(ctx, "cardano-cli", "query", "protocol-parameters", "--testnet-magic", "1097911063")
+	if err != nil {
+		return ProtocolParams{}, err
+	}
+
+	cliParams := &cardanoCliProtocolParams{}
+	err = json.Unmarshal(out.Bytes(), cliParams)
+	if err != nil {
+		return ProtocolParams{}, err
+	}
+
+	priceMem, err := parseRational(cliParams.ExecutionUnitPrices.PriceMemory.String())
+	if err != nil {
+		return ProtocolParams{}, err
+	}
+	priceStep, err := parseRational(cliParams.ExecutionUnitPrices.PriceSteps.String())
+	if err != nil {
+		return ProtocolParams{}, err
+	}
+
+	return ProtocolParams{
+		MinimumUtxoValue: cliParams.MinUTxOValue,
+		PoolDeposit:      cliParams.StakePoolDeposit,
+		KeyDeposit:       cliParams.StakeAddressDeposit,
+		MinFeeA:          cliParams.TxFeePerByte,
+		MinFeeB:          cliParams.TxFeeFixed,
+		MaxTxSize:        cliParams.MaxTxSize,
+		CoinsPerUTxOByte: cliParams.UTxOCostPerByte,
+		PriceMem:         priceMem,
+		PriceStep:        priceStep,
+		CostModels:       parseCostModels(cliParams.CostModels),
+	}, nil
+}
+
+// QueryTxStatus is not supported, cardano-cli can't look up transactions.
+func (cli *cardanoCli) QueryTxStatus(ctx context.Context, txId TransactionID) (TxStatus, error) {
+	return TxStatus{}, ErrNotSupported
+}
+
+//TODO: add ability to use mainnet and testnet
+func (cli *cardanoCli) QueryRewardAccount(ctx context.Context, stakeAddr Address) (RewardAccount, error) {
+	out, err := runCommand(ctx, "cardano-cli", "query", "stake-address-info", "--address", string(stakeAddr), "--testnet-magic", "1097911063")
+	if err != nil {
+		return RewardAccount{}, err
+	}
+
+	cliInfo := []cardanoCliStakeAddressInfo{}
+	err = json.Unmarshal(out.Bytes(), &cliInfo)
+	if err != nil {
+		return RewardAccount{}, err
+	}
+
+	account := RewardAccount{Address: stakeAddr}
+	if len(cliInfo) > 0 {
+		account.Registered = true
+		account.Balance = cliInfo[0].RewardAccountBalance
+		account.Delegation = cliInfo[0].Delegation
+		if account.Delegation == "" {
+			account.Delegation = cliInfo[0].StakeDelegation
+		}
+	}
+	return account, nil
+}
+
+//TODO: add ability to use mainnet and testnet
+func (cli *cardanoCli) SubmitTx(ctx context.Context, tx Transaction) (TransactionID, error) {
 	txPayload := cardanoCliTx{
 		Type:        "Tx MaryEra",
 		Description: "",
@@ -129,30 +202,60 @@ func (cli *cardanoCli) SubmitTx(tx Transaction) error {
 
 	txPayloadJson, err := json.Marshal(txPayload)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	err = ioutil.WriteFile(txFileName, txPayloadJson, 777)
+	// A file per call, readable by the owner only, so that concurrent
+	// submissions don't overwrite each other
+	txFile, err := os.CreateTemp("", "txsigned-*.json")
 	if err != nil {
-		return err
+		return "", err
+	}
+	defer os.Remove(txFile.Name())
+	_, err = txFile.Write(txPayloadJson)
+	if closeErr := txFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
 	}
 
-	out, err := runCommand("cardano-cli", "transaction", "submit", "--tx-file", txFileName, "--testnet-magic", "1097911063")
-	fmt.Print(out.String())
+	_, err = runCommand(ctx, "cardano-cli", "transaction", "submit", "--tx-file", txFile.Name(), "--testnet-magic", "1097911063")
+	if cliErr, ok := err.(*CliError); ok {
+		return "", submitError(cliErr, cliErr.Stderr)
+	} else if err != nil {
+		return "", err
+	}
 
-	err = os.Remove(txFileName)
+	return tx.ID(), nil
+}
+
+// CliError is returned when a cardano-cli command fails.
+type CliError struct {
+	Args   []string
+	Stderr string
+	Err    error
+}
 
-	return err
+func (e *CliError) Error() string {
+	return fmt.Sprintf("cardano-cli %v: %v: %v", strings.Join(e.Args, " "), e.Err, strings.TrimSpace(e.Stderr))
 }
 
-func runCommand(cmd string, arg ...string) (*bytes.Buffer, error) {
-	out := &bytes.Buffer{}
-	command := exec.Command(cmd, arg...)
+func (e *CliError) Unwrap() error {
+	return e.Err
+}
+
+func runCommand(ctx context.Context, cmd string, arg ...string) (*bytes.Buffer, error) {
+	out, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	command := exec.CommandContext(ctx, cmd, arg...)
 	command.Stdout = out
-	command.Stderr = os.Stderr
+	command.Stderr = stderr
 
 	err := command.Run()
 	if err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			return nil, &CliError{Args: arg, Stderr: stderr.String(), Err: err}
+		}
 		return nil, err
 	}
 
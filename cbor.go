@@ -0,0 +1,112 @@
+package cardano
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+const (
+	cborMajorArray byte = 4
+	cborMajorMap   byte = 5
+	cborMajorTag   byte = 6
+	cborBreak      byte = 0xff
+)
+
+// cborHeader returns the cbor header of a definite length item.
+func cborHeader(major byte, n uint64) []byte {
+	major <<= 5
+	switch {
+	case n < 24:
+		return []byte{major | byte(n)}
+	case n <= 0xff:
+		return []byte{major | 24, byte(n)}
+	case n <= 0xffff:
+		header := []byte{major | 25, 0, 0}
+		binary.BigEndian.PutUint16(header[1:], uint16(n))
+		return header
+	case n <= 0xffffffff:
+		header := []byte{major | 26, 0, 0, 0, 0}
+		binary.BigEndian.PutUint32(header[1:], uint32(n))
+		return header
+	default:
+		header := []byte{major | 27, 0, 0, 0, 0, 0, 0, 0, 0}
+		binary.BigEndian.PutUint64(header[1:], n)
+		return header
+	}
+}
+
+// cborMajor returns the major type of a cbor item.
+func cborMajor(data []byte) byte {
+	if len(data) == 0 {
+		return 0xff
+	}
+	return data[0] >> 5
+}
+
+// cborUntag returns the content of a tagged cbor item, like a set wrapped in
+// tag 258, or the item itself if it isn't tagged.
+func cborUntag(data []byte) []byte {
+	if cborMajor(data) != cborMajorTag {
+		return data
+	}
+	tag := cbor.RawTag{}
+	if err := cbor.Unmarshal(data, &tag); err != nil {
+		return data
+	}
+	return tag.Content
+}
+
+// decodeCborMap calls fn with the raw key and value of each entry of a cbor
+// map. Unlike cbor.Unmarshal it supports byte string keys.
+func decodeCborMap(data []byte, fn func(key, value cbor.RawMessage) error) error {
+	if cborMajor(data) != cborMajorMap {
+		return fmt.Errorf("cbor: expected map, got major type %v", cborMajor(data))
+	}
+
+	info := data[0] & 0x1f
+	offset, length := 1, uint64(0)
+	indefinite := info == 31
+	switch {
+	case info < 24:
+		length = uint64(info)
+	case info == 24 && len(data) >= 2:
+		length, offset = uint64(data[1]), 2
+	case info == 25 && len(data) >= 3:
+		length, offset = uint64(binary.BigEndian.Uint16(data[1:])), 3
+	case info == 26 && len(data) >= 5:
+		length, offset = uint64(binary.BigEndian.Uint32(data[1:])), 5
+	case info == 27 && len(data) >= 9:
+		length, offset = binary.BigEndian.Uint64(data[1:]), 9
+	case indefinite:
+	default:
+		return fmt.Errorf("cbor: malformed map header")
+	}
+
+	rest := data[offset:]
+	dec := cbor.NewDecoder(bytes.NewReader(rest))
+	for i := uint64(0); indefinite || i < length; i++ {
+		if indefinite {
+			read := dec.NumBytesRead()
+			if read >= len(rest) {
+				return fmt.Errorf("cbor: unexpected end of map")
+			}
+			if rest[read] == cborBreak {
+				break
+			}
+		}
+		var key, value cbor.RawMessage
+		if err := dec.Decode(&key); err != nil {
+			return err
+		}
+		if err := dec.Decode(&value); err != nil {
+			return err
+		}
+		if err := fn(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,267 @@
+package cardano
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+	"golang.org/x/crypto/blake2b"
+)
+
+// ErrNoIntersection is returned by ChainFollower.Follow when none of the
+// persisted points is on the chain anymore.
+var ErrNoIntersection = errors.New("chain sync: no intersection with the chain")
+
+// chainFollowerPoints is the number of recent points persisted by a
+// ChainFollower to intersect with the chain.
+const chainFollowerPoints = 16
+
+// ChainPoint identifies a block by its slot and hash. The zero ChainPoint is
+// the origin of the chain.
+type ChainPoint struct {
+	Slot uint64
+	Hash []byte
+}
+
+// IsOrigin returns whether the point is the origin of the chain.
+func (p ChainPoint) IsOrigin() bool {
+	return len(p.Hash) == 0
+}
+
+// Equal returns whether both points identify the same block.
+func (p ChainPoint) Equal(other ChainPoint) bool {
+	return p.Slot == other.Slot && bytes.Equal(p.Hash, other.Hash)
+}
+
+func (p ChainPoint) String() string {
+	if p.IsOrigin() {
+		return "origin"
+	}
+	return fmt.Sprintf("%v.%x", p.Slot, p.Hash)
+}
+
+// Block is a block of a Shelley based era.
+type Block struct {
+	Era    uint64
+	Number uint64
+	Point  ChainPoint
+	// Transactions are the valid transactions of the block, the ones that
+	// failed phase-2 validation are left out.
+	Transactions []Transaction
+	// TxIDs are the ids of the Transactions, hashed from the original body
+	// bytes.
+	TxIDs []TransactionID
+}
+
+// ChainEventType is the direction of a ChainEvent.
+type ChainEventType int
+
+const (
+	RollForward ChainEventType = iota
+	RollBackward
+)
+
+// ChainEvent moves the chain followed by a BlockSource forward by a block, or
+// backward to a point after a fork.
+type ChainEvent struct {
+	Type     ChainEventType
+	Block    *Block     // the new block if rolling forward
+	Point    ChainPoint // the new block or the point rolled back to
+	Tip      ChainPoint
+	TipBlock uint64 // block number of the tip
+}
+
+// ErrCursorLost is returned by a BlockSource whose cursor was reset, like
+// after a reconnection. The cursor must be moved again with FindIntersect.
+var ErrCursorLost = errors.New("chain sync: cursor lost")
+
+// BlockSource is a cursor on the chain.
+type BlockSource interface {
+	// FindIntersect moves the cursor to the most recent of the points that
+	// is on the chain. It returns that point and the tip of the chain, found
+	// being false if none of the points is on the chain.
+	FindIntersect(ctx context.Context, points []ChainPoint) (intersect, tip ChainPoint, found bool, err error)
+	// RequestNext returns the event following the cursor, waiting for a new
+	// block if the cursor is at the tip. It returns ErrCursorLost if the
+	// cursor was reset since the last FindIntersect.
+	RequestNext(ctx context.Context) (ChainEvent, error)
+}
+
+// ChainPointStore persists the recent points of a ChainFollower, the most
+// recent first.
+type ChainPointStore interface {
+	ChainPoints() ([]ChainPoint, error)
+	SaveChainPoints(points []ChainPoint) error
+}
+
+// ChainFollower follows the chain of a BlockSource and persists the points it
+// went through, so that it resumes where it stopped after a restart.
+type ChainFollower struct {
+	source BlockSource
+	store  ChainPointStore
+}
+
+// NewChainFollower creates a ChainFollower of the source persisting its
+// points in store.
+func NewChainFollower(source BlockSource, store ChainPointStore) *ChainFollower {
+	return &ChainFollower{source: source, store: store}
+}
+
+// Follow calls handler with every event of the chain until ctx is done or
+// handler fails. It starts from the most recent persisted point on the chain,
+// or from the tip if there is none.
+//
+// The first event is always a rollback to the starting point. A point is only
+// persisted after handler returns successfully, so events may be replayed
+// after a failure but never skipped; handler must revert the blocks after the
+// point of a rollback. If the source loses its cursor, Follow moves it again
+// to the persisted points, starting with a new rollback.
+func (f *ChainFollower) Follow(ctx context.Context, handler func(ChainEvent) error) error {
+	points, err := f.store.ChainPoints()
+	if err != nil {
+		return err
+	}
+	if len(points) == 0 {
+		_, tip, _, err := f.source.FindIntersect(ctx, nil)
+		if err != nil {
+			return err
+		}
+		points = []ChainPoint{tip}
+	}
+	_, _, found, err := f.source.FindIntersect(ctx, points)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrNoIntersection
+	}
+
+	for {
+		event, err := f.source.RequestNext(ctx)
+		if errors.Is(err, ErrCursorLost) {
+			if _, _, found, err = f.source.FindIntersect(ctx, points); err != nil {
+				return err
+			}
+			if !found {
+				return ErrNoIntersection
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := handler(event); err != nil {
+			return err
+		}
+		switch event.Type {
+		case RollForward:
+			points = append([]ChainPoint{event.Point}, points...)
+		case RollBackward:
+			for len(points) > 0 && points[0].Slot > event.Point.Slot {
+				points = points[1:]
+			}
+			if len(points) == 0 || !points[0].Equal(event.Point) {
+				points = append([]ChainPoint{event.Point}, points...)
+			}
+		}
+		if len(points) > chainFollowerPoints {
+			points = points[:chainFollowerPoints]
+		}
+		if err := f.store.SaveChainPoints(points); err != nil {
+			return err
+		}
+	}
+}
+
+// decodeBlock decodes a block of a Shelley based era. Auxiliary data is not
+// decoded.
+func decodeBlock(era uint64, data []byte) (*Block, error) {
+	if era < eraShelley {
+		return nil, fmt.Errorf("chain sync: byron blocks not supported")
+	}
+	fields := []cbor.RawMessage{}
+	if err := cbor.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	if len(fields) < 4 {
+		return nil, fmt.Errorf("chain sync: malformed block")
+	}
+
+	header := []cbor.RawMessage{}
+	if err := cbor.Unmarshal(fields[0], &header); err != nil {
+		return nil, err
+	}
+	if len(header) != 2 {
+		return nil, fmt.Errorf("chain sync: malformed block header")
+	}
+	// The header body starts with the block number and the slot
+	headerBody := []cbor.RawMessage{}
+	if err := cbor.Unmarshal(header[0], &headerBody); err != nil {
+		return nil, err
+	}
+	if len(headerBody) < 2 {
+		return nil, fmt.Errorf("chain sync: malformed block header")
+	}
+	var number, slot uint64
+	if err := cbor.Unmarshal(headerBody[0], &number); err != nil {
+		return nil, err
+	}
+	if err := cbor.Unmarshal(headerBody[1], &slot); err != nil {
+		return nil, err
+	}
+	hash := blake2b.Sum256(fields[0])
+
+	bodies, witnesses := []cbor.RawMessage{}, []cbor.RawMessage{}
+	if err := cbor.Unmarshal(fields[1], &bodies); err != nil {
+		return nil, err
+	}
+	if err := cbor.Unmarshal(fields[2], &witnesses); err != nil {
+		return nil, err
+	}
+	if len(bodies) != len(witnesses) {
+		return nil, fmt.Errorf("chain sync: malformed block body")
+	}
+	invalid := map[uint64]bool{}
+	if len(fields) > 4 {
+		indexes := []uint64{}
+		if err := cbor.Unmarshal(fields[4], &indexes); err != nil {
+			return nil, err
+		}
+		for _, index := range indexes {
+			invalid[index] = true
+		}
+	}
+
+	block := &Block{
+		Era:    era,
+		Number: number,
+		Point:  ChainPoint{Slot: slot, Hash: hash[:]},
+	}
+	for i := range bodies {
+		if invalid[uint64(i)] {
+			continue
+		}
+		tx := Transaction{}
+		if err := cbor.Unmarshal(bodies[i], &tx.Body); err != nil {
+			return nil, err
+		}
+		// Witness sets with the Conway redeemers map are only decoded up to
+		// their vkey witnesses
+		if err := cbor.Unmarshal(witnesses[i], &tx.WitnessSet); err != nil {
+			vkeys := struct {
+				VKeyWitnessSet []VKeyWitness `cbor:"0,keyasint,omitempty"`
+			}{}
+			if err := cbor.Unmarshal(witnesses[i], &vkeys); err != nil {
+				return nil, err
+			}
+			tx.WitnessSet = TransactionWitnessSet{VKeyWitnessSet: vkeys.VKeyWitnessSet}
+		}
+		txHash := blake2b.Sum256(bodies[i])
+		block.Transactions = append(block.Transactions, tx)
+		block.TxIDs = append(block.TxIDs, TransactionID(hex.EncodeToString(txHash[:])))
+	}
+	return block, nil
+}
@@ -0,0 +1,223 @@
+package cardano
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"golang.org/x/crypto/blake2b"
+)
+
+var errEndOfChain = errors.New("end of chain")
+
+// fakeBlockSource intersects with its chain and then replays its events.
+type fakeBlockSource struct {
+	chain     []ChainPoint
+	events    []ChainEvent
+	intersect []ChainPoint
+	// lost is the number of events after which the cursor is lost once
+	lost int
+}
+
+func (s *fakeBlockSource) FindIntersect(ctx context.Context, points []ChainPoint) (ChainPoint, ChainPoint, bool, error) {
+	s.intersect = points
+	tip := s.chain[len(s.chain)-1]
+	for _, point := range points {
+		for _, chainPoint := range s.chain {
+			if point.Equal(chainPoint) {
+				return point, tip, true, nil
+			}
+		}
+	}
+	return ChainPoint{}, tip, false, nil
+}
+
+func (s *fakeBlockSource) RequestNext(ctx context.Context) (ChainEvent, error) {
+	if len(s.events) == 0 {
+		return ChainEvent{}, errEndOfChain
+	}
+	if s.lost--; s.lost == 0 {
+		return ChainEvent{}, ErrCursorLost
+	}
+	event := s.events[0]
+	s.events = s.events[1:]
+	return event, nil
+}
+
+func testChainPoint(slot uint64, fork byte) ChainPoint {
+	hash := make([]byte, 32)
+	hash[0], hash[31] = byte(slot), fork
+	return ChainPoint{Slot: slot, Hash: hash}
+}
+
+func forward(point ChainPoint) ChainEvent {
+	return ChainEvent{Type: RollForward, Block: &Block{Point: point}, Point: point}
+}
+
+func backward(point ChainPoint) ChainEvent {
+	return ChainEvent{Type: RollBackward, Point: point}
+}
+
+func TestChainFollower(t *testing.T) {
+	p1, p2, p3, p4, p5 := testChainPoint(1, 0), testChainPoint(2, 0), testChainPoint(3, 0), testChainPoint(4, 0), testChainPoint(5, 0)
+	p5b := testChainPoint(5, 1)
+	source := &fakeBlockSource{
+		chain:  []ChainPoint{p1, p2, p3},
+		events: []ChainEvent{backward(p3), forward(p4), forward(p5), backward(p4), forward(p5b)},
+	}
+	store := &memoryChainPoints{}
+
+	// The consumer keeps the blocks of the chain it follows
+	blocks := []ChainPoint{p1, p2, p3}
+	handler := func(event ChainEvent) error {
+		switch event.Type {
+		case RollForward:
+			blocks = append(blocks, event.Point)
+		case RollBackward:
+			for len(blocks) > 0 && blocks[len(blocks)-1].Slot > event.Point.Slot {
+				blocks = blocks[:len(blocks)-1]
+			}
+		}
+		return nil
+	}
+
+	follower := NewChainFollower(source, store)
+	if err := follower.Follow(context.Background(), handler); err != errEndOfChain {
+		t.Fatalf("got error %v want %v", err, errEndOfChain)
+	}
+	if got, want := len(source.intersect), 1; got != want || !source.intersect[0].Equal(p3) {
+		t.Errorf("got intersect %v want the tip %v", source.intersect, p3)
+	}
+	if got, want := blocks, []ChainPoint{p1, p2, p3, p4, p5b}; len(got) != len(want) || !got[4].Equal(want[4]) {
+		t.Errorf("got blocks %v want %v", got, want)
+	}
+
+	points, _ := store.ChainPoints()
+	want := []ChainPoint{p5b, p4, p3}
+	if len(points) != len(want) {
+		t.Fatalf("got points %v want %v", points, want)
+	}
+	for i := range want {
+		if !points[i].Equal(want[i]) {
+			t.Errorf("got points %v want %v", points, want)
+		}
+	}
+
+	// A restarted follower resumes from the persisted points, the failed
+	// event is not persisted
+	source.chain = []ChainPoint{p1, p2, p3, p4, p5b}
+	source.events = []ChainEvent{backward(p5b), forward(testChainPoint(6, 0))}
+	errHandler := errors.New("handler failed")
+	err := NewChainFollower(source, store).Follow(context.Background(), func(event ChainEvent) error {
+		if event.Type == RollForward {
+			return errHandler
+		}
+		return nil
+	})
+	if err != errHandler {
+		t.Fatalf("got error %v want %v", err, errHandler)
+	}
+	if got, want := len(source.intersect), 3; got != want || !source.intersect[0].Equal(p5b) {
+		t.Errorf("got intersect %v want the persisted points", source.intersect)
+	}
+	if points, _ := store.ChainPoints(); !points[0].Equal(p5b) {
+		t.Errorf("got most recent point %v want %v", points[0], p5b)
+	}
+
+	// None of the points is on the chain after a deep rollback
+	source.chain = []ChainPoint{p1}
+	if err := NewChainFollower(source, store).Follow(context.Background(), handler); err != ErrNoIntersection {
+		t.Errorf("got error %v want %v", err, ErrNoIntersection)
+	}
+}
+
+func TestDecodeBlock(t *testing.T) {
+	body := TransactionBody{
+		Inputs:  []TransactionInput{{ID: make([]byte, 32), Index: 1}},
+		Outputs: []TransactionOutput{{Address: make([]byte, 29), Amount: 1000000}},
+		Fee:     170000,
+	}
+	bodyBytes, err := cbor.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A body with a field we don't decode still hashes to its id
+	bodyBytes = append(append([]byte{0xa5}, bodyBytes[1:]...), 0x09, 0xa0)
+
+	header := []interface{}{[]interface{}{uint64(42), uint64(1000), make([]byte, 32)}, make([]byte, 64)}
+	data, err := cbor.Marshal([]interface{}{
+		header,
+		[]cbor.RawMessage{bodyBytes, bodyBytes},
+		[]interface{}{map[uint64]interface{}{}, map[uint64]interface{}{5: map[uint64]interface{}{}}},
+		map[uint64]interface{}{},
+		[]uint64{1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block, err := decodeBlock(eraConway, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	headerBytes, _ := cbor.Marshal(header)
+	hash := blake2b.Sum256(headerBytes)
+	if got, want := block.Point, (ChainPoint{Slot: 1000, Hash: hash[:]}); !got.Equal(want) || block.Number != 42 {
+		t.Errorf("got block %v #%v want %v #42", got, block.Number, want)
+	}
+	// The second transaction is invalid
+	if got, want := len(block.Transactions), 1; got != want {
+		t.Fatalf("got %v transactions want %v", got, want)
+	}
+	txHash := blake2b.Sum256(bodyBytes)
+	if got, want := block.TxIDs[0], TransactionID(hex.EncodeToString(txHash[:])); got != want {
+		t.Errorf("got tx id %v want %v", got, want)
+	}
+	if got, want := block.Transactions[0].Body.Fee, body.Fee; got != want {
+		t.Errorf("got fee %v want %v", got, want)
+	}
+}
+
+func TestChainFollowerCursorLost(t *testing.T) {
+	p1, p2, p3 := testChainPoint(1, 0), testChainPoint(2, 0), testChainPoint(3, 0)
+	source := &fakeBlockSource{
+		chain:  []ChainPoint{p1, p2, p3},
+		events: []ChainEvent{backward(p1), forward(p2), forward(p3)},
+		lost:   3,
+	}
+	store := &memoryChainPoints{points: []ChainPoint{p1}}
+
+	follower := NewChainFollower(source, store)
+	if err := follower.Follow(context.Background(), func(ChainEvent) error { return nil }); err != errEndOfChain {
+		t.Fatalf("got error %v want %v", err, errEndOfChain)
+	}
+	// The cursor is moved again to the points persisted so far
+	if got := source.intersect; len(got) != 2 || !got[0].Equal(p2) || !got[1].Equal(p1) {
+		t.Errorf("got intersect %v want %v", got, []ChainPoint{p2, p1})
+	}
+	if points, _ := store.ChainPoints(); len(points) != 3 || !points[0].Equal(p3) {
+		t.Errorf("got points %v want %v first", points, p3)
+	}
+}
+
+// memoryChainPoints is a ChainPointStore that keeps the points in memory.
+type memoryChainPoints struct {
+	mu     sync.Mutex
+	points []ChainPoint
+}
+
+func (m *memoryChainPoints) ChainPoints() ([]ChainPoint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]ChainPoint{}, m.points...), nil
+}
+
+func (m *memoryChainPoints) SaveChainPoints(points []ChainPoint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.points = append([]ChainPoint{}, points...)
+	return nil
+}
@@ -28,7 +28,11 @@ var balanceCmd = &cobra.Command{
 			return err
 		}
 		w.SetNetwork(network)
-		balance, err := w.Balance()
+		account, err := walletAccount(cmd, w)
+		if err != nil {
+			return err
+		}
+		balance, err := account.Balance()
 		fmt.Printf("%-25v %-9v\n", "ASSET", "AMOUNT")
 		fmt.Printf("%-25v %-9v\n", "Lovelace", balance)
 		return err
@@ -38,4 +42,5 @@ var balanceCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(balanceCmd)
 	balanceCmd.Flags().Bool("testnet", false, "Use testnet network")
+	balanceCmd.Flags().Uint32("account", 0, "Index of the wallet account")
 }
@@ -1 +1,16 @@
 package cmd
+
+import (
+	"github.com/qredo/cardano-go"
+	"github.com/spf13/cobra"
+)
+
+// walletAccount returns the account of the wallet selected by the account
+// flag.
+func walletAccount(cmd *cobra.Command, w *cardano.Wallet) (*cardano.Account, error) {
+	index, err := cmd.Flags().GetUint32("account")
+	if err != nil {
+		return nil, err
+	}
+	return w.AccountAt(index)
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/qredo/cardano-go"
+	"github.com/spf13/cobra"
+)
+
+// listAccountCmd represents the listAccount command
+var listAccountCmd = &cobra.Command{
+	Use:     "list-account [wallet-id]",
+	Short:   "Print a list of wallet's accounts",
+	Aliases: []string{"lsacc"},
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client := cardano.NewClient()
+		defer client.Close()
+
+		useTestnet, err := cmd.Flags().GetBool("testnet")
+		network := cardano.Mainnet
+		if useTestnet {
+			network = cardano.Testnet
+		}
+
+		id := args[0]
+		w, err := client.Wallet(id)
+		if err != nil {
+			return err
+		}
+		w.SetNetwork(network)
+
+		fmt.Printf("%-18v %-9v %-9v\n", "PATH", "ADDRESS", "STAKE ADDRESS")
+		for _, account := range w.Accounts() {
+			stakeAddress, err := account.StakeAddress()
+			if err != nil {
+				stakeAddress = "-"
+			}
+			path := fmt.Sprintf("m/1852'/1815'/%v'", account.Index)
+			fmt.Printf("%-18v %-9v %-9v\n", path, len(account.Addresses()), stakeAddress)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(listAccountCmd)
+	listAccountCmd.Flags().Bool("testnet", false, "Use testnet network")
+}
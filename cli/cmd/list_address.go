@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"strconv"
 
 	"github.com/qredo/cardano-go"
 	"github.com/spf13/cobra"
@@ -30,11 +29,15 @@ var listAddressCmd = &cobra.Command{
 			return err
 		}
 		w.SetNetwork(network)
+		account, err := walletAccount(cmd, w)
+		if err != nil {
+			return err
+		}
 
-		addresses := w.Addresses()
+		addresses := account.Addresses()
 		fmt.Printf("%-25v %-9v\n", "PATH", "ADDRESS")
 		for i, addr := range addresses {
-			fmt.Printf("%-25v %-9v\n", "m/1852'/1815'/0'/0/"+strconv.Itoa(i), addr)
+			fmt.Printf("%-25v %-9v\n", fmt.Sprintf("m/1852'/1815'/%v'/0/%v", account.Index, i), addr)
 		}
 		return nil
 	},
@@ -43,4 +46,5 @@ var listAddressCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(listAddressCmd)
 	listAddressCmd.Flags().Bool("testnet", false, "Use testnet network")
+	listAddressCmd.Flags().Uint32("account", 0, "Index of the wallet account")
 }
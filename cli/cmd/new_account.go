@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/qredo/cardano-go"
+	"github.com/spf13/cobra"
+)
+
+// newAccountCmd represents the newAccount command
+var newAccountCmd = &cobra.Command{
+	Use:     "new-account [wallet-id]",
+	Short:   "Create a new wallet account",
+	Args:    cobra.ExactArgs(1),
+	Aliases: []string{"newacc"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client := cardano.NewClient()
+		defer client.Close()
+
+		id := args[0]
+		w, err := client.Wallet(id)
+		if err != nil {
+			return err
+		}
+		account, err := w.AddAccount()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("account: %v\n", account.Index)
+
+		return client.SaveWallet(w)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(newAccountCmd)
+}
@@ -27,14 +27,18 @@ var newAddressCmd = &cobra.Command{
 			return err
 		}
 		w.SetNetwork(network)
-		w.AddAddress()
-		client.SaveWallet(w)
+		account, err := walletAccount(cmd, w)
+		if err != nil {
+			return err
+		}
+		account.AddAddress()
 
-		return nil
+		return client.SaveWallet(w)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(newAddressCmd)
 	newAddressCmd.Flags().Bool("testnet", false, "Use testnet network")
+	newAddressCmd.Flags().Uint32("account", 0, "Index of the wallet account")
 }
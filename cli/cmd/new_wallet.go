@@ -12,7 +12,8 @@ var newWalletCmd = &cobra.Command{
 	Use:   "new-wallet [wallet-name]",
 	Short: "Create or restore a wallet",
 	Long: `Create or restore a wallet. If the mnemonic flag is present 
-it will restore a wallet using the mnemonic and password.`,
+it will restore a wallet using the mnemonic and password. If the account-key
+flag is present it will create a watch-only wallet from the account key.`,
 	Aliases: []string{"neww"},
 	Args:    cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
@@ -20,8 +21,24 @@ it will restore a wallet using the mnemonic and password.`,
 		defer client.Close()
 		password, _ := cmd.Flags().GetString("password")
 		mnemonic, _ := cmd.Flags().GetStringSlice("mnemonic")
+		accountKey, _ := cmd.Flags().GetString("account-key")
 		name := args[0]
 
+		gapLimit, _ := cmd.Flags().GetInt("gap-limit")
+		opts := []cardano.RestoreOption{cardano.WithGapLimit(gapLimit)}
+		// The network is the one of the node unless set
+		if cmd.Flags().Changed("testnet") {
+			network := cardano.Mainnet
+			if useTestnet, _ := cmd.Flags().GetBool("testnet"); useTestnet {
+				network = cardano.Testnet
+			}
+			opts = append(opts, cardano.WithRestoreNetwork(network))
+		}
+
+		if accountKey != "" {
+			_, err := client.WatchWallet(name, accountKey, opts...)
+			return err
+		}
 		if len(mnemonic) == 0 {
 			_, mnemonic, err := client.CreateWallet(name, password)
 			if err != nil {
@@ -29,7 +46,7 @@ it will restore a wallet using the mnemonic and password.`,
 			}
 			fmt.Printf("mnemonic: %v\n", mnemonic)
 		} else {
-			_, err := client.RestoreWallet(name, password, strings.Join(mnemonic, " "))
+			_, err := client.RestoreWallet(name, password, strings.Join(mnemonic, " "), opts...)
 			if err != nil {
 				return err
 			}
@@ -43,4 +60,7 @@ func init() {
 
 	newWalletCmd.Flags().StringP("password", "p", "", "A list of mnemonic words")
 	newWalletCmd.Flags().StringSliceP("mnemonic", "m", nil, "Password to lock and protect the wallet")
+	newWalletCmd.Flags().String("account-key", "", "Account extended verification key (acct_xvk) of a watch-only wallet")
+	newWalletCmd.Flags().Int("gap-limit", cardano.DefaultGapLimit, "Number of consecutive unused addresses ending the address discovery of a restored wallet")
+	newWalletCmd.Flags().Bool("testnet", false, "Use testnet network, or mainnet if false, instead of the node network")
 }
@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"fmt"
+
 	"github.com/qredo/cardano-go"
 	"github.com/spf13/cobra"
 )
@@ -8,28 +10,60 @@ import (
 // TODO: Ask for password if present
 // Experimental feature, only for testnet
 var transferCmd = &cobra.Command{
-	Use:   "transfer [wallet-id] [amount] [receiver-address]",
-	Short: "Transfer an amount of lovelace to the given address",
+	Use:   "transfer [wallet-id] [amount|all] [receiver-address]",
+	Short: "Transfer an amount of lovelace, or the whole balance, to the given address",
 	Args:  cobra.ExactArgs(3),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		client := cardano.NewClient()
 		defer client.Close()
 		senderId := args[0]
 		receiver := cardano.Address(args[2])
-		amount, err := cardano.ParseUint64(args[1])
+		w, err := client.Wallet(senderId)
 		if err != nil {
 			return err
 		}
-		w, err := client.Wallet(senderId)
+		w.SetNetwork(cardano.Testnet)
+		account, err := walletAccount(cmd, w)
 		if err != nil {
 			return err
 		}
-		w.SetNetwork(cardano.Testnet)
-		err = w.Transfer(receiver, amount)
-		return err
+
+		if unsigned, _ := cmd.Flags().GetBool("unsigned"); unsigned {
+			amount, err := cardano.ParseUint64(args[1])
+			if err != nil {
+				return err
+			}
+			tx, err := account.BuildTransfer(receiver, amount)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("unsigned transaction: %x\n", tx.Bytes())
+			return client.SaveWallet(w)
+		}
+
+		var txId cardano.TransactionID
+		if args[1] == "all" {
+			txId, err = account.TransferAll(receiver)
+		} else {
+			var amount uint64
+			amount, err = cardano.ParseUint64(args[1])
+			if err != nil {
+				return err
+			}
+			txId, err = account.Transfer(receiver, amount)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("transaction: %v\n", txId)
+
+		// Keep the change address of the transaction
+		return client.SaveWallet(w)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(transferCmd)
+	transferCmd.Flags().Uint32("account", 0, "Index of the wallet account")
+	transferCmd.Flags().Bool("unsigned", false, "Print the unsigned transaction instead of submitting it, for watch-only wallets")
 }
@@ -1,17 +1,26 @@
 package cardano
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"sync"
 
+	"github.com/echovl/bech32"
 	"github.com/qredo/cardano-go/crypto"
 	"github.com/tyler-smith/go-bip39"
 )
 
 // Client provides a clean interface for creating, saving and deleting Wallets.
 type Client struct {
-	db         DB
-	node       cardanoNode
-	socketPath string
+	db   DB
+	node ChainBackend
+
+	lockersMu sync.Mutex
+	lockers   map[string]*UtxoLocker
+
+	// walletsMu serializes the updates of saved wallets.
+	walletsMu sync.Mutex
 }
 
 // NewClient builds a new Client using cardano-cli as the default connection
@@ -29,9 +38,13 @@ func NewClient(opts ...Options) *Client {
 	return client
 }
 
-// Close closes all the resources used by the Client.
+// Close closes all the resources used by the Client, its backend included if
+// it can be closed, like NodeSocket and Ogmios.
 func (c *Client) Close() {
 	c.db.Close()
+	if closer, ok := c.node.(io.Closer); ok {
+		closer.Close()
+	}
 }
 
 // CreateWallet creates a new Wallet using a secure entropy and password,
@@ -45,27 +58,137 @@ func (c *Client) CreateWallet(name, password string) (*Wallet, string, error) {
 	if err != nil {
 		return nil, "", err
 	}
+	if wallet.locker, err = c.utxoLocker(wallet.ID); err != nil {
+		return nil, "", err
+	}
+	wallet.save = c.updateWallet
 	return wallet, mnemonic, nil
 }
 
-// RestoreWallet restores a Wallet from a mnemonic and password.
-func (c *Client) RestoreWallet(name, password, mnemonic string) (*Wallet, error) {
+// RestoreWallet restores a Wallet from a mnemonic and password. The
+// accounts and addresses used on chain are found by address discovery, which
+// stops after DefaultGapLimit consecutive unused addresses unless set
+// otherwise. Discovery queries the Client's node, so the restore fails if it
+// can't be reached; WithGapLimit(0) restores the first address only, without
+// any query. The network is taken from the Client's backend if it's a
+// NetworkBackend, and must be set by WithRestoreNetwork otherwise.
+func (c *Client) RestoreWallet(name, password, mnemonic string, opts ...RestoreOption) (*Wallet, error) {
+	options := &restoreOptions{gapLimit: DefaultGapLimit}
+	for _, opt := range opts {
+		opt(options)
+	}
+	network, err := c.restoreNetwork(options)
+	if err != nil {
+		return nil, err
+	}
 	entropy, err := bip39.EntropyFromMnemonic(mnemonic)
 	if err != nil {
 		return nil, err
 	}
 	wallet := newWallet(name, password, entropy)
 	wallet.node = c.node
+	wallet.SetNetwork(network)
+	if options.gapLimit > 0 {
+		if err := wallet.DiscoverAccounts(context.Background(), options.gapLimit); err != nil {
+			return nil, err
+		}
+	}
 	err = c.db.SaveWallet(wallet)
 	if err != nil {
 		return nil, err
 	}
+	if wallet.locker, err = c.utxoLocker(wallet.ID); err != nil {
+		return nil, err
+	}
+	wallet.save = c.updateWallet
 
 	return wallet, nil
 }
 
-// SaveWallet saves a Wallet in the Client's storage.
+// WatchWallet creates a watch-only Wallet from a bech32 encoded account
+// extended verification key (acct_xvk). It tracks the balance and history of
+// the account and builds unsigned transactions, but returns ErrWatchOnly when
+// asked to sign unless given a Signer by Wallet.SetSigner. Its used addresses
+// are found by address discovery, as in RestoreWallet.
+func (c *Client) WatchWallet(name, accountKey string, opts ...RestoreOption) (*Wallet, error) {
+	options := &restoreOptions{gapLimit: DefaultGapLimit}
+	for _, opt := range opts {
+		opt(options)
+	}
+	hrp, accountXvk, err := bech32.DecodeToBase256(accountKey)
+	if err != nil {
+		return nil, err
+	}
+	if hrp != accountKeyHrp {
+		return nil, fmt.Errorf("invalid account key prefix %v, want %v", hrp, accountKeyHrp)
+	}
+	network, err := c.restoreNetwork(options)
+	if err != nil {
+		return nil, err
+	}
+	wallet, err := newWatchOnlyWallet(name, accountXvk)
+	if err != nil {
+		return nil, err
+	}
+	wallet.node = c.node
+	wallet.SetNetwork(network)
+	if options.gapLimit > 0 {
+		if err := wallet.DiscoverAccounts(context.Background(), options.gapLimit); err != nil {
+			return nil, err
+		}
+	}
+	err = c.db.SaveWallet(wallet)
+	if err != nil {
+		return nil, err
+	}
+	if wallet.locker, err = c.utxoLocker(wallet.ID); err != nil {
+		return nil, err
+	}
+	wallet.save = c.updateWallet
+
+	return wallet, nil
+}
+
+// restoreNetwork returns the network of a restored wallet, set by the options
+// or by the Client's backend.
+func (c *Client) restoreNetwork(options *restoreOptions) (Network, error) {
+	backend, ok := c.node.(NetworkBackend)
+	switch {
+	case options.network == nil && !ok:
+		return 0, fmt.Errorf("unknown network of the restored wallet, set it with WithRestoreNetwork")
+	case options.network == nil:
+		return backend.Network(), nil
+	case ok && backend.Network() != *options.network:
+		return 0, fmt.Errorf("%w: wallet of network %v, backend of network %v", ErrNetworkMismatch, *options.network, backend.Network())
+	}
+	return *options.network, nil
+}
+
+// SaveWallet saves a Wallet in the Client's storage. Keys saved meanwhile by
+// other Wallets with the same id are added to it first.
 func (c *Client) SaveWallet(w *Wallet) error {
+	return c.updateWallet(w, func() {})
+}
+
+// updateWallet runs update, which derives keys of the wallet, and saves the
+// wallet. The keys saved by other Wallets with the same id are added to the
+// wallet first, so that the update doesn't derive them again and the save
+// doesn't drop them. Wallets derive their change addresses this way before
+// submitting transactions.
+func (c *Client) updateWallet(w *Wallet, update func()) error {
+	c.walletsMu.Lock()
+	defer c.walletsMu.Unlock()
+
+	wallets, err := c.db.GetWallets()
+	if err != nil {
+		return err
+	}
+	for _, stored := range wallets {
+		if stored.ID == w.ID {
+			w.catchUp(stored)
+		}
+	}
+	update()
 	return c.db.SaveWallet(w)
 }
 
@@ -77,10 +200,34 @@ func (c *Client) Wallets() ([]*Wallet, error) {
 	}
 	for i := range wallets {
 		wallets[i].node = c.node
+		if wallets[i].locker, err = c.utxoLocker(wallets[i].ID); err != nil {
+			return nil, err
+		}
+		wallets[i].save = c.updateWallet
 	}
 	return wallets, nil
 }
 
+// utxoLocker returns the UtxoLocker of a wallet, shared by all the Wallets
+// with its id.
+func (c *Client) utxoLocker(walletID string) (*UtxoLocker, error) {
+	c.lockersMu.Lock()
+	defer c.lockersMu.Unlock()
+
+	if locker, ok := c.lockers[walletID]; ok {
+		return locker, nil
+	}
+	locker, err := NewUtxoLocker(walletID, c.db)
+	if err != nil {
+		return nil, err
+	}
+	if c.lockers == nil {
+		c.lockers = map[string]*UtxoLocker{}
+	}
+	c.lockers[walletID] = locker
+	return locker, nil
+}
+
 // Wallet returns a Wallet with the given id from the Client's storage.
 func (c *Client) Wallet(id string) (*Wallet, error) {
 	wallets, err := c.Wallets()
@@ -95,6 +242,23 @@ func (c *Client) Wallet(id string) (*Wallet, error) {
 	return nil, fmt.Errorf("wallet %v not found", id)
 }
 
+// ChainFollower returns a ChainFollower of the source, its points persisted
+// in the Client's storage.
+func (c *Client) ChainFollower(source BlockSource) *ChainFollower {
+	return NewChainFollower(source, c.db)
+}
+
+// WalletIndexer returns a WalletIndexer of the wallet, its state persisted in
+// the Client's storage.
+func (c *Client) WalletIndexer(w *Wallet) (*WalletIndexer, error) {
+	return NewWalletIndexer(w, c.db)
+}
+
+// TxTracker returns a TxTracker polling the Client's node.
+func (c *Client) TxTracker(opts ...TxTrackerOption) *TxTracker {
+	return NewTxTracker(c.node, opts...)
+}
+
 // DeleteWallet removes a Wallet with the given id from the Client's storage.
 func (c *Client) DeleteWallet(id string) error {
 	return c.db.DeleteWallet(id)
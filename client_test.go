@@ -1,8 +1,11 @@
 package cardano
 
 import (
+	"errors"
+	"sync"
 	"testing"
 
+	"github.com/qredo/cardano-go/crypto"
 	"github.com/tyler-smith/go-bip39"
 )
 
@@ -53,7 +56,11 @@ var testVectors = []TestVector{
 	},
 }
 
+// MockDB is a DB that doesn't store the wallets.
 type MockDB struct {
+	memoryUtxoLocks
+	memoryChainPoints
+	memoryWalletIndex
 	calls int
 }
 
@@ -76,6 +83,52 @@ func (db *MockDB) DeleteWallet(id string) error {
 	return nil
 }
 
+// memoryDB is a DB keeping the wallet dumps in memory.
+type memoryDB struct {
+	memoryUtxoLocks
+	memoryChainPoints
+	memoryWalletIndex
+	mu      sync.Mutex
+	wallets map[string][]byte
+}
+
+func (db *memoryDB) SaveWallet(w *Wallet) error {
+	data, err := w.marshal()
+	if err != nil {
+		return err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.wallets == nil {
+		db.wallets = map[string][]byte{}
+	}
+	db.wallets[w.ID] = data
+	return nil
+}
+
+func (db *memoryDB) GetWallets() ([]*Wallet, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	wallets := []*Wallet{}
+	for _, data := range db.wallets {
+		w := &Wallet{}
+		if err := w.unmarshal(data); err != nil {
+			return nil, err
+		}
+		wallets = append(wallets, w)
+	}
+	return wallets, nil
+}
+
+func (db *memoryDB) DeleteWallet(id string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	delete(db.wallets, id)
+	return nil
+}
+
+func (db *memoryDB) Close() {}
+
 func TestCreateWallet(t *testing.T) {
 	for _, testVector := range testVectors {
 		client := NewClient(WithDB(&MockDB{}))
@@ -112,22 +165,26 @@ func TestCreateWallet(t *testing.T) {
 			t.Errorf("invalid mnemonic:\ngot: %v\nwant: %v", mnemonic, testVector.mnemonic)
 		}
 
-		if addresses[0] != testVector.paymentAddr0 {
-			t.Errorf("invalid paymentAddr0:\ngot: %v\nwant: %v", addresses[0], testVector.paymentAddr0)
+		xvk0 := w.skeys[0].ExtendedVerificationKey()
+		if paymentAddr0 := NewEnterpriseAddress(xvk0, Testnet); paymentAddr0 != testVector.paymentAddr0 {
+			t.Errorf("invalid paymentAddr0:\ngot: %v\nwant: %v", paymentAddr0, testVector.paymentAddr0)
+		}
+
+		if want := NewBaseAddress(xvk0, w.stakeKey.ExtendedVerificationKey(), Testnet); addresses[0] != want {
+			t.Errorf("invalid address 0:\ngot: %v\nwant: %v", addresses[0], want)
 		}
 	}
 }
 
 func TestRestoreWallet(t *testing.T) {
 	for _, testVector := range testVectors {
-		client := NewClient(WithDB(&MockDB{}))
+		client := NewClient(WithDB(&MockDB{}), WithNode(NewEmulator(ShelleyProtocol, nil)))
 		defer client.Close()
 
-		w, err := client.RestoreWallet("test", "", testVector.mnemonic)
+		w, err := client.RestoreWallet("test", "", testVector.mnemonic, WithRestoreNetwork(Testnet))
 		if err != nil {
-			t.Error(err)
+			t.Fatal(err)
 		}
-		w.SetNetwork(Testnet)
 
 		addrXsk0 := bech32From("addr_xsk", w.skeys[0])
 		addrXvk0 := bech32From("addr_xvk", w.skeys[0].ExtendedVerificationKey())
@@ -142,8 +199,137 @@ func TestRestoreWallet(t *testing.T) {
 
 		addresses := w.Addresses()
 
-		if addresses[0] != testVector.paymentAddr0 {
-			t.Errorf("invalid paymentAddr0:\ngot: %v\nwant: %v", addresses[0], testVector.paymentAddr0)
+		xvk0 := w.skeys[0].ExtendedVerificationKey()
+		if paymentAddr0 := NewEnterpriseAddress(xvk0, Testnet); paymentAddr0 != testVector.paymentAddr0 {
+			t.Errorf("invalid paymentAddr0:\ngot: %v\nwant: %v", paymentAddr0, testVector.paymentAddr0)
 		}
+
+		if want := NewBaseAddress(xvk0, w.stakeKey.ExtendedVerificationKey(), Testnet); addresses[0] != want {
+			t.Errorf("invalid address 0:\ngot: %v\nwant: %v", addresses[0], want)
+		}
+	}
+}
+
+func TestClientWalletChangeSaved(t *testing.T) {
+	client := NewClient(WithDB(&memoryDB{}), WithNode(NewEmulator(ShelleyProtocol, nil)))
+	defer client.Close()
+	created, _, err := client.CreateWallet("test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	created.SetNetwork(Testnet)
+	emulator := NewEmulator(ShelleyProtocol, map[Address]uint64{created.Addresses()[0]: 100 * ShelleyProtocol.MinimumUtxoValue})
+	client.node = emulator
+
+	// Two instances of the wallet loaded at once transfer without saving it
+	instances := []*Wallet{}
+	for i := 0; i < 2; i++ {
+		w, err := client.Wallet(created.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.SetNetwork(Testnet)
+		instances = append(instances, w)
+	}
+	receiverKey := crypto.NewExtendedSigningKey([]byte("receiver"), "")
+	receiver := NewEnterpriseAddress(receiverKey.ExtendedVerificationKey(), Testnet)
+	var fees uint64
+	for _, w := range instances {
+		txId, err := w.Transfer(receiver, 10*ShelleyProtocol.MinimumUtxoValue)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx, err := emulator.Transaction(txId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fees += tx.Body.Fee
+	}
+
+	w, err := client.Wallet(created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.SetNetwork(Testnet)
+	if changeAddresses := w.ChangeAddresses(); len(changeAddresses) != 2 || changeAddresses[0] == changeAddresses[1] {
+		t.Errorf("got change addresses %v want 2 distinct ones", changeAddresses)
+	}
+	want := 80*ShelleyProtocol.MinimumUtxoValue - fees
+	if balance, err := w.Balance(); err != nil || balance != want {
+		t.Errorf("got balance %v, %v want %v", balance, err, want)
+	}
+}
+
+// networkBackend is an Emulator bound to a network.
+type networkBackend struct {
+	*Emulator
+	network Network
+}
+
+func (n networkBackend) Network() Network {
+	return n.network
+}
+
+func TestRestoreWalletNetwork(t *testing.T) {
+	mnemonic := testVectors[0].mnemonic
+	emulator := NewEmulator(ShelleyProtocol, nil)
+	tests := []struct {
+		name    string
+		node    ChainBackend
+		opts    []RestoreOption
+		want    Network
+		wantErr bool
+	}{
+		{name: "set", node: emulator, opts: []RestoreOption{WithRestoreNetwork(Mainnet)}, want: Mainnet},
+		{name: "unknown", node: emulator, wantErr: true},
+		{name: "from the backend", node: networkBackend{emulator, Mainnet}, want: Mainnet},
+		{name: "matching the backend", node: networkBackend{emulator, Testnet}, opts: []RestoreOption{WithRestoreNetwork(Testnet)}, want: Testnet},
+		{name: "mismatch", node: networkBackend{emulator, Testnet}, opts: []RestoreOption{WithRestoreNetwork(Mainnet)}, wantErr: true},
+		{name: "mainnet socket", node: NewNodeSocket("node.socket", MainnetMagic), opts: []RestoreOption{WithGapLimit(0)}, want: Mainnet},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient(WithDB(&MockDB{}), WithNode(tt.node))
+			defer client.Close()
+
+			w, err := client.RestoreWallet("test", "", mnemonic, tt.opts...)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("got wallet of network %v want error", w.network)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := w.network, tt.want; got != want {
+				t.Errorf("got network %v want %v", got, want)
+			}
+		})
+	}
+	client := NewClient(WithDB(&MockDB{}), WithNode(networkBackend{emulator, Testnet}))
+	defer client.Close()
+	if _, err := client.RestoreWallet("test", "", mnemonic, WithRestoreNetwork(Mainnet)); !errors.Is(err, ErrNetworkMismatch) {
+		t.Errorf("got error %v want %v", err, ErrNetworkMismatch)
+	}
+}
+
+// closingBackend is an Emulator recording whether it was closed.
+type closingBackend struct {
+	*Emulator
+	closed bool
+}
+
+func (c *closingBackend) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestClientClose(t *testing.T) {
+	node := &closingBackend{Emulator: NewEmulator(ShelleyProtocol, nil)}
+	client := NewClient(WithDB(&MockDB{}), WithNode(node))
+	client.Close()
+	if !node.closed {
+		t.Error("got open backend after closing the client")
 	}
 }
@@ -0,0 +1,130 @@
+package cardano
+
+import (
+	"math/rand"
+
+	"github.com/qredo/cardano-go/coinselection"
+)
+
+// txInputSize is the estimated size in bytes of an input and its vkey
+// witness, used to bound the number of inputs by the transaction size limit.
+const txInputSize = 140
+
+// selectCoins completes the body with inputs picked from utxos by
+// Random-Improve, a change output to the change address and the fee of a
+// transaction carrying the metadata, if any. It returns the picked utxos,
+// whose keys must witness the transaction. rnd is the source of randomness of
+// the selection, seeded from the current time if nil.
+func selectCoins(body TransactionBody, metadata transactionMetadata, utxos []Utxo, changeAddress Address, protocol ProtocolParams, rnd *rand.Rand) ([]Utxo, TransactionBody, error) {
+	if len(metadata) > 0 {
+		body.MetadataHash = metadata.hash()
+	}
+	byID := map[string]Utxo{}
+	available := make([]coinselection.Input, len(utxos))
+	for i, utxo := range utxos {
+		id := utxoKey(utxo.TxId.Bytes(), utxo.Index)
+		byID[id] = utxo
+		available[i] = coinselection.Input{ID: id, Value: selectionValue(Value{Coin: utxo.Amount, Assets: utxo.Assets})}
+	}
+	outputs := make([]coinselection.Value, len(body.Outputs))
+	for i, output := range body.Outputs {
+		outputs[i] = selectionValue(output.Value())
+	}
+
+	// complete returns the body spending the inputs and paying the change
+	complete := func(inputs []coinselection.Input, change []coinselection.Value, fee uint64) TransactionBody {
+		completed := body
+		completed.Inputs = make([]TransactionInput, len(inputs))
+		for i, input := range inputs {
+			utxo := byID[input.ID]
+			completed.Inputs[i] = TransactionInput{ID: utxo.TxId.Bytes(), Index: utxo.Index}
+		}
+		completed.Outputs = append([]TransactionOutput{}, body.Outputs...)
+		for _, value := range change {
+			completed.Outputs = append(completed.Outputs, changeOutput(changeAddress, value))
+		}
+		completed.Fee = fee
+		return completed
+	}
+
+	selection, err := coinselection.RandomImprove(coinselection.Params{
+		Available: available,
+		Outputs:   outputs,
+		MaxInputs: maxInputs(complete(nil, []coinselection.Value{{}}, 0), metadata, protocol),
+		Fee: func(inputs []coinselection.Input, change []coinselection.Value) uint64 {
+			// Set a temporary realistic fee in order to serialize a valid transaction
+			completed := complete(inputs, change, 200000)
+			spent := make([]Utxo, len(inputs))
+			for i, input := range inputs {
+				spent[i] = byID[input.ID]
+			}
+			return completed.calculateMinFee(protocol, TransactionWitnessSet{}, witnessCount(spent), metadata)
+		},
+		MinCoin: func(change coinselection.Value) uint64 {
+			return minUtxoValue(changeOutput(changeAddress, change), protocol)
+		},
+		Rand: rnd,
+	})
+	if err != nil {
+		return nil, TransactionBody{}, err
+	}
+
+	picked := make([]Utxo, len(selection.Inputs))
+	for i, input := range selection.Inputs {
+		picked[i] = byID[input.ID]
+	}
+	return picked, complete(selection.Inputs, selection.Change, selection.Fee), nil
+}
+
+// maxInputs returns how many inputs the body can spend without exceeding the
+// transaction size limit, or zero if there's no limit.
+func maxInputs(body TransactionBody, metadata transactionMetadata, protocol ProtocolParams) int {
+	if protocol.MaxTxSize == 0 {
+		return 0
+	}
+	body.Fee = 200000
+	tx := &Transaction{Body: body}
+	if len(metadata) > 0 {
+		tx.Metadata = &metadata
+	}
+	size := uint64(len(tx.Bytes()))
+	if size+txInputSize > protocol.MaxTxSize {
+		return 1
+	}
+	return int((protocol.MaxTxSize - size) / txInputSize)
+}
+
+func changeOutput(address Address, value coinselection.Value) TransactionOutput {
+	change := cardanoValue(value)
+	return TransactionOutput{Address: address.Bytes(), Amount: change.Coin, Assets: change.Assets}
+}
+
+// selectionValue converts a value to the coinselection one.
+func selectionValue(value Value) coinselection.Value {
+	converted := coinselection.Value{Coin: value.Coin}
+	if value.Assets.IsEmpty() {
+		return converted
+	}
+	converted.Assets = map[coinselection.AssetID]uint64{}
+	for policyID, assets := range value.Assets {
+		for assetName, quantity := range assets {
+			converted.Assets[coinselection.AssetID{PolicyID: policyID, Name: assetName}] = quantity
+		}
+	}
+	return converted
+}
+
+// cardanoValue converts a coinselection value back.
+func cardanoValue(value coinselection.Value) Value {
+	converted := Value{Coin: value.Coin}
+	for asset, quantity := range value.Assets {
+		if quantity == 0 {
+			continue
+		}
+		if converted.Assets == nil {
+			converted.Assets = MultiAsset{}
+		}
+		converted.Assets.set(asset.PolicyID, asset.Name, quantity)
+	}
+	return converted
+}
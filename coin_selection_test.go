@@ -0,0 +1,68 @@
+package cardano
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/qredo/cardano-go/crypto"
+)
+
+func TestSelectCoins(t *testing.T) {
+	key := crypto.NewExtendedSigningKey([]byte("coin selection"), "")
+	changeAddress := NewEnterpriseAddress(key.ExtendedVerificationKey(), Testnet)
+	receiverKey := crypto.NewExtendedSigningKey([]byte("receiver"), "")
+	receiver := NewEnterpriseAddress(receiverKey.ExtendedVerificationKey(), Testnet)
+	protocol := ProtocolParams{MinFeeA: 44, MinFeeB: 155381, MaxTxSize: 16384, CoinsPerUTxOByte: 4310}
+	token := NewMultiAsset("00", "74657374", 10)
+
+	utxos := []Utxo{}
+	for i := uint64(0); i < 10; i++ {
+		utxos = append(utxos, testLockerUtxo(byte(i), i, 3000000))
+	}
+	tokenUtxo := testLockerUtxo(10, 0, 2000000)
+	tokenUtxo.Assets = token
+	utxos = append(utxos, tokenUtxo)
+
+	tests := []struct {
+		name    string
+		outputs []TransactionOutput
+	}{
+		{
+			name:    "lovelace",
+			outputs: []TransactionOutput{{Address: receiver.Bytes(), Amount: 5000000}},
+		},
+		{
+			name:    "token",
+			outputs: []TransactionOutput{{Address: receiver.Bytes(), Amount: 2000000, Assets: NewMultiAsset("00", "74657374", 4)}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for seed := int64(0); seed < 10; seed++ {
+				body := TransactionBody{Outputs: tt.outputs, Ttl: 1000}
+				picked, body, err := selectCoins(body, nil, utxos, changeAddress, protocol, rand.New(rand.NewSource(seed)))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				spent := Value{}
+				for _, utxo := range picked {
+					spent = spent.Add(Value{Coin: utxo.Amount, Assets: utxo.Assets})
+				}
+				paid := Value{Coin: body.Fee}
+				for _, output := range body.Outputs {
+					paid = paid.Add(output.Value())
+					if min := minUtxoValue(output, protocol); output.Amount < min {
+						t.Errorf("seed %v: got output of %v want at least %v", seed, output.Amount, min)
+					}
+				}
+				if !spent.Equal(paid) {
+					t.Errorf("seed %v: got %v spent and %v paid", seed, spent, paid)
+				}
+				if min := body.calculateMinFee(protocol, TransactionWitnessSet{}, witnessCount(picked), nil); body.Fee < min {
+					t.Errorf("seed %v: got fee %v want at least %v", seed, body.Fee, min)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,277 @@
+// Package coinselection implements the coin selection algorithms of CIP-2,
+// Largest-First and Random-Improve, which pick the outputs of a wallet that a
+// transaction spends.
+package coinselection
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+)
+
+var (
+	// ErrBalanceInsufficient is returned when the available inputs can't pay
+	// for the outputs and the fee.
+	ErrBalanceInsufficient = errors.New("coin selection: balance insufficient")
+	// ErrMaxInputCountExceeded is returned when paying for the outputs and the
+	// fee takes more inputs than allowed.
+	ErrMaxInputCountExceeded = errors.New("coin selection: maximum input count exceeded")
+)
+
+// Input is an unspent output available for selection.
+type Input struct {
+	// ID identifies the output for the caller, like "txid#index".
+	ID    string
+	Value Value
+}
+
+// Params are the parameters of a selection.
+type Params struct {
+	// Available are the inputs to select from.
+	Available []Input
+	// Outputs are the values paid by the transaction.
+	Outputs []Value
+	// MaxInputs is the maximum number of inputs the transaction can hold, as
+	// allowed by the transaction size limit. There's no limit if it's zero.
+	MaxInputs int
+	// Fee returns the fee of a transaction spending the inputs and paying the
+	// outputs and the change. No fee is paid if it's nil.
+	Fee func(inputs []Input, change []Value) uint64
+	// MinCoin returns the lovelace a change output holding the value must
+	// hold at least. There's no minimum if it's nil.
+	MinCoin func(change Value) uint64
+	// Rand is the source of randomness of Random-Improve, seeded from the
+	// current time if nil.
+	Rand *rand.Rand
+}
+
+// Selection is the result of a coin selection.
+type Selection struct {
+	Inputs []Input
+	// Change holds the value of the change output, if any. Leftovers too
+	// small for a change output are added to the fee.
+	Change []Value
+	Fee    uint64
+}
+
+// selector holds the state of a selection.
+type selector struct {
+	params    Params
+	total     Value // sum of the outputs
+	remaining []Input
+	selected  []Input
+	sum       Value // sum of the selected inputs
+}
+
+func newSelector(params Params) (*selector, error) {
+	s := &selector{params: params, remaining: append([]Input{}, params.Available...)}
+	available := Value{}
+	for _, output := range params.Outputs {
+		s.total = s.total.Add(output)
+	}
+	for _, input := range params.Available {
+		available = available.Add(input.Value)
+	}
+	if !available.Covers(s.total) {
+		return nil, ErrBalanceInsufficient
+	}
+	return s, nil
+}
+
+// pick moves the remaining input i to the selected ones.
+func (s *selector) pick(i int) error {
+	if s.params.MaxInputs > 0 && len(s.selected) >= s.params.MaxInputs {
+		return ErrMaxInputCountExceeded
+	}
+	s.selected = append(s.selected, s.remaining[i])
+	s.sum = s.sum.Add(s.remaining[i].Value)
+	s.remaining = append(s.remaining[:i], s.remaining[i+1:]...)
+	return nil
+}
+
+// cover picks inputs with next until the selected inputs hold the quantity
+// of the asset, or the coin if asset is nil. next returns the index of a
+// remaining input holding some, or -1 if there's none.
+func (s *selector) cover(asset *AssetID, next func(quantity func(Input) uint64) int) error {
+	quantity := func(input Input) uint64 {
+		if asset == nil {
+			return input.Value.Coin
+		}
+		return input.Value.Assets[*asset]
+	}
+	covered := func() bool {
+		if asset == nil {
+			return s.sum.Coin >= s.total.Coin
+		}
+		return s.sum.Assets[*asset] >= s.total.Assets[*asset]
+	}
+	for !covered() {
+		i := next(quantity)
+		if i < 0 {
+			return ErrBalanceInsufficient
+		}
+		if err := s.pick(i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// coverAll covers every asset of the outputs, then their coin.
+func (s *selector) coverAll(next func(quantity func(Input) uint64) int) error {
+	for _, asset := range s.total.sortedAssets() {
+		asset := asset
+		if err := s.cover(&asset, next); err != nil {
+			return err
+		}
+	}
+	return s.cover(nil, next)
+}
+
+// balance adds the fee and the change to the selection, picking more inputs
+// with next until they pay for the fee and the minimum coin of the change.
+func (s *selector) balance(next func(quantity func(Input) uint64) int) (Selection, error) {
+	fee := func(change []Value) uint64 {
+		if s.params.Fee == nil {
+			return 0
+		}
+		return s.params.Fee(s.selected, change)
+	}
+	minCoin := func(change Value) uint64 {
+		if s.params.MinCoin == nil {
+			return 0
+		}
+		return s.params.MinCoin(change)
+	}
+
+	for {
+		change, _ := s.sum.Sub(s.total)
+		if change.Coin > 0 || change.HasAssets() {
+			withChange := fee([]Value{change})
+			if change.Coin >= withChange {
+				output := change
+				output.Coin -= withChange
+				if output.Coin >= minCoin(output) {
+					return Selection{Inputs: s.selected, Change: []Value{output}, Fee: withChange}, nil
+				}
+			}
+		}
+		if !change.HasAssets() && change.Coin >= fee(nil) {
+			return Selection{Inputs: s.selected, Fee: change.Coin}, nil
+		}
+
+		i := next(func(input Input) uint64 { return input.Value.Coin })
+		if i < 0 {
+			return Selection{}, ErrBalanceInsufficient
+		}
+		if err := s.pick(i); err != nil {
+			return Selection{}, err
+		}
+	}
+}
+
+// largest returns the index of the remaining input with the largest
+// quantity, or -1 if none holds some.
+func (s *selector) largest(quantity func(Input) uint64) int {
+	index, max := -1, uint64(0)
+	for i, input := range s.remaining {
+		if q := quantity(input); q > max {
+			index, max = i, q
+		}
+	}
+	return index
+}
+
+// random returns the index of a random remaining input holding some of the
+// quantity, or -1 if none does.
+func (s *selector) random(rnd *rand.Rand) func(quantity func(Input) uint64) int {
+	return func(quantity func(Input) uint64) int {
+		candidates := []int{}
+		for i, input := range s.remaining {
+			if quantity(input) > 0 {
+				candidates = append(candidates, i)
+			}
+		}
+		if len(candidates) == 0 {
+			return -1
+		}
+		return candidates[rnd.Intn(len(candidates))]
+	}
+}
+
+// LargestFirst selects the inputs with the largest quantity of each asset of
+// the outputs, then the ones with the most lovelace, until they pay for the
+// outputs, the fee and the change.
+func LargestFirst(params Params) (Selection, error) {
+	s, err := newSelector(params)
+	if err != nil {
+		return Selection{}, err
+	}
+	if err := s.coverAll(s.largest); err != nil {
+		return Selection{}, err
+	}
+	return s.balance(s.largest)
+}
+
+// RandomImprove selects random inputs until they pay for the outputs, then
+// improves the selection with random inputs that bring the selected lovelace
+// closer to twice the outputs' lovelace, without exceeding three times, so
+// that the change looks like the payment and the UTxO set doesn't get
+// fragmented. It falls back to Largest-First if the random selection takes
+// too many inputs.
+func RandomImprove(params Params) (Selection, error) {
+	rnd := params.Rand
+	if rnd == nil {
+		rnd = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	s, err := newSelector(params)
+	if err != nil {
+		return Selection{}, err
+	}
+	err = s.coverAll(s.random(rnd))
+	if errors.Is(err, ErrMaxInputCountExceeded) {
+		return LargestFirst(params)
+	}
+	if err != nil {
+		return Selection{}, err
+	}
+
+	// Improvement only considers lovelace only inputs, so that the change
+	// doesn't collect unrelated assets
+	ideal, upper := 2*s.total.Coin, 3*s.total.Coin
+	distance := func(coin uint64) uint64 {
+		if coin > ideal {
+			return coin - ideal
+		}
+		return ideal - coin
+	}
+	taken := make([]bool, len(s.remaining))
+	for _, i := range rnd.Perm(len(s.remaining)) {
+		if s.params.MaxInputs > 0 && len(s.selected) >= s.params.MaxInputs {
+			break
+		}
+		candidate := s.remaining[i]
+		if candidate.Value.HasAssets() {
+			continue
+		}
+		coin := s.sum.Coin + candidate.Value.Coin
+		if coin <= upper && distance(coin) < distance(s.sum.Coin) {
+			s.selected = append(s.selected, candidate)
+			s.sum = s.sum.Add(candidate.Value)
+			taken[i] = true
+		}
+	}
+	remaining := []Input{}
+	for i, input := range s.remaining {
+		if !taken[i] {
+			remaining = append(remaining, input)
+		}
+	}
+	s.remaining = remaining
+
+	selection, err := s.balance(s.random(rnd))
+	if errors.Is(err, ErrMaxInputCountExceeded) {
+		return LargestFirst(params)
+	}
+	return selection, err
+}
@@ -0,0 +1,238 @@
+package coinselection
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+var testToken = AssetID{PolicyID: "00", Name: "74657374"}
+
+func testInputs(coins ...uint64) []Input {
+	inputs := make([]Input, len(coins))
+	for i, coin := range coins {
+		inputs[i] = Input{ID: fmt.Sprintf("%v", i), Value: Value{Coin: coin}}
+	}
+	return inputs
+}
+
+// testFee charges 1 per input and change output.
+func testFee(inputs []Input, change []Value) uint64 {
+	return uint64(len(inputs) + len(change))
+}
+
+func inputIDs(inputs []Input) []string {
+	ids := make([]string, len(inputs))
+	for i, input := range inputs {
+		ids[i] = input.ID
+	}
+	return ids
+}
+
+// checkSelection checks that the selection spends exactly what it pays.
+func checkSelection(t *testing.T, params Params, selection Selection) {
+	t.Helper()
+	spent, paid := Value{}, Value{Coin: selection.Fee}
+	for _, input := range selection.Inputs {
+		spent = spent.Add(input.Value)
+	}
+	for _, output := range append(append([]Value{}, params.Outputs...), selection.Change...) {
+		paid = paid.Add(output)
+	}
+	if !spent.Covers(paid) || !paid.Covers(spent) {
+		t.Errorf("got inputs %+v paying %+v", spent, paid)
+	}
+	if params.MaxInputs > 0 && len(selection.Inputs) > params.MaxInputs {
+		t.Errorf("got %v inputs want at most %v", len(selection.Inputs), params.MaxInputs)
+	}
+	for _, change := range selection.Change {
+		if params.MinCoin != nil && change.Coin < params.MinCoin(change) {
+			t.Errorf("got change %+v below the minimum coin", change)
+		}
+	}
+}
+
+func TestLargestFirst(t *testing.T) {
+	tests := []struct {
+		name       string
+		params     Params
+		wantInputs []string
+		wantChange []Value
+		wantFee    uint64
+		wantErr    error
+	}{
+		{
+			name:       "largest inputs",
+			params:     Params{Available: testInputs(3, 10, 5), Outputs: []Value{{Coin: 4}}},
+			wantInputs: []string{"1"},
+			wantChange: []Value{{Coin: 6, Assets: map[AssetID]uint64{}}},
+		},
+		{
+			name:       "fee",
+			params:     Params{Available: testInputs(3, 10, 5), Outputs: []Value{{Coin: 10}}, Fee: testFee},
+			wantInputs: []string{"1", "2"},
+			wantChange: []Value{{Coin: 2, Assets: map[AssetID]uint64{}}},
+			wantFee:    3,
+		},
+		{
+			name: "change below minimum coin",
+			params: Params{
+				Available: testInputs(3, 10, 5),
+				Outputs:   []Value{{Coin: 10}},
+				Fee:       testFee,
+				MinCoin:   func(Value) uint64 { return 5 },
+			},
+			wantInputs: []string{"1", "2"},
+			wantFee:    5,
+		},
+		{
+			name: "change above minimum coin",
+			params: Params{
+				Available: testInputs(3, 10, 5),
+				Outputs:   []Value{{Coin: 3}},
+				Fee:       testFee,
+				MinCoin:   func(Value) uint64 { return 5 },
+			},
+			wantInputs: []string{"1"},
+			wantChange: []Value{{Coin: 5, Assets: map[AssetID]uint64{}}},
+			wantFee:    2,
+		},
+		{
+			name: "multi asset",
+			params: Params{
+				Available: []Input{
+					{ID: "ada", Value: Value{Coin: 10}},
+					{ID: "token", Value: Value{Coin: 2, Assets: map[AssetID]uint64{testToken: 5}}},
+				},
+				Outputs: []Value{{Coin: 5, Assets: map[AssetID]uint64{testToken: 3}}},
+				Fee:     testFee,
+				MinCoin: func(v Value) uint64 {
+					if v.HasAssets() {
+						return 2
+					}
+					return 1
+				},
+			},
+			wantInputs: []string{"token", "ada"},
+			wantChange: []Value{{Coin: 4, Assets: map[AssetID]uint64{testToken: 2}}},
+			wantFee:    3,
+		},
+		{
+			name:    "insufficient balance",
+			params:  Params{Available: testInputs(3, 10, 5), Outputs: []Value{{Coin: 19}}},
+			wantErr: ErrBalanceInsufficient,
+		},
+		{
+			name:    "insufficient balance for the fee",
+			params:  Params{Available: testInputs(3, 10, 5), Outputs: []Value{{Coin: 17}}, Fee: testFee},
+			wantErr: ErrBalanceInsufficient,
+		},
+		{
+			name:    "missing asset",
+			params:  Params{Available: testInputs(3, 10, 5), Outputs: []Value{{Coin: 1, Assets: map[AssetID]uint64{testToken: 1}}}},
+			wantErr: ErrBalanceInsufficient,
+		},
+		{
+			name:    "maximum input count",
+			params:  Params{Available: testInputs(3, 3, 3), Outputs: []Value{{Coin: 5}}, MaxInputs: 1},
+			wantErr: ErrMaxInputCountExceeded,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selection, err := LargestFirst(tt.params)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got error %v want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkSelection(t, tt.params, selection)
+			if got, want := inputIDs(selection.Inputs), tt.wantInputs; !reflect.DeepEqual(got, want) {
+				t.Errorf("got inputs %v want %v", got, want)
+			}
+			if got, want := selection.Change, tt.wantChange; !reflect.DeepEqual(got, want) {
+				t.Errorf("got change %+v want %+v", got, want)
+			}
+			if got, want := selection.Fee, tt.wantFee; got != want {
+				t.Errorf("got fee %v want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestRandomImprove(t *testing.T) {
+	many := testInputs(10, 10, 10, 10, 10, 10, 10, 10, 10, 10, 10, 10, 10, 10, 10, 10, 10, 10, 10, 10)
+	tests := []struct {
+		name    string
+		params  Params
+		minCoin uint64 // bounds of the selected lovelace
+		maxCoin uint64
+		wantErr error
+	}{
+		{
+			name:    "improved towards twice the outputs",
+			params:  Params{Available: many, Outputs: []Value{{Coin: 50}}, Fee: testFee},
+			minCoin: 100,
+			maxCoin: 150,
+		},
+		{
+			name: "multi asset",
+			params: Params{
+				Available: append(testInputs(4, 4, 4), Input{ID: "token", Value: Value{Coin: 2, Assets: map[AssetID]uint64{testToken: 5}}}),
+				Outputs:   []Value{{Coin: 5, Assets: map[AssetID]uint64{testToken: 5}}},
+				Fee:       testFee,
+			},
+			minCoin: 5,
+			maxCoin: 14,
+		},
+		{
+			name:    "falls back to largest first",
+			params:  Params{Available: append(testInputs(1, 1, 1, 1, 1, 1), Input{ID: "large", Value: Value{Coin: 6}}), Outputs: []Value{{Coin: 5}}, MaxInputs: 1},
+			minCoin: 6,
+			maxCoin: 6,
+		},
+		{
+			name:    "insufficient balance",
+			params:  Params{Available: many, Outputs: []Value{{Coin: 200}}, Fee: testFee},
+			wantErr: ErrBalanceInsufficient,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for seed := int64(0); seed < 20; seed++ {
+				tt.params.Rand = rand.New(rand.NewSource(seed))
+				selection, err := RandomImprove(tt.params)
+				if tt.wantErr != nil {
+					if !errors.Is(err, tt.wantErr) {
+						t.Fatalf("got error %v want %v", err, tt.wantErr)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				checkSelection(t, tt.params, selection)
+				coin := uint64(0)
+				for _, input := range selection.Inputs {
+					coin += input.Value.Coin
+				}
+				if coin < tt.minCoin || coin > tt.maxCoin {
+					t.Errorf("seed %v: got %v lovelace selected want between %v and %v", seed, coin, tt.minCoin, tt.maxCoin)
+				}
+
+				// The same seed gives the same selection
+				tt.params.Rand = rand.New(rand.NewSource(seed))
+				again, err := RandomImprove(tt.params)
+				if err != nil || !reflect.DeepEqual(inputIDs(again.Inputs), inputIDs(selection.Inputs)) {
+					t.Errorf("seed %v: got inputs %v and %v", seed, inputIDs(selection.Inputs), inputIDs(again.Inputs))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package coinselection
+
+import "sort"
+
+// AssetID identifies a native asset by its hex encoded policy id and name.
+type AssetID struct {
+	PolicyID string
+	Name     string
+}
+
+// Value is an amount of lovelace and native assets.
+type Value struct {
+	Coin   uint64
+	Assets map[AssetID]uint64
+}
+
+// Add returns the sum of both values.
+func (v Value) Add(other Value) Value {
+	sum := Value{Coin: v.Coin + other.Coin, Assets: v.copyAssets()}
+	for id, quantity := range other.Assets {
+		sum.Assets[id] += quantity
+	}
+	return sum
+}
+
+// Sub returns the difference of both values. It returns false if any
+// quantity of other is greater than the one in v.
+func (v Value) Sub(other Value) (Value, bool) {
+	if !v.Covers(other) {
+		return Value{}, false
+	}
+	diff := Value{Coin: v.Coin - other.Coin, Assets: v.copyAssets()}
+	for id, quantity := range other.Assets {
+		diff.Assets[id] -= quantity
+		if diff.Assets[id] == 0 {
+			delete(diff.Assets, id)
+		}
+	}
+	return diff, true
+}
+
+// Covers reports whether v holds at least every quantity of other.
+func (v Value) Covers(other Value) bool {
+	if v.Coin < other.Coin {
+		return false
+	}
+	for id, quantity := range other.Assets {
+		if v.Assets[id] < quantity {
+			return false
+		}
+	}
+	return true
+}
+
+// HasAssets reports whether the value holds native assets.
+func (v Value) HasAssets() bool {
+	for _, quantity := range v.Assets {
+		if quantity > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (v Value) copyAssets() map[AssetID]uint64 {
+	assets := map[AssetID]uint64{}
+	for id, quantity := range v.Assets {
+		if quantity > 0 {
+			assets[id] = quantity
+		}
+	}
+	return assets
+}
+
+// sortedAssets returns the ids of the assets of the value, sorted so that
+// selections are deterministic.
+func (v Value) sortedAssets() []AssetID {
+	ids := make([]AssetID, 0, len(v.Assets))
+	for id, quantity := range v.Assets {
+		if quantity > 0 {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if ids[i].PolicyID != ids[j].PolicyID {
+			return ids[i].PolicyID < ids[j].PolicyID
+		}
+		return ids[i].Name < ids[j].Name
+	})
+	return ids
+}
@@ -1,10 +1,27 @@
 package cardano
 
 import (
+	"encoding/json"
+
 	"github.com/dgraph-io/badger/v3"
 )
 
+// Keys of the badgerDB. Wallets are stored under their id, which starts with
+// the wallet prefix.
+var (
+	walletKeyPrefix      = []byte("wallet_")
+	walletIndexKeyPrefix = []byte("walletindex_")
+	utxoLocksKeyPrefix   = []byte("utxolocks_")
+	chainPointsKey       = []byte("chainsync_points")
+)
+
+// DB is the storage of a Client. Besides the wallets, it persists the
+// reservations of their UtxoLockers, the points of ChainFollowers and the
+// state of WalletIndexers, so that none is lost on a restart.
 type DB interface {
+	UtxoLockStore
+	ChainPointStore
+	WalletIndexStore
 	SaveWallet(*Wallet) error
 	GetWallets() ([]*Wallet, error)
 	DeleteWallet(string) error
@@ -44,7 +61,9 @@ func (bdb *badgerDB) SaveWallet(w *Wallet) error {
 func (bdb *badgerDB) GetWallets() ([]*Wallet, error) {
 	wallets := []*Wallet{}
 	err := bdb.db.View(func(txn *badger.Txn) error {
-		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = walletKeyPrefix
+		it := txn.NewIterator(opts)
 		defer it.Close()
 
 		for it.Rewind(); it.Valid(); it.Next() {
@@ -67,7 +86,101 @@ func (bdb *badgerDB) GetWallets() ([]*Wallet, error) {
 
 func (bdb *badgerDB) DeleteWallet(id string) error {
 	err := bdb.db.Update(func(txn *badger.Txn) error {
+		if err := txn.Delete(walletIndexKey(id)); err != nil {
+			return err
+		}
+		if err := txn.Delete(utxoLocksKey(id)); err != nil {
+			return err
+		}
 		return txn.Delete([]byte(id))
 	})
 	return err
 }
+
+func walletIndexKey(walletID string) []byte {
+	return append(append([]byte{}, walletIndexKeyPrefix...), walletID...)
+}
+
+func (bdb *badgerDB) WalletIndex(walletID string) ([]byte, error) {
+	var index []byte
+	err := bdb.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(walletIndexKey(walletID))
+		if err == badger.ErrKeyNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		index, err = item.ValueCopy(nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+func (bdb *badgerDB) SaveWalletIndex(walletID string, index []byte) error {
+	return bdb.db.Update(func(txn *badger.Txn) error {
+		return txn.Set(walletIndexKey(walletID), index)
+	})
+}
+
+func utxoLocksKey(walletID string) []byte {
+	return append(append([]byte{}, utxoLocksKeyPrefix...), walletID...)
+}
+
+func (bdb *badgerDB) UtxoLocks(walletID string) ([]byte, error) {
+	var locks []byte
+	err := bdb.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(utxoLocksKey(walletID))
+		if err == badger.ErrKeyNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		locks, err = item.ValueCopy(nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return locks, nil
+}
+
+func (bdb *badgerDB) SaveUtxoLocks(walletID string, locks []byte) error {
+	return bdb.db.Update(func(txn *badger.Txn) error {
+		return txn.Set(utxoLocksKey(walletID), locks)
+	})
+}
+
+func (bdb *badgerDB) ChainPoints() ([]ChainPoint, error) {
+	points := []ChainPoint{}
+	err := bdb.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(chainPointsKey)
+		if err == badger.ErrKeyNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return item.Value(func(value []byte) error {
+			return json.Unmarshal(value, &points)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return points, nil
+}
+
+func (bdb *badgerDB) SaveChainPoints(points []ChainPoint) error {
+	bytes, err := json.Marshal(points)
+	if err != nil {
+		return err
+	}
+	return bdb.db.Update(func(txn *badger.Txn) error {
+		return txn.Set(chainPointsKey, bytes)
+	})
+}
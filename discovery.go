@@ -0,0 +1,142 @@
+package cardano
+
+import (
+	"context"
+	"errors"
+)
+
+// DefaultGapLimit is the number of consecutive unused addresses after which
+// address discovery stops, as recommended by BIP-44.
+const DefaultGapLimit = 20
+
+// RestoreOption configures Client.RestoreWallet.
+type RestoreOption func(*restoreOptions)
+
+type restoreOptions struct {
+	gapLimit int
+	network  *Network
+}
+
+// WithGapLimit sets the number of consecutive unused addresses after which
+// address discovery stops. A limit of zero disables discovery, restoring only
+// the first address.
+func WithGapLimit(limit int) RestoreOption {
+	return func(o *restoreOptions) {
+		o.gapLimit = limit
+	}
+}
+
+// WithRestoreNetwork sets the network of the restored wallet. It's required
+// unless the Client's backend is a NetworkBackend, whose network it must then
+// match.
+func WithRestoreNetwork(network Network) RestoreOption {
+	return func(o *restoreOptions) {
+		o.network = &network
+	}
+}
+
+// DiscoverAddresses derives the addresses of the external and internal chains
+// of the wallet, in order, until gapLimit consecutive addresses are unused.
+// The keys up to the last used address of each chain are added to the
+// wallet, which must be saved to keep them.
+//
+// An address is used if the backend implements AddressUsageQuerier and
+// reports it as such, or if it holds unspent outputs otherwise.
+func (a *Account) DiscoverAddresses(ctx context.Context, gapLimit int) error {
+	_, err := a.discover(ctx, gapLimit)
+	return err
+}
+
+// DiscoverAccounts discovers the addresses of the accounts of the wallet, in
+// order, adding accounts until one has no used address. The wallet must be
+// saved to keep them.
+func (w *Wallet) DiscoverAccounts(ctx context.Context, gapLimit int) error {
+	for _, account := range w.Accounts() {
+		if _, err := account.discover(ctx, gapLimit); err != nil {
+			return err
+		}
+	}
+	if len(w.coinKey) == 0 {
+		return nil
+	}
+	for {
+		account, err := w.AddAccount()
+		if err != nil {
+			return err
+		}
+		used, err := account.discover(ctx, gapLimit)
+		if err != nil || !used {
+			w.removeLastAccount()
+			return err
+		}
+	}
+}
+
+// discover runs address discovery on the account and returns whether any of
+// its addresses is used.
+func (a *Account) discover(ctx context.Context, gapLimit int) (bool, error) {
+	chains := []uint32{externalChainIndex}
+	if len(a.internalKey) > 0 || a.WatchOnly() {
+		chains = append(chains, internalChainIndex)
+	}
+
+	used := false
+	for _, chain := range chains {
+		length, err := a.discoverChain(ctx, chain, gapLimit)
+		if err != nil {
+			return false, err
+		}
+		a.keysMu.Lock()
+		for a.chainLength(chain) < length {
+			a.appendKey(chain)
+		}
+		a.keysMu.Unlock()
+		used = used || length > 0
+	}
+	return used, nil
+}
+
+// discoverChain returns the number of addresses of the chain up to its last
+// used one.
+func (a *Account) discoverChain(ctx context.Context, chain uint32, gapLimit int) (int, error) {
+	stakeXvk := a.stakeVerificationKey()
+	used := 0
+	for index := 0; index < used+gapLimit; index++ {
+		key, err := a.addressKey(chain, uint32(index))
+		if err != nil {
+			return 0, err
+		}
+		addresses := []Address{a.address(key, stakeXvk)}
+		if stakeXvk != nil {
+			// Older versions handed out the enterprise address of the key
+			addresses = append(addresses, NewEnterpriseAddress(key, a.wallet.network))
+		}
+		for _, addr := range addresses {
+			ok, err := addressUsed(ctx, a.wallet.node, addr)
+			if err != nil {
+				return 0, err
+			}
+			if ok {
+				used = index + 1
+				break
+			}
+		}
+	}
+	return used, nil
+}
+
+// addressUsed returns whether the address was ever used, or holds unspent
+// outputs if the backend can't tell.
+func addressUsed(ctx context.Context, node ChainBackend, addr Address) (bool, error) {
+	if querier, ok := node.(AddressUsageQuerier); ok {
+		used, err := querier.QueryAddressUsed(ctx, addr)
+		if !errors.Is(err, ErrNotSupported) {
+			return used, err
+		}
+	}
+	utxos, err := node.QueryUtxos(ctx, addr)
+	if err != nil {
+		return false, err
+	}
+	return len(utxos) > 0, nil
+}
@@ -0,0 +1,193 @@
+package cardano
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/qredo/cardano-go/crypto"
+	"github.com/tyler-smith/go-bip39"
+)
+
+func TestRestoreWalletDiscovery(t *testing.T) {
+	entropy, err := bip39.EntropyFromMnemonic(testVectors[0].mnemonic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := newWallet("", "", entropy)
+	stakeXvk := keys.stakeKey.ExtendedVerificationKey()
+	external := func(index uint32) Address {
+		key := crypto.DeriveSigningKey(keys.rootKey, index)
+		return NewBaseAddress(key.ExtendedVerificationKey(), stakeXvk, Testnet)
+	}
+	internal := func(index uint32) Address {
+		key := crypto.DeriveSigningKey(keys.internalKey, index)
+		return NewBaseAddress(key.ExtendedVerificationKey(), stakeXvk, Testnet)
+	}
+	legacy := func(index uint32) Address {
+		key := crypto.DeriveSigningKey(keys.rootKey, index)
+		return NewEnterpriseAddress(key.ExtendedVerificationKey(), Testnet)
+	}
+
+	tests := []struct {
+		name         string
+		funded       []Address
+		spent        []Address
+		opts         []RestoreOption
+		wantExternal int
+		wantInternal int
+	}{
+		{
+			name:         "unused",
+			wantExternal: 1,
+		},
+		{
+			name:         "funded addresses",
+			funded:       []Address{external(0), external(3), internal(1)},
+			wantExternal: 4,
+			wantInternal: 2,
+		},
+		{
+			name:         "spent addresses",
+			funded:       []Address{external(2)},
+			spent:        []Address{external(21), internal(19)},
+			wantExternal: 22,
+			wantInternal: 20,
+		},
+		{
+			name:         "enterprise addresses",
+			funded:       []Address{legacy(2), external(4)},
+			spent:        []Address{legacy(9)},
+			wantExternal: 10,
+		},
+		{
+			name:         "beyond the gap limit",
+			funded:       []Address{external(1), external(7)},
+			opts:         []RestoreOption{WithGapLimit(5)},
+			wantExternal: 2,
+		},
+		{
+			name:         "discovery disabled",
+			funded:       []Address{external(1)},
+			opts:         []RestoreOption{WithGapLimit(0)},
+			wantExternal: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			genesis := map[Address]uint64{}
+			for _, addr := range tt.funded {
+				genesis[addr] = ShelleyProtocol.MinimumUtxoValue
+			}
+			emulator := NewEmulator(ShelleyProtocol, genesis)
+			for _, addr := range tt.spent {
+				emulator.used[addr] = true
+			}
+			client := NewClient(WithDB(&MockDB{}), WithNode(emulator))
+			defer client.Close()
+
+			opts := append([]RestoreOption{WithRestoreNetwork(Testnet)}, tt.opts...)
+			w, err := client.RestoreWallet("test", "", testVectors[0].mnemonic, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := len(w.Addresses()), tt.wantExternal; got != want {
+				t.Errorf("got %v addresses want %v", got, want)
+			}
+			if got, want := len(w.ChangeAddresses()), tt.wantInternal; got != want {
+				t.Errorf("got %v change addresses want %v", got, want)
+			}
+			if tt.opts != nil {
+				return
+			}
+			balance, err := w.Balance()
+			if want := uint64(len(tt.funded)) * ShelleyProtocol.MinimumUtxoValue; err != nil || balance != want {
+				t.Errorf("got balance %v, %v want %v", balance, err, want)
+			}
+		})
+	}
+}
+
+func TestRestoreWalletAccountDiscovery(t *testing.T) {
+	entropy, err := bip39.EntropyFromMnemonic(testVectors[0].mnemonic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := newWallet("", "", entropy)
+	keys.SetNetwork(Testnet)
+	account1, err := keys.AddAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	account1.AddAddress()
+
+	emulator := NewEmulator(ShelleyProtocol, map[Address]uint64{account1.Addresses()[1]: ShelleyProtocol.MinimumUtxoValue})
+	client := NewClient(WithDB(&MockDB{}), WithNode(emulator))
+	defer client.Close()
+
+	w, err := client.RestoreWallet("test", "", testVectors[0].mnemonic, WithRestoreNetwork(Testnet))
+	if err != nil {
+		t.Fatal(err)
+	}
+	accounts := w.Accounts()
+	if got, want := len(accounts), 2; got != want {
+		t.Fatalf("got %v accounts want %v", got, want)
+	}
+	if got, want := len(accounts[1].Addresses()), 2; got != want {
+		t.Errorf("got %v addresses want %v", got, want)
+	}
+	if balance, err := accounts[1].Balance(); err != nil || balance != ShelleyProtocol.MinimumUtxoValue {
+		t.Errorf("got balance %v, %v want %v", balance, err, ShelleyProtocol.MinimumUtxoValue)
+	}
+}
+
+// usageBackend is a fakeBackend answering address usage queries with err.
+type usageBackend struct {
+	*fakeBackend
+	usageErr error
+}
+
+func (b *usageBackend) QueryAddressUsed(ctx context.Context, addr Address) (bool, error) {
+	return false, b.usageErr
+}
+
+func TestAddressUsedMultiBackend(t *testing.T) {
+	funded := &fakeBackend{utxos: []Utxo{{Amount: ShelleyProtocol.MinimumUtxoValue}}}
+	tests := []struct {
+		name     string
+		backends []ChainBackend
+		wantUsed bool
+		wantErr  bool
+	}{
+		{
+			name:     "usage not supported",
+			backends: []ChainBackend{funded, funded},
+			wantUsed: true,
+		},
+		{
+			name:     "usage failing on a backend",
+			backends: []ChainBackend{funded, &usageBackend{funded, errors.New("backend down")}},
+			wantErr:  true,
+		},
+		{
+			name:     "usage not supported by any backend",
+			backends: []ChainBackend{funded, &usageBackend{funded, ErrNotSupported}},
+			wantUsed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			multi, err := NewMultiBackend(tt.backends)
+			if err != nil {
+				t.Fatal(err)
+			}
+			used, err := addressUsed(context.Background(), multi, "addr_test1")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v want error %v", err, tt.wantErr)
+			}
+			if used != tt.wantUsed {
+				t.Errorf("got used %v want %v", used, tt.wantUsed)
+			}
+		})
+	}
+}
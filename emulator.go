@@ -0,0 +1,239 @@
+package cardano
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/echovl/bech32"
+	"golang.org/x/crypto/blake2b"
+)
+
+const emulatorEpochLength = 432000
+
+// Emulator is an in-memory ledger that can be used as the Client's node. It
+// validates submitted transactions against its UTxO set and applies them in
+// a new block, so that wallet flows can be tested without a real node.
+type Emulator struct {
+	mu       sync.Mutex
+	protocol ProtocolParams
+	utxos    map[string]Utxo
+	txs      map[TransactionID]emulatorTx
+	accounts map[string]RewardAccount // keyed by hex encoded stake credential
+	used     map[Address]bool
+	slot     uint64
+	block    uint64
+}
+
+type emulatorTx struct {
+	tx    Transaction
+	block uint64
+	slot  uint64
+}
+
+// NewEmulator creates an Emulator whose UTxO set holds the genesis funds,
+// one output per address.
+func NewEmulator(protocol ProtocolParams, genesis map[Address]uint64) *Emulator {
+	emulator := &Emulator{
+		protocol: protocol,
+		utxos:    map[string]Utxo{},
+		txs:      map[TransactionID]emulatorTx{},
+		accounts: map[string]RewardAccount{},
+		used:     map[Address]bool{},
+	}
+	for addr, amount := range genesis {
+		hash := blake2b.Sum256(addr.Bytes())
+		utxo := Utxo{Address: addr, TxId: TransactionID(hex.EncodeToString(hash[:])), Amount: amount}
+		emulator.utxos[utxoKey(hash[:], 0)] = utxo
+		emulator.used[addr] = true
+	}
+	return emulator
+}
+
+// QueryUtxos returns the unspent outputs locked by the address.
+func (e *Emulator) QueryUtxos(ctx context.Context, addr Address) ([]Utxo, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	utxos := []Utxo{}
+	for _, utxo := range e.utxos {
+		if utxo.Address == addr {
+			utxos = append(utxos, utxo)
+		}
+	}
+	sort.Slice(utxos, func(i, j int) bool {
+		if utxos[i].TxId == utxos[j].TxId {
+			return utxos[i].Index < utxos[j].Index
+		}
+		return utxos[i].TxId < utxos[j].TxId
+	})
+	return utxos, nil
+}
+
+// QueryAddressUsed returns whether any transaction paid to the address.
+func (e *Emulator) QueryAddressUsed(ctx context.Context, addr Address) (bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.used[addr], nil
+}
+
+// QueryTip returns the current slot and block of the emulated chain.
+func (e *Emulator) QueryTip(ctx context.Context) (NodeTip, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return NodeTip{Epoch: e.slot / emulatorEpochLength, Block: e.block, Slot: e.slot}, nil
+}
+
+// QueryProtocolParams returns the protocol parameters the emulator validates
+// transactions with.
+func (e *Emulator) QueryProtocolParams(ctx context.Context) (ProtocolParams, error) {
+	return e.protocol, nil
+}
+
+// QueryTxStatus returns the block and slot in which the transaction was
+// applied.
+func (e *Emulator) QueryTxStatus(ctx context.Context, txId TransactionID) (TxStatus, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	applied, ok := e.txs[txId]
+	if !ok {
+		return TxStatus{}, nil
+	}
+	return TxStatus{Confirmed: true, Block: applied.block, Slot: applied.slot}, nil
+}
+
+// QueryRewardAccount returns the registration, delegation and rewards of the
+// stake address.
+func (e *Emulator) QueryRewardAccount(ctx context.Context, stakeAddr Address) (RewardAccount, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	account, ok := e.accounts[stakeCredentialKey(stakeAddr.Bytes())]
+	if !ok {
+		return RewardAccount{Address: stakeAddr}, nil
+	}
+	account.Address = stakeAddr
+	return account, nil
+}
+
+// AddRewards credits rewards to a registered stake address.
+func (e *Emulator) AddRewards(stakeAddr Address, amount uint64) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	key := stakeCredentialKey(stakeAddr.Bytes())
+	account, ok := e.accounts[key]
+	if !ok {
+		return fmt.Errorf("stake address %v not registered", stakeAddr)
+	}
+	account.Balance += amount
+	e.accounts[key] = account
+	return nil
+}
+
+// SubmitTx validates the transaction and, if valid, applies it in a new block
+// consuming its inputs and creating its outputs.
+func (e *Emulator) SubmitTx(ctx context.Context, tx Transaction) (TransactionID, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	utxos := make([]Utxo, 0, len(e.utxos))
+	for _, utxo := range e.utxos {
+		utxos = append(utxos, utxo)
+	}
+	if err := ValidateTx(&tx, utxos, e.protocol, e.slot); err != nil {
+		return "", err
+	}
+	for addr, amount := range tx.Body.Withdrawals {
+		addrBytes, err := hex.DecodeString(addr)
+		if err != nil {
+			return "", err
+		}
+		account, ok := e.accounts[stakeCredentialKey(addrBytes)]
+		if !ok || account.Balance != amount {
+			return "", &WithdrawalsNotInRewardsError{Address: addr, Amount: amount}
+		}
+	}
+
+	txId := tx.ID()
+	for _, input := range tx.Body.Inputs {
+		delete(e.utxos, utxoKey(input.ID, input.Index))
+	}
+	for i, output := range tx.Body.Outputs {
+		addr, err := BytesToAddress(output.Address, Network(output.Address[0]&0x0F))
+		if err != nil {
+			return "", err
+		}
+		e.used[addr] = true
+		e.utxos[utxoKey(txId.Bytes(), uint64(i))] = Utxo{
+			Address: addr,
+			TxId:    txId,
+			Amount:  output.Amount,
+			Index:   uint64(i),
+			Assets:  output.Assets,
+		}
+	}
+	e.applyStake(&tx.Body)
+	e.block++
+	e.txs[txId] = emulatorTx{tx: tx, block: e.block, slot: e.slot}
+	return txId, nil
+}
+
+func (e *Emulator) applyStake(body *TransactionBody) {
+	for addr := range body.Withdrawals {
+		addrBytes, _ := hex.DecodeString(addr)
+		key := stakeCredentialKey(addrBytes)
+		account := e.accounts[key]
+		account.Balance = 0
+		e.accounts[key] = account
+	}
+	for _, cert := range body.Certificates {
+		key := hex.EncodeToString(cert.StakeCredential.Hash)
+		switch cert.Type {
+		case StakeRegistration:
+			e.accounts[key] = RewardAccount{Registered: true}
+		case StakeDeregistration:
+			delete(e.accounts, key)
+		case StakeDelegation:
+			account := e.accounts[key]
+			poolId, err := bech32.EncodeFromBase256("pool", cert.PoolKeyHash)
+			if err == nil {
+				account.Delegation = poolId
+			}
+			e.accounts[key] = account
+		}
+	}
+}
+
+// AdvanceSlots moves the emulated chain forward by the given number of slots.
+func (e *Emulator) AdvanceSlots(slots uint64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.slot += slots
+}
+
+// Transaction returns a transaction applied by the emulator.
+func (e *Emulator) Transaction(id TransactionID) (Transaction, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	applied, ok := e.txs[id]
+	if !ok {
+		return Transaction{}, fmt.Errorf("transaction %v not found", id)
+	}
+	return applied.tx, nil
+}
+
+// stakeCredentialKey returns the hex encoded stake credential of a reward
+// address.
+func stakeCredentialKey(rewardAddr []byte) string {
+	if len(rewardAddr) < 29 {
+		return ""
+	}
+	return hex.EncodeToString(rewardAddr[1:29])
+}
@@ -0,0 +1,112 @@
+package cardano
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/qredo/cardano-go/crypto"
+	"github.com/tyler-smith/go-bip39"
+)
+
+func TestEmulatorTransfer(t *testing.T) {
+	testVector := testVectors[0]
+	genesisAmount := 100 * ShelleyProtocol.MinimumUtxoValue
+	emulator := NewEmulator(ShelleyProtocol, map[Address]uint64{testVector.paymentAddr0: genesisAmount})
+
+	client := NewClient(WithDB(&MockDB{}), WithNode(emulator))
+	defer client.Close()
+	newEntropy = func(bitSize int) []byte {
+		entropy, err := bip39.EntropyFromMnemonic(testVector.mnemonic)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return entropy
+	}
+	w, _, err := client.CreateWallet("test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.SetNetwork(Testnet)
+
+	receiverKey := crypto.NewExtendedSigningKey([]byte("receiver"), "")
+	receiver := NewEnterpriseAddress(receiverKey.ExtendedVerificationKey(), Testnet)
+	amount := 10 * ShelleyProtocol.MinimumUtxoValue
+	if _, err := w.Transfer(receiver, amount); err != nil {
+		t.Fatal(err)
+	}
+
+	receiverUtxos, err := emulator.QueryUtxos(context.Background(), receiver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(receiverUtxos), 1; got != want {
+		t.Fatalf("got %v receiver utxos want %v", got, want)
+	}
+	if got, want := receiverUtxos[0].Amount, amount; got != want {
+		t.Errorf("got receiver amount %v want %v", got, want)
+	}
+
+	tx, err := emulator.Transaction(receiverUtxos[0].TxId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	balance, err := w.Balance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := balance, genesisAmount-amount-tx.Body.Fee; got != want {
+		t.Errorf("got balance %v want %v", got, want)
+	}
+
+	tip, err := emulator.QueryTip(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := tip.Block, uint64(1); got != want {
+		t.Errorf("got block %v want %v", got, want)
+	}
+
+	status, err := emulator.QueryTxStatus(context.Background(), tx.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := status, (TxStatus{Confirmed: true, Block: 1}); got != want {
+		t.Errorf("got status %+v want %+v", got, want)
+	}
+
+	// Spent inputs can't be spent again
+	var badInputs *BadInputsError
+	if _, err := emulator.SubmitTx(context.Background(), tx); !errors.As(err, &badInputs) {
+		t.Errorf("got error %v want %T", err, badInputs)
+	}
+}
+
+func TestEmulatorValidityInterval(t *testing.T) {
+	key := crypto.NewExtendedSigningKey([]byte("sender"), "")
+	sender := NewEnterpriseAddress(key.ExtendedVerificationKey(), Testnet)
+	emulator := NewEmulator(ShelleyProtocol, map[Address]uint64{sender: 5 * ShelleyProtocol.MinimumUtxoValue})
+
+	utxos, err := emulator.QueryUtxos(context.Background(), sender)
+	if err != nil {
+		t.Fatal(err)
+	}
+	builder := NewTxBuilder(ShelleyProtocol)
+	builder.AddInput(key.ExtendedVerificationKey(), utxos[0].TxId, utxos[0].Index, utxos[0].Amount)
+	builder.AddOutput(sender, ShelleyProtocol.MinimumUtxoValue)
+	builder.SetTtl(100)
+	if err := builder.AddFee(sender); err != nil {
+		t.Fatal(err)
+	}
+	builder.Sign(key)
+	tx := builder.Build()
+
+	emulator.AdvanceSlots(100)
+	var outside *OutsideValidityIntervalError
+	if _, err := emulator.SubmitTx(context.Background(), tx); !errors.As(err, &outside) {
+		t.Errorf("got error %v want %T", err, outside)
+	}
+	if got, err := emulator.QueryUtxos(context.Background(), sender); err != nil || len(got) != 1 {
+		t.Errorf("got utxos %v, %v want the genesis utxo", got, err)
+	}
+}
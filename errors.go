@@ -0,0 +1,149 @@
+package cardano
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// BadInputsError is returned when a transaction spends inputs that are not
+// in the UTxO set.
+type BadInputsError struct {
+	Inputs []TransactionInput
+}
+
+func (e *BadInputsError) Error() string {
+	inputs := make([]string, len(e.Inputs))
+	for i, input := range e.Inputs {
+		inputs[i] = fmt.Sprintf("%x#%v", input.ID, input.Index)
+	}
+	return fmt.Sprintf("bad inputs: %v", strings.Join(inputs, ", "))
+}
+
+// ValueNotConservedError is returned when the value consumed by a transaction
+// differs from the value it produces.
+type ValueNotConservedError struct {
+	Consumed Value
+	Produced Value
+}
+
+func (e *ValueNotConservedError) Error() string {
+	return fmt.Sprintf("value not conserved, consumed %v produced %v", e.Consumed, e.Produced)
+}
+
+// FeeTooSmallError is returned when the fee of a transaction is lower than
+// the minimum fee.
+type FeeTooSmallError struct {
+	Fee    uint64
+	MinFee uint64
+}
+
+func (e *FeeTooSmallError) Error() string {
+	return fmt.Sprintf("fee too small, got %v want at least %v", e.Fee, e.MinFee)
+}
+
+// MaxTxSizeExceededError is returned when a transaction is bigger than the
+// maximum transaction size.
+type MaxTxSizeExceededError struct {
+	Size    uint64
+	MaxSize uint64
+}
+
+func (e *MaxTxSizeExceededError) Error() string {
+	return fmt.Sprintf("max tx size exceeded, got %v want at most %v", e.Size, e.MaxSize)
+}
+
+// OutputTooSmallError is returned when an output holds less lovelace than the
+// minimum UTxO value.
+type OutputTooSmallError struct {
+	Index     int // -1 if the output index is unknown
+	Amount    uint64
+	MinAmount uint64
+}
+
+func (e *OutputTooSmallError) Error() string {
+	return fmt.Sprintf("output %v too small, got %v want at least %v", e.Index, e.Amount, e.MinAmount)
+}
+
+// MalformedOutputAddressError is returned when an output address is too short
+// to hold a header and a payment credential.
+type MalformedOutputAddressError struct {
+	Index   int
+	Address []byte
+}
+
+func (e *MalformedOutputAddressError) Error() string {
+	return fmt.Sprintf("output %v address malformed: %x", e.Index, e.Address)
+}
+
+// OutsideValidityIntervalError is returned when a transaction is not valid in
+// the current slot.
+type OutsideValidityIntervalError struct {
+	Slot      uint64
+	ValidFrom uint64
+	TTL       uint64
+}
+
+func (e *OutsideValidityIntervalError) Error() string {
+	return fmt.Sprintf("slot %v outside validity interval [%v, %v)", e.Slot, e.ValidFrom, e.TTL)
+}
+
+// MissingVKeyWitnessesError is returned when a required key hash has not
+// witnessed the transaction.
+type MissingVKeyWitnessesError struct {
+	KeyHashes [][]byte
+}
+
+func (e *MissingVKeyWitnessesError) Error() string {
+	hashes := make([]string, len(e.KeyHashes))
+	for i, hash := range e.KeyHashes {
+		hashes[i] = hex.EncodeToString(hash)
+	}
+	return fmt.Sprintf("missing vkey witnesses: %v", strings.Join(hashes, ", "))
+}
+
+// InvalidSignatureError is returned when a vkey witness signature doesn't
+// match the transaction body.
+type InvalidSignatureError struct {
+	VKey []byte
+}
+
+func (e *InvalidSignatureError) Error() string {
+	return fmt.Sprintf("invalid signature for vkey %x", e.VKey)
+}
+
+// WithdrawalsNotInRewardsError is returned when a withdrawal doesn't match
+// the rewards of its reward account.
+type WithdrawalsNotInRewardsError struct {
+	Address string // hex encoded reward address
+	Amount  uint64
+}
+
+func (e *WithdrawalsNotInRewardsError) Error() string {
+	return fmt.Sprintf("withdrawal of %v from %v doesn't match the rewards", e.Amount, e.Address)
+}
+
+// TxExpiredError is returned by TxTracker.Wait when the tip of the chain
+// passed the TTL of a transaction that wasn't included.
+type TxExpiredError struct {
+	ID   TransactionID
+	TTL  uint64
+	Slot uint64 // slot of the tip
+}
+
+func (e *TxExpiredError) Error() string {
+	return fmt.Sprintf("transaction %v expired at slot %v, tip at slot %v", e.ID, e.TTL, e.Slot)
+}
+
+// TxInputsSpentError is returned by TxTracker.Wait when the tip of the chain
+// passed the TTL of a transaction that wasn't found, but whose inputs are
+// spent. The transaction may have been included and its outputs spent since,
+// so it must not be paid again before checking the chain.
+type TxInputsSpentError struct {
+	ID   TransactionID
+	Slot uint64 // slot of the tip
+}
+
+func (e *TxInputsSpentError) Error() string {
+	return fmt.Sprintf("transaction %v not found at slot %v but its inputs are spent, it may have been included", e.ID, e.Slot)
+}
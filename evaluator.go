@@ -0,0 +1,51 @@
+package cardano
+
+import (
+	"context"
+	"math/big"
+	"time"
+)
+
+// evaluateTimeout bounds the evaluation of a transaction whose context has no
+// deadline.
+const evaluateTimeout = 30 * time.Second
+
+// RedeemerKey identifies a redeemer within a transaction.
+type RedeemerKey struct {
+	Tag   RedeemerTag
+	Index uint64
+}
+
+// Evaluator computes the execution units spent by each redeemer of a
+// transaction, usually by running its scripts against the current ledger state.
+type Evaluator interface {
+	EvaluateTx(ctx context.Context, tx *Transaction) (map[RedeemerKey]ExUnits, error)
+}
+
+// scriptFee returns the fee paid for the execution units of the redeemers,
+// priced with the protocol execution prices.
+func scriptFee(redeemers []Redeemer, protocol ProtocolParams) uint64 {
+	var mem, steps uint64
+	for _, redeemer := range redeemers {
+		mem += redeemer.ExUnits.Mem
+		steps += redeemer.ExUnits.Steps
+	}
+
+	fee := new(big.Rat)
+	fee.Add(priceOf(mem, protocol.PriceMem), priceOf(steps, protocol.PriceStep))
+
+	// Round up to the next lovelace
+	quo, rem := new(big.Int).QuoRem(fee.Num(), fee.Denom(), new(big.Int))
+	if rem.Sign() > 0 {
+		quo.Add(quo, big.NewInt(1))
+	}
+	return quo.Uint64()
+}
+
+func priceOf(units uint64, price Rational) *big.Rat {
+	if price.Den == 0 {
+		return new(big.Rat)
+	}
+	rat := new(big.Rat).SetFrac(new(big.Int).SetUint64(price.Num), new(big.Int).SetUint64(price.Den))
+	return rat.Mul(rat, new(big.Rat).SetInt(new(big.Int).SetUint64(units)))
+}
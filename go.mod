@@ -13,5 +13,6 @@ require (
 	github.com/stretchr/testify v1.7.0 // indirect
 	github.com/tyler-smith/go-bip39 v1.1.0
 	golang.org/x/crypto v0.0.0-20200622213623-75b288015ac9
+	golang.org/x/net v0.0.0-20201021035429-f5854403a974
 	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b // indirect
 )
@@ -0,0 +1,315 @@
+package cardano
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// Koios API base URLs.
+const (
+	KoiosMainnet = "https://api.koios.rest/api/v1"
+	KoiosPreprod = "https://preprod.koios.rest/api/v1"
+	KoiosPreview = "https://preview.koios.rest/api/v1"
+)
+
+const koiosPageSize = 1000
+
+// KoiosError is an error response of the Koios API.
+type KoiosError struct {
+	StatusCode int
+	Message    string `json:"message"`
+	Details    string `json:"details"`
+}
+
+func (e *KoiosError) Error() string {
+	if e.Details != "" {
+		return fmt.Sprintf("koios: %v %v: %v", e.StatusCode, e.Message, e.Details)
+	}
+	return fmt.Sprintf("koios: %v %v", e.StatusCode, e.Message)
+}
+
+// KoiosOption configures a Koios backend.
+type KoiosOption func(*Koios)
+
+// WithKoiosHTTPClient sets the http client used to call the API.
+func WithKoiosHTTPClient(client *http.Client) KoiosOption {
+	return func(k *Koios) {
+		k.client = client
+	}
+}
+
+// WithKoiosToken sets the bearer token sent with every request.
+func WithKoiosToken(token string) KoiosOption {
+	return func(k *Koios) {
+		k.token = token
+	}
+}
+
+// Koios is a ChainBackend using the Koios REST API.
+type Koios struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+// NewKoios creates a Koios backend for the API at baseURL, one of
+// KoiosMainnet, KoiosPreprod, KoiosPreview or a self hosted instance.
+func NewKoios(baseURL string, opts ...KoiosOption) *Koios {
+	k := &Koios{baseURL: baseURL, client: http.DefaultClient}
+	for _, opt := range opts {
+		opt(k)
+	}
+	return k
+}
+
+type koiosAsset struct {
+	PolicyID  string `json:"policy_id"`
+	AssetName string `json:"asset_name"`
+	Quantity  string `json:"quantity"`
+}
+
+type koiosUtxo struct {
+	TxHash    string       `json:"tx_hash"`
+	TxIndex   uint64       `json:"tx_index"`
+	Address   string       `json:"address"`
+	Value     string       `json:"value"`
+	AssetList []koiosAsset `json:"asset_list"`
+}
+
+type koiosTip struct {
+	EpochNo uint64 `json:"epoch_no"`
+	AbsSlot uint64 `json:"abs_slot"`
+	BlockNo uint64 `json:"block_no"`
+}
+
+type koiosParams struct {
+	MinFeeA          uint64                     `json:"min_fee_a"`
+	MinFeeB          uint64                     `json:"min_fee_b"`
+	MaxTxSize        uint64                     `json:"max_tx_size"`
+	KeyDeposit       string                     `json:"key_deposit"`
+	PoolDeposit      string                     `json:"pool_deposit"`
+	MinUtxoValue     string                     `json:"min_utxo_value"`
+	CoinsPerUtxoSize *string                    `json:"coins_per_utxo_size"`
+	PriceMem         json.Number                `json:"price_mem"`
+	PriceStep        json.Number                `json:"price_step"`
+	CostModels       map[string]json.RawMessage `json:"cost_models"`
+}
+
+type koiosTxInfo struct {
+	TxHash      string  `json:"tx_hash"`
+	BlockHeight *uint64 `json:"block_height"`
+}
+
+type koiosAccount struct {
+	StakeAddress     string  `json:"stake_address"`
+	Status           string  `json:"status"`
+	DelegatedPool    *string `json:"delegated_pool"`
+	RewardsAvailable string  `json:"rewards_available"`
+}
+
+// QueryUtxos returns the unspent outputs locked by the address, fetching
+// every page.
+func (k *Koios) QueryUtxos(ctx context.Context, addr Address) ([]Utxo, error) {
+	utxos := []Utxo{}
+	body := map[string]interface{}{"_addresses": []Address{addr}, "_extended": true}
+	for offset := 0; ; offset += koiosPageSize {
+		koiosUtxos := []koiosUtxo{}
+		rng := fmt.Sprintf("%v-%v", offset, offset+koiosPageSize-1)
+		if err := k.do(ctx, http.MethodPost, "/address_utxos", rng, body, &koiosUtxos); err != nil {
+			return nil, err
+		}
+
+		for _, koiosUtxo := range koiosUtxos {
+			utxo := Utxo{
+				Address: Address(koiosUtxo.Address),
+				TxId:    TransactionID(koiosUtxo.TxHash),
+				Index:   koiosUtxo.TxIndex,
+			}
+			if err := addUnit(&utxo, "lovelace", koiosUtxo.Value); err != nil {
+				return nil, err
+			}
+			for _, asset := range koiosUtxo.AssetList {
+				if err := addUnit(&utxo, asset.PolicyID+asset.AssetName, asset.Quantity); err != nil {
+					return nil, err
+				}
+			}
+			utxos = append(utxos, utxo)
+		}
+		if len(koiosUtxos) < koiosPageSize {
+			return utxos, nil
+		}
+	}
+}
+
+// QueryAddressUsed returns whether the address has any transaction.
+func (k *Koios) QueryAddressUsed(ctx context.Context, addr Address) (bool, error) {
+	txs := []json.RawMessage{}
+	body := map[string]interface{}{"_addresses": []Address{addr}}
+	if err := k.do(ctx, http.MethodPost, "/address_txs", "0-0", body, &txs); err != nil {
+		return false, err
+	}
+	return len(txs) > 0, nil
+}
+
+// QueryTip returns the latest block.
+func (k *Koios) QueryTip(ctx context.Context) (NodeTip, error) {
+	tips := []koiosTip{}
+	if err := k.do(ctx, http.MethodGet, "/tip", "", nil, &tips); err != nil {
+		return NodeTip{}, err
+	}
+	if len(tips) == 0 {
+		return NodeTip{}, fmt.Errorf("koios: empty tip response")
+	}
+	return NodeTip{Epoch: tips[0].EpochNo, Block: tips[0].BlockNo, Slot: tips[0].AbsSlot}, nil
+}
+
+// QueryProtocolParams returns the protocol parameters of the current epoch.
+func (k *Koios) QueryProtocolParams(ctx context.Context) (ProtocolParams, error) {
+	tip, err := k.QueryTip(ctx)
+	if err != nil {
+		return ProtocolParams{}, err
+	}
+	epochParams := []koiosParams{}
+	if err := k.do(ctx, http.MethodGet, fmt.Sprintf("/epoch_params?_epoch_no=%v", tip.Epoch), "", nil, &epochParams); err != nil {
+		return ProtocolParams{}, err
+	}
+	if len(epochParams) == 0 {
+		return ProtocolParams{}, fmt.Errorf("koios: no parameters for epoch %v", tip.Epoch)
+	}
+
+	params := epochParams[0]
+	protocol := ProtocolParams{
+		MinFeeA:   params.MinFeeA,
+		MinFeeB:   params.MinFeeB,
+		MaxTxSize: params.MaxTxSize,
+	}
+	if protocol.KeyDeposit, err = parseQuantity(params.KeyDeposit); err != nil {
+		return ProtocolParams{}, err
+	}
+	if protocol.PoolDeposit, err = parseQuantity(params.PoolDeposit); err != nil {
+		return ProtocolParams{}, err
+	}
+	if params.CoinsPerUtxoSize != nil {
+		if protocol.CoinsPerUTxOByte, err = parseQuantity(*params.CoinsPerUtxoSize); err != nil {
+			return ProtocolParams{}, err
+		}
+	} else if protocol.MinimumUtxoValue, err = parseQuantity(params.MinUtxoValue); err != nil {
+		return ProtocolParams{}, err
+	}
+	if protocol.PriceMem, err = parseRational(params.PriceMem.String()); err != nil {
+		return ProtocolParams{}, err
+	}
+	if protocol.PriceStep, err = parseRational(params.PriceStep.String()); err != nil {
+		return ProtocolParams{}, err
+	}
+	protocol.CostModels = parseCostModels(params.CostModels)
+	return protocol, nil
+}
+
+// QueryTxStatus returns whether the transaction is confirmed and the height
+// of its block.
+func (k *Koios) QueryTxStatus(ctx context.Context, txId TransactionID) (TxStatus, error) {
+	infos := []koiosTxInfo{}
+	body := map[string]interface{}{"_tx_hashes": []TransactionID{txId}}
+	if err := k.do(ctx, http.MethodPost, "/tx_info", "", body, &infos); err != nil {
+		return TxStatus{}, err
+	}
+	if len(infos) == 0 || infos[0].BlockHeight == nil {
+		return TxStatus{}, nil
+	}
+	return TxStatus{Confirmed: true, Block: *infos[0].BlockHeight}, nil
+}
+
+// QueryRewardAccount returns the state of the reward account of the stake
+// address.
+func (k *Koios) QueryRewardAccount(ctx context.Context, stakeAddr Address) (RewardAccount, error) {
+	accounts := []koiosAccount{}
+	body := map[string]interface{}{"_stake_addresses": []Address{stakeAddr}}
+	if err := k.do(ctx, http.MethodPost, "/account_info", "", body, &accounts); err != nil {
+		return RewardAccount{}, err
+	}
+
+	account := RewardAccount{Address: stakeAddr}
+	if len(accounts) == 0 {
+		return account, nil
+	}
+	balance, err := parseQuantity(accounts[0].RewardsAvailable)
+	if err != nil {
+		return RewardAccount{}, err
+	}
+	account.Registered = accounts[0].Status == "registered"
+	account.Balance = balance
+	if accounts[0].DelegatedPool != nil {
+		account.Delegation = *accounts[0].DelegatedPool
+	}
+	return account, nil
+}
+
+// SubmitTx submits the cbor encoded transaction.
+func (k *Koios) SubmitTx(ctx context.Context, tx Transaction) (TransactionID, error) {
+	var txId TransactionID
+	if err := k.do(ctx, http.MethodPost, "/submittx", "", tx.Bytes(), &txId); err != nil {
+		if koiosErr, ok := err.(*KoiosError); ok && koiosErr.StatusCode == http.StatusBadRequest {
+			return "", submitError(koiosErr, koiosErr.Message+koiosErr.Details)
+		}
+		return "", err
+	}
+	return txId, nil
+}
+
+// do sends a request to the API and decodes the json response into out. The
+// body is sent as cbor if it's a byte slice and as json otherwise.
+func (k *Koios) do(ctx context.Context, method, path, rng string, body interface{}, out interface{}) error {
+	var reqBody []byte
+	contentType := "application/json"
+	switch b := body.(type) {
+	case nil:
+	case []byte:
+		reqBody = b
+		contentType = "application/cbor"
+	default:
+		var err error
+		if reqBody, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, k.baseURL+path, bytes.NewReader(reqBody))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if rng != "" {
+		req.Header.Set("Range", rng)
+	}
+	if k.token != "" {
+		req.Header.Set("Authorization", "Bearer "+k.token)
+	}
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		koiosErr := &KoiosError{}
+		if err := json.Unmarshal(respBody, koiosErr); err != nil || koiosErr.Message == "" {
+			koiosErr.Message = string(respBody)
+		}
+		koiosErr.StatusCode = resp.StatusCode
+		return koiosErr
+	}
+	return json.Unmarshal(respBody, out)
+}
@@ -0,0 +1,227 @@
+package cardano
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+const koiosTestAddr = Address("addr_test1vqgjd0t02q9yglcjwdc8dht9tz6gkfpqqm7evs5csrklakcqmwv40")
+
+func newKoiosTestServer(t *testing.T, handler http.HandlerFunc) *Koios {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.Header.Get("Authorization"), "Bearer test-token"; got != want {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"code": "PGRST301", "message": "JWT invalid"}`)
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return NewKoios(server.URL, WithKoiosToken("test-token"))
+}
+
+func TestKoiosQueryUtxos(t *testing.T) {
+	policyID := "1d7f33bd23d85e1a25d87d86fac4f199c3197a2f7afeb662a0f34e1e"
+	koios := newKoiosTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.URL.Path, "/address_utxos"; got != want {
+			t.Errorf("got path %v want %v", got, want)
+		}
+		var body struct {
+			Addresses []Address `json:"_addresses"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || len(body.Addresses) != 1 || body.Addresses[0] != koiosTestAddr {
+			t.Errorf("got body %+v", body)
+		}
+		// The first range is full, the second one is not
+		switch r.Header.Get("Range") {
+		case fmt.Sprintf("0-%v", koiosPageSize-1):
+			w.WriteHeader(http.StatusPartialContent)
+			fmt.Fprint(w, "[")
+			for i := 0; i < koiosPageSize; i++ {
+				if i > 0 {
+					fmt.Fprint(w, ",")
+				}
+				fmt.Fprintf(w, `{"tx_hash": "%064x", "tx_index": %v, "address": "%v", "value": "1000000", "asset_list": []}`, 1, i, koiosTestAddr)
+			}
+			fmt.Fprint(w, "]")
+		case fmt.Sprintf("%v-%v", koiosPageSize, 2*koiosPageSize-1):
+			fmt.Fprintf(w, `[{"tx_hash": "%064x", "tx_index": 0, "address": "%v", "value": "2000000", "asset_list": [{"policy_id": "%v", "asset_name": "776f726c64", "quantity": "12"}]}]`, 2, koiosTestAddr, policyID)
+		default:
+			fmt.Fprint(w, "[]")
+		}
+	})
+
+	utxos, err := koios.QueryUtxos(context.Background(), koiosTestAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(utxos), koiosPageSize+1; got != want {
+		t.Fatalf("got %v utxos want %v", got, want)
+	}
+	last := utxos[len(utxos)-1]
+	if got, want := last.Amount, uint64(2000000); got != want {
+		t.Errorf("got amount %v want %v", got, want)
+	}
+	if got, want := last.Assets.Quantity(policyID, "776f726c64"), uint64(12); got != want {
+		t.Errorf("got asset quantity %v want %v", got, want)
+	}
+	if got, want := last.Address, koiosTestAddr; got != want {
+		t.Errorf("got address %v want %v", got, want)
+	}
+}
+
+func TestKoiosQueryProtocolParams(t *testing.T) {
+	koios := newKoiosTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/tip":
+			fmt.Fprint(w, `[{"hash": "ab", "epoch_no": 425, "abs_slot": 110000000, "epoch_slot": 1000, "block_no": 9500000}]`)
+		case "/epoch_params":
+			if got, want := r.URL.Query().Get("_epoch_no"), "425"; got != want {
+				t.Errorf("got epoch %v want %v", got, want)
+			}
+			fmt.Fprint(w, `[{
+				"epoch_no": 425, "min_fee_a": 44, "min_fee_b": 155381, "max_tx_size": 16384,
+				"key_deposit": "2000000", "pool_deposit": "500000000", "min_utxo_value": null,
+				"price_mem": 0.0577, "price_step": 0.0000721, "coins_per_utxo_size": "4310",
+				"cost_models": {"PlutusV1": [205665, -1], "PlutusV2": [205665, 812]}
+			}]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	protocol, err := koios.QueryProtocolParams(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ProtocolParams{
+		MinFeeA:          44,
+		MinFeeB:          155381,
+		MaxTxSize:        16384,
+		KeyDeposit:       2000000,
+		PoolDeposit:      500000000,
+		CoinsPerUTxOByte: 4310,
+		PriceMem:         Rational{Num: 577, Den: 10000},
+		PriceStep:        Rational{Num: 721, Den: 10000000},
+		CostModels:       map[PlutusVersion][]int64{PlutusV1: {205665, -1}, PlutusV2: {205665, 812}},
+	}
+	if !reflect.DeepEqual(protocol, want) {
+		t.Errorf("got %+v want %+v", protocol, want)
+	}
+}
+
+func TestKoiosQueryTxStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     TxStatus
+	}{
+		{name: "confirmed", response: fmt.Sprintf(`[{"tx_hash": "%064x", "block_height": 9499998}]`, 1), want: TxStatus{Confirmed: true, Block: 9499998}},
+		{name: "pending", response: `[]`, want: TxStatus{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			koios := newKoiosTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/tx_info" {
+					fmt.Fprint(w, tt.response)
+				}
+			})
+
+			status, err := koios.QueryTxStatus(context.Background(), TransactionID(fmt.Sprintf("%064x", 1)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if status != tt.want {
+				t.Errorf("got status %+v want %+v", status, tt.want)
+			}
+		})
+	}
+}
+
+func TestKoiosQueryAddressUsed(t *testing.T) {
+	koios := newKoiosTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.URL.Path, "/address_txs"; got != want {
+			t.Errorf("got path %v want %v", got, want)
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		if bytes.Contains(body, []byte("unused")) {
+			fmt.Fprint(w, `[]`)
+			return
+		}
+		fmt.Fprintf(w, `[{"tx_hash": "%064x", "epoch_no": 425, "block_height": 9500000, "block_time": 1650000000}]`, 1)
+	})
+
+	if used, err := koios.QueryAddressUsed(context.Background(), "addr_test1used"); err != nil || !used {
+		t.Errorf("got used %v, %v want true", used, err)
+	}
+	if used, err := koios.QueryAddressUsed(context.Background(), "addr_test1unused"); err != nil || used {
+		t.Errorf("got used %v, %v want false", used, err)
+	}
+}
+
+func TestKoiosSubmitTx(t *testing.T) {
+	tx := Transaction{Body: TransactionBody{Inputs: []TransactionInput{{ID: make([]byte, 32)}}, Fee: 170000, Ttl: 100}}
+	koios := newKoiosTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.Header.Get("Content-Type"), "application/cbor"; got != want {
+			t.Errorf("got content type %v want %v", got, want)
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		if got, want := hex.EncodeToString(body), tx.CborHex(); got != want {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"message": "transaction submit error"}`)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprintf(w, `"%v"`, tx.ID())
+	})
+
+	txId, err := koios.SubmitTx(context.Background(), tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := txId, tx.ID(); got != want {
+		t.Errorf("got tx id %v want %v", got, want)
+	}
+
+	koios = newKoiosTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"message": "transaction submit error"}`)
+	})
+	var koiosErr *KoiosError
+	if _, err := koios.SubmitTx(context.Background(), tx); !errors.As(err, &koiosErr) || koiosErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("got error %v want bad request", err)
+	}
+
+	koios = newKoiosTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, `ApplyTxError [ConwayUtxowFailure (UtxoFailure (BadInputsUTxO (fromList [TxIn (TxId {unTxId = SafeHash "%064x"}) (TxIx 0)])))]`, 0)
+	})
+	_, err = koios.SubmitTx(context.Background(), tx)
+	var badInputsErr *BadInputsError
+	if !errors.As(err, &badInputsErr) || len(badInputsErr.Inputs) != 1 {
+		t.Errorf("got error %v want bad inputs", err)
+	}
+}
+
+func TestKoiosQueryRewardAccount(t *testing.T) {
+	koios := newKoiosTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"stake_address": "stake_test1uqfu74w3wh4gfzu8m6e7j987h4lq9r3t7ef5gaw497uu85qsqfy27", "status": "registered", "rewards_available": "31337", "delegated_pool": "pool1pu5jlj4q9w9jlxeu370a3c9myx47md5j5m2str0naunn2q3lkdy"}]`)
+	})
+
+	account, err := koios.QueryRewardAccount(context.Background(), "stake_test1uqfu74w3wh4gfzu8m6e7j987h4lq9r3t7ef5gaw497uu85qsqfy27")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !account.Registered || account.Balance != 31337 || account.Delegation != "pool1pu5jlj4q9w9jlxeu370a3c9myx47md5j5m2str0naunn2q3lkdy" {
+		t.Errorf("got account %+v", account)
+	}
+}
@@ -0,0 +1,238 @@
+package cardano
+
+import (
+	"encoding/hex"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Rejections reported as text by cardano-cli, Blockfrost and Koios are the
+// Haskell representation of the ledger failures, like:
+//
+//	ApplyTxError [UtxowFailure (UtxoFailure (FeeTooSmallUTxO (Coin 200000) (Coin 170000)))]
+//
+// The failures are matched by name and their fields picked up with regular
+// expressions, which is good enough for the common ones across eras.
+var (
+	ledgerCoinRegexp     = regexp.MustCompile(`Coin (\d+)`)
+	ledgerIntRegexp      = regexp.MustCompile(`\b(\d+)\b`)
+	ledgerValueRegexp    = regexp.MustCompile(`MaryValue \(?(?:Coin )?(\d+)`)
+	ledgerSuppliedRegexp = regexp.MustCompile(`mismatchSupplied = (?:Coin )?(\d+)`)
+	ledgerExpectedRegexp = regexp.MustCompile(`mismatchExpected = (?:Coin )?(\d+)`)
+	ledgerTxInRegexp     = regexp.MustCompile(`SafeHash "([0-9a-f]{64})"\}?\)? \(?(?:TxIx )?(\d+)`)
+	ledgerKeyHashRegexp  = regexp.MustCompile(`"([0-9a-f]{56})"`)
+	ledgerVKeyRegexp     = regexp.MustCompile(`"([0-9a-f]{64})"`)
+	ledgerBeforeRegexp   = regexp.MustCompile(`invalidBefore = SJust \(SlotNo (\d+)\)`)
+	ledgerAfterRegexp    = regexp.MustCompile(`invalidHereafter = SJust \(SlotNo (\d+)\)`)
+	ledgerSlotRegexp     = regexp.MustCompile(`SlotNo (\d+)\)*$`)
+)
+
+// ledgerFailureParsers maps the names of the ledger failures to the parsers
+// of their fields.
+var ledgerFailureParsers = []struct {
+	name  string
+	parse func(fields string) error
+}{
+	{"BadInputsUTxO", parseBadInputs},
+	{"FeeTooSmallUTxO", parseFeeTooSmall},
+	{"OutsideValidityIntervalUTxO", parseOutsideValidityInterval},
+	{"ValueNotConservedUTxO", parseValueNotConserved},
+	{"BabbageOutputTooSmallUTxO", parseOutputTooSmall},
+	{"OutputTooSmallUTxO", parseOutputTooSmall},
+	{"MaxTxSizeUTxO", parseMaxTxSize},
+	{"MissingVKeyWitnessesUTXOW", parseMissingVKeyWitnesses},
+	{"InvalidWitnessesUTXOW", parseInvalidWitnesses},
+}
+
+// parseLedgerFailures returns the typed errors of the known ledger failures
+// found in a text rejection, in order.
+func parseLedgerFailures(msg string) ValidationErrors {
+	var errs ValidationErrors
+	var starts []int
+	for _, parser := range ledgerFailureParsers {
+		for offset := 0; ; {
+			i := strings.Index(msg[offset:], parser.name)
+			if i < 0 {
+				break
+			}
+			start := offset + i
+			offset = start + len(parser.name)
+			// OutputTooSmallUTxO is a suffix of BabbageOutputTooSmallUTxO
+			if start > 0 && isIdentChar(msg[start-1]) || offset < len(msg) && isIdentChar(msg[offset]) {
+				continue
+			}
+			errs = append(errs, parser.parse(ledgerFailureFields(msg[offset:])))
+			starts = append(starts, start)
+		}
+	}
+	sort.Sort(ledgerFailuresByStart{errs, starts})
+	return errs
+}
+
+// ledgerFailureFields returns the text of the fields following the name of a
+// failure, up to the end of the expression enclosing it.
+func ledgerFailureFields(msg string) string {
+	depth, quoted := 0, false
+	for i := 0; i < len(msg); i++ {
+		switch c := msg[i]; {
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']' || c == '}':
+			if depth == 0 {
+				return strings.TrimSpace(msg[:i])
+			}
+			depth--
+		case c == ',' && depth == 0:
+			return strings.TrimSpace(msg[:i])
+		}
+	}
+	return strings.TrimSpace(msg)
+}
+
+type ledgerFailuresByStart struct {
+	errs   ValidationErrors
+	starts []int
+}
+
+func (s ledgerFailuresByStart) Len() int           { return len(s.errs) }
+func (s ledgerFailuresByStart) Less(i, j int) bool { return s.starts[i] < s.starts[j] }
+func (s ledgerFailuresByStart) Swap(i, j int) {
+	s.errs[i], s.errs[j] = s.errs[j], s.errs[i]
+	s.starts[i], s.starts[j] = s.starts[j], s.starts[i]
+}
+
+func isIdentChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_'
+}
+
+// ledgerUints returns the numbers of the first submatch of every match.
+func ledgerUints(re *regexp.Regexp, fields string) []uint64 {
+	var values []uint64
+	for _, match := range re.FindAllStringSubmatch(fields, -1) {
+		value, err := strconv.ParseUint(match[1], 10, 64)
+		if err == nil {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// ledgerMismatch returns the supplied and expected values of a Conway
+// mismatch, ok being false if the fields are not a mismatch.
+func ledgerMismatch(fields string) (supplied, expected uint64, ok bool) {
+	s, e := ledgerUints(ledgerSuppliedRegexp, fields), ledgerUints(ledgerExpectedRegexp, fields)
+	if len(s) == 0 || len(e) == 0 {
+		return 0, 0, false
+	}
+	return s[0], e[0], true
+}
+
+func parseBadInputs(fields string) error {
+	badInputs := &BadInputsError{}
+	for _, match := range ledgerTxInRegexp.FindAllStringSubmatch(fields, -1) {
+		id, _ := hex.DecodeString(match[1])
+		index, _ := strconv.ParseUint(match[2], 10, 64)
+		badInputs.Inputs = append(badInputs.Inputs, TransactionInput{ID: id, Index: index})
+	}
+	return badInputs
+}
+
+func parseFeeTooSmall(fields string) error {
+	if fee, minFee, ok := ledgerMismatch(fields); ok {
+		return &FeeTooSmallError{Fee: fee, MinFee: minFee}
+	}
+	feeErr := &FeeTooSmallError{}
+	if coins := ledgerUints(ledgerCoinRegexp, fields); len(coins) == 2 {
+		feeErr.MinFee, feeErr.Fee = coins[0], coins[1]
+	}
+	return feeErr
+}
+
+func parseOutsideValidityInterval(fields string) error {
+	outside := &OutsideValidityIntervalError{}
+	if before := ledgerUints(ledgerBeforeRegexp, fields); len(before) > 0 {
+		outside.ValidFrom = before[0]
+	}
+	if after := ledgerUints(ledgerAfterRegexp, fields); len(after) > 0 {
+		outside.TTL = after[0]
+	}
+	if slot := ledgerUints(ledgerSlotRegexp, fields); len(slot) > 0 {
+		outside.Slot = slot[0]
+	}
+	return outside
+}
+
+// parseValueNotConserved only picks up the lovelace of the values.
+func parseValueNotConserved(fields string) error {
+	notConserved := &ValueNotConservedError{}
+	if consumed, produced, ok := ledgerMismatch(fields); ok {
+		notConserved.Consumed.Coin, notConserved.Produced.Coin = consumed, produced
+	} else if values := ledgerUints(ledgerValueRegexp, fields); len(values) == 2 {
+		notConserved.Consumed.Coin, notConserved.Produced.Coin = values[0], values[1]
+	}
+	return notConserved
+}
+
+// parseOutputTooSmall reports the first output, the minimum amount being only
+// known since Babbage.
+func parseOutputTooSmall(fields string) error {
+	tooSmall := &OutputTooSmallError{Index: -1}
+	amounts := ledgerUints(ledgerValueRegexp, fields)
+	if len(amounts) == 0 {
+		amounts = ledgerUints(ledgerCoinRegexp, fields)
+	}
+	if len(amounts) > 0 {
+		tooSmall.Amount = amounts[0]
+	}
+	if strings.HasPrefix(fields, "[(") {
+		// Babbage outputs are paired with their minimum amount
+		if coins := ledgerUints(ledgerCoinRegexp, fields); len(coins) > 0 {
+			tooSmall.MinAmount = coins[len(coins)-1]
+		}
+	}
+	return tooSmall
+}
+
+func parseMaxTxSize(fields string) error {
+	if size, maxSize, ok := ledgerMismatch(fields); ok {
+		return &MaxTxSizeExceededError{Size: size, MaxSize: maxSize}
+	}
+	sizeErr := &MaxTxSizeExceededError{}
+	if sizes := ledgerUints(ledgerIntRegexp, fields); len(sizes) == 2 {
+		sizeErr.Size, sizeErr.MaxSize = sizes[0], sizes[1]
+	}
+	return sizeErr
+}
+
+func parseMissingVKeyWitnesses(fields string) error {
+	missing := &MissingVKeyWitnessesError{}
+	for _, match := range ledgerKeyHashRegexp.FindAllStringSubmatch(fields, -1) {
+		hash, _ := hex.DecodeString(match[1])
+		missing.KeyHashes = append(missing.KeyHashes, hash)
+	}
+	return missing
+}
+
+func parseInvalidWitnesses(fields string) error {
+	invalid := &InvalidSignatureError{}
+	if match := ledgerVKeyRegexp.FindStringSubmatch(fields); match != nil {
+		invalid.VKey, _ = hex.DecodeString(match[1])
+	}
+	return invalid
+}
+
+// submitError adds the typed errors of the ledger failures found in the text
+// rejection msg to err, the error of the backend.
+func submitError(err error, msg string) error {
+	// Some APIs return the rejection json encoded in the message
+	failures := parseLedgerFailures(strings.ReplaceAll(msg, `\"`, `"`))
+	if len(failures) == 0 {
+		return err
+	}
+	return append(failures, err)
+}
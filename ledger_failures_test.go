@@ -0,0 +1,86 @@
+package cardano
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseLedgerFailures(t *testing.T) {
+	txId, _ := hex.DecodeString("abababababababababababababababababababababababababababababababab")
+	keyHash, _ := hex.DecodeString("cdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcd")
+
+	tests := []struct {
+		name string
+		msg  string
+		want ValidationErrors
+	}{
+		{
+			name: "babbage",
+			msg: `Command failed: transaction submit  Error: Error while submitting tx: ShelleyTxValidationError ShelleyBasedEraBabbage (ApplyTxError [` +
+				`UtxowFailure (UtxoFailure (AlonzoInBabbageUtxoPredFailure (ValueNotConservedUTxO (MaryValue 0 (MultiAsset (fromList []))) (MaryValue 9829735 (MultiAsset (fromList [])))))),` +
+				`UtxowFailure (UtxoFailure (AlonzoInBabbageUtxoPredFailure (BadInputsUTxO (fromList [TxIn (TxId {unTxId = SafeHash "abababababababababababababababababababababababababababababababab"}) (TxIx 1)])))),` +
+				`UtxowFailure (UtxoFailure (AlonzoInBabbageUtxoPredFailure (FeeTooSmallUTxO (Coin 171000) (Coin 170000)))),` +
+				`UtxowFailure (UtxoFailure (AlonzoInBabbageUtxoPredFailure (OutsideValidityIntervalUTxO (ValidityInterval {invalidBefore = SNothing, invalidHereafter = SJust (SlotNo 100)}) (SlotNo 200))))])`,
+			want: ValidationErrors{
+				&ValueNotConservedError{Consumed: Value{Coin: 0}, Produced: Value{Coin: 9829735}},
+				&BadInputsError{Inputs: []TransactionInput{{ID: txId, Index: 1}}},
+				&FeeTooSmallError{Fee: 170000, MinFee: 171000},
+				&OutsideValidityIntervalError{Slot: 200, TTL: 100},
+			},
+		},
+		{
+			name: "babbage outputs",
+			msg: `ApplyTxError [UtxowFailure (UtxoFailure (BabbageOutputTooSmallUTxO [(Sized {sizedValue = (BabbageTxOut (Addr Testnet (KeyHashObj (KeyHash "cdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcd")) StakeRefNull) (MaryValue 1000 (MultiAsset (fromList []))) NoDatum SNothing), sizedSize = 67},Coin 969750)])),` +
+				`UtxowFailure (UtxoFailure (AlonzoInBabbageUtxoPredFailure (MaxTxSizeUTxO 20000 16384))),` +
+				`UtxowFailure (MissingVKeyWitnessesUTXOW (fromList [KeyHash {unKeyHash = "cdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcd"}]))]`,
+			want: ValidationErrors{
+				&OutputTooSmallError{Index: -1, Amount: 1000, MinAmount: 969750},
+				&MaxTxSizeExceededError{Size: 20000, MaxSize: 16384},
+				&MissingVKeyWitnessesError{KeyHashes: [][]byte{keyHash}},
+			},
+		},
+		{
+			name: "conway",
+			msg: `ConwayUtxowFailure (UtxoFailure (FeeTooSmallUTxO (Mismatch {mismatchSupplied = Coin 170000, mismatchExpected = Coin 171000}))),` +
+				`ConwayUtxowFailure (UtxoFailure (MaxTxSizeUTxO (Mismatch {mismatchSupplied = 20000, mismatchExpected = 16384}))),` +
+				`ConwayUtxowFailure (InvalidWitnessesUTXOW [VKey (VerKeyEd25519DSIGN "abababababababababababababababababababababababababababababababab")])`,
+			want: ValidationErrors{
+				&FeeTooSmallError{Fee: 170000, MinFee: 171000},
+				&MaxTxSizeExceededError{Size: 20000, MaxSize: 16384},
+				&InvalidSignatureError{VKey: txId},
+			},
+		},
+		{
+			name: "unknown",
+			msg:  `ApplyTxError [ConwayUtxowFailure (UtxoFailure (InsufficientCollateral (DeltaCoin 0) (Coin 1000)))]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseLedgerFailures(tt.msg); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunCommandError(t *testing.T) {
+	msg := `ApplyTxError [UtxowFailure (UtxoFailure (FeeTooSmallUTxO (Coin 171000) (Coin 170000)))]`
+	_, err := runCommand(context.Background(), "sh", "-c", "echo '"+msg+"' >&2; exit 1")
+	var cliErr *CliError
+	if !errors.As(err, &cliErr) {
+		t.Fatalf("got error %v want %T", err, cliErr)
+	}
+
+	err = submitError(cliErr, cliErr.Stderr)
+	var feeErr *FeeTooSmallError
+	if !errors.As(err, &feeErr) || feeErr.MinFee != 171000 {
+		t.Errorf("got error %v want fee too small", err)
+	}
+	if !errors.As(err, &cliErr) {
+		t.Errorf("got error %v want %T", err, cliErr)
+	}
+}
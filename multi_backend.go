@@ -0,0 +1,388 @@
+package cardano
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoQuorum is returned by a MultiBackend in quorum mode when not enough
+// backends agree on an answer.
+var ErrNoQuorum = errors.New("backends: no quorum")
+
+const (
+	multiBackendTimeout = 10 * time.Second
+	// multiBackendTipTolerance is how many blocks the tips of backends
+	// agreeing in quorum mode can be apart.
+	multiBackendTipTolerance = 2
+)
+
+// BackendErrors holds the error of every backend of a MultiBackend that
+// failed a call.
+type BackendErrors []error
+
+func (errs BackendErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return "all backends failed: " + strings.Join(msgs, "; ")
+}
+
+func (errs BackendErrors) As(target interface{}) bool {
+	for _, err := range errs {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (errs BackendErrors) Is(target error) bool {
+	for _, err := range errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// MultiBackendOption configures a MultiBackend.
+type MultiBackendOption func(*MultiBackend)
+
+// WithMultiBackendTimeout sets how long a backend has to answer before
+// failing over to the next one, 10 seconds by default.
+func WithMultiBackendTimeout(timeout time.Duration) MultiBackendOption {
+	return func(m *MultiBackend) {
+		m.timeout = timeout
+	}
+}
+
+// WithMultiBackendQuorum makes the tip and UTxO queries ask every backend,
+// and only succeed when at least quorum of them agree. The UTxOs must be the
+// same, while tips agree when they are at most the tip tolerance apart.
+func WithMultiBackendQuorum(quorum int) MultiBackendOption {
+	return func(m *MultiBackend) {
+		m.quorum = quorum
+	}
+}
+
+// WithMultiBackendTipTolerance sets how many blocks the tips of backends can
+// be apart and still agree in quorum mode, 2 by default.
+func WithMultiBackendTipTolerance(blocks uint64) MultiBackendOption {
+	return func(m *MultiBackend) {
+		m.tipTolerance = blocks
+	}
+}
+
+// MultiBackend is a ChainBackend spreading calls over several backends.
+//
+// Queries are sent to the first healthy backend, failing over to the next
+// ones on errors and timeouts. A backend that failed is marked unhealthy and
+// only tried again after the healthy ones, until it answers a call or a
+// health check. Transactions are submitted to all backends in parallel.
+type MultiBackend struct {
+	backends     []ChainBackend
+	timeout      time.Duration
+	quorum       int
+	tipTolerance uint64
+
+	mu      sync.Mutex
+	healthy []bool
+}
+
+// NewMultiBackend creates a MultiBackend over the backends, in order of
+// preference. It fails if the quorum is negative or larger than the number of
+// backends.
+func NewMultiBackend(backends []ChainBackend, opts ...MultiBackendOption) (*MultiBackend, error) {
+	m := &MultiBackend{
+		backends:     backends,
+		timeout:      multiBackendTimeout,
+		tipTolerance: multiBackendTipTolerance,
+		healthy:      make([]bool, len(backends)),
+	}
+	for i := range m.healthy {
+		m.healthy[i] = true
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	if m.quorum < 0 || m.quorum > len(backends) {
+		return nil, fmt.Errorf("backends: invalid quorum %v of %v backends", m.quorum, len(backends))
+	}
+	return m, nil
+}
+
+// Healthy returns whether each backend is healthy.
+func (m *MultiBackend) Healthy() []bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]bool{}, m.healthy...)
+}
+
+// CheckHealth queries the tip of every backend and updates their health. It
+// returns an error if none is healthy.
+func (m *MultiBackend) CheckHealth(ctx context.Context) error {
+	errs := make([]error, len(m.backends))
+	m.all(ctx, func(ctx context.Context, i int, backend ChainBackend) {
+		_, errs[i] = backend.QueryTip(ctx)
+		m.setHealthy(i, errs[i] == nil)
+	})
+	for _, err := range errs {
+		if err == nil {
+			return nil
+		}
+	}
+	return BackendErrors(errs)
+}
+
+// HealthCheck runs CheckHealth every interval until ctx is done.
+func (m *MultiBackend) HealthCheck(ctx context.Context, interval time.Duration) error {
+	for {
+		m.CheckHealth(ctx)
+		if err := sleepContext(ctx, interval); err != nil {
+			return err
+		}
+	}
+}
+
+// QueryUtxos returns the unspent outputs locked by the address.
+func (m *MultiBackend) QueryUtxos(ctx context.Context, addr Address) ([]Utxo, error) {
+	if m.quorum > 0 {
+		answer, err := m.quorumCall(ctx, func(ctx context.Context, backend ChainBackend) (interface{}, error) {
+			return backend.QueryUtxos(ctx, addr)
+		}, func(a, b interface{}) bool {
+			return utxosDigest(a.([]Utxo)) == utxosDigest(b.([]Utxo))
+		})
+		if err != nil {
+			return nil, err
+		}
+		return answer.([]Utxo), nil
+	}
+
+	var utxos []Utxo
+	err := m.failover(ctx, func(ctx context.Context, backend ChainBackend) (err error) {
+		utxos, err = backend.QueryUtxos(ctx, addr)
+		return err
+	})
+	return utxos, err
+}
+
+// QueryTip returns the tip of the chain.
+func (m *MultiBackend) QueryTip(ctx context.Context) (NodeTip, error) {
+	if m.quorum > 0 {
+		// A tip agrees with the tips at most the tolerance ahead of it, so
+		// that the returned tip is reached by a quorum of backends
+		answer, err := m.quorumCall(ctx, func(ctx context.Context, backend ChainBackend) (interface{}, error) {
+			return backend.QueryTip(ctx)
+		}, func(a, b interface{}) bool {
+			tip, other := a.(NodeTip), b.(NodeTip)
+			return other.Block >= tip.Block && other.Block-tip.Block <= m.tipTolerance
+		})
+		if err != nil {
+			return NodeTip{}, err
+		}
+		return answer.(NodeTip), nil
+	}
+
+	var tip NodeTip
+	err := m.failover(ctx, func(ctx context.Context, backend ChainBackend) (err error) {
+		tip, err = backend.QueryTip(ctx)
+		return err
+	})
+	return tip, err
+}
+
+// QueryProtocolParams returns the current protocol parameters.
+func (m *MultiBackend) QueryProtocolParams(ctx context.Context) (ProtocolParams, error) {
+	var protocol ProtocolParams
+	err := m.failover(ctx, func(ctx context.Context, backend ChainBackend) (err error) {
+		protocol, err = backend.QueryProtocolParams(ctx)
+		return err
+	})
+	return protocol, err
+}
+
+// QueryAddressUsed returns whether the address was ever used, asking the
+// backends that can tell.
+func (m *MultiBackend) QueryAddressUsed(ctx context.Context, addr Address) (bool, error) {
+	var used bool
+	err := m.failover(ctx, func(ctx context.Context, backend ChainBackend) (err error) {
+		querier, ok := backend.(AddressUsageQuerier)
+		if !ok {
+			return ErrNotSupported
+		}
+		used, err = querier.QueryAddressUsed(ctx, addr)
+		return err
+	})
+	return used, err
+}
+
+// QueryTxStatus returns whether the transaction is included in the chain.
+func (m *MultiBackend) QueryTxStatus(ctx context.Context, txId TransactionID) (TxStatus, error) {
+	var status TxStatus
+	err := m.failover(ctx, func(ctx context.Context, backend ChainBackend) (err error) {
+		status, err = backend.QueryTxStatus(ctx, txId)
+		return err
+	})
+	return status, err
+}
+
+// QueryRewardAccount returns the state of the reward account of the stake
+// address.
+func (m *MultiBackend) QueryRewardAccount(ctx context.Context, stakeAddr Address) (RewardAccount, error) {
+	var account RewardAccount
+	err := m.failover(ctx, func(ctx context.Context, backend ChainBackend) (err error) {
+		account, err = backend.QueryRewardAccount(ctx, stakeAddr)
+		return err
+	})
+	return account, err
+}
+
+// SubmitTx submits the transaction to every backend in parallel. It succeeds
+// if any of them accepts it, and returns BackendErrors otherwise.
+func (m *MultiBackend) SubmitTx(ctx context.Context, tx Transaction) (TransactionID, error) {
+	txIds := make([]TransactionID, len(m.backends))
+	errs := make([]error, len(m.backends))
+	m.all(ctx, func(ctx context.Context, i int, backend ChainBackend) {
+		txIds[i], errs[i] = backend.SubmitTx(ctx, tx)
+	})
+	for i, err := range errs {
+		if err == nil {
+			return txIds[i], nil
+		}
+	}
+	return "", BackendErrors(errs)
+}
+
+// failover calls fn with the backends in order until one succeeds. Backends
+// that don't support the call are skipped without being marked unhealthy, and
+// left out of the returned BackendErrors. It returns ErrNotSupported if none
+// of the backends supports the call.
+func (m *MultiBackend) failover(ctx context.Context, fn func(context.Context, ChainBackend) error) error {
+	var errs BackendErrors
+	unsupported := false
+	for _, i := range m.order() {
+		callCtx, cancel := context.WithTimeout(ctx, m.timeout)
+		err := fn(callCtx, m.backends[i])
+		cancel()
+		if err == nil {
+			m.setHealthy(i, true)
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if errors.Is(err, ErrNotSupported) {
+			unsupported = true
+			continue
+		}
+		m.setHealthy(i, false)
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	if unsupported {
+		return ErrNotSupported
+	}
+	return fmt.Errorf("backends: no backend")
+}
+
+// quorumCall calls fn on every backend, unhealthy ones included so that they
+// can recover, and returns the first answer, in order of preference, agreed
+// with by at least quorum backends.
+func (m *MultiBackend) quorumCall(ctx context.Context, fn func(context.Context, ChainBackend) (interface{}, error), agree func(answer, other interface{}) bool) (interface{}, error) {
+	answers := make([]interface{}, len(m.backends))
+	errs := make([]error, len(m.backends))
+	m.all(ctx, func(ctx context.Context, i int, backend ChainBackend) {
+		answers[i], errs[i] = fn(ctx, backend)
+	})
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	answered := []int{}
+	var failed BackendErrors
+	for _, i := range m.order() {
+		if errs[i] != nil {
+			if !errors.Is(errs[i], ErrNotSupported) {
+				m.setHealthy(i, false)
+			}
+			failed = append(failed, errs[i])
+			continue
+		}
+		m.setHealthy(i, true)
+		answered = append(answered, i)
+	}
+	if len(answered) == 0 {
+		return nil, failed
+	}
+	for _, i := range answered {
+		votes := 0
+		for _, j := range answered {
+			if agree(answers[i], answers[j]) {
+				votes++
+			}
+		}
+		if votes >= m.quorum {
+			return answers[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %v backends answered, fewer than %v agree", ErrNoQuorum, len(answered), m.quorum)
+}
+
+// all calls fn on every backend in parallel and waits for them.
+func (m *MultiBackend) all(ctx context.Context, fn func(context.Context, int, ChainBackend)) {
+	var wg sync.WaitGroup
+	for i, backend := range m.backends {
+		wg.Add(1)
+		go func(i int, backend ChainBackend) {
+			defer wg.Done()
+			callCtx, cancel := context.WithTimeout(ctx, m.timeout)
+			defer cancel()
+			fn(callCtx, i, backend)
+		}(i, backend)
+	}
+	wg.Wait()
+}
+
+// order returns the indexes of the healthy backends followed by the unhealthy
+// ones.
+func (m *MultiBackend) order() []int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	indexes := make([]int, 0, len(m.backends))
+	for i, healthy := range m.healthy {
+		if healthy {
+			indexes = append(indexes, i)
+		}
+	}
+	for i, healthy := range m.healthy {
+		if !healthy {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+func (m *MultiBackend) setHealthy(i int, healthy bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.healthy[i] = healthy
+}
+
+// utxosDigest returns a digest of the outputs that doesn't depend on their
+// order.
+func utxosDigest(utxos []Utxo) string {
+	keys := make([]string, len(utxos))
+	for i, utxo := range utxos {
+		keys[i] = fmt.Sprintf("%v#%v:%v:%v", utxo.TxId, utxo.Index, utxo.Amount, utxo.Assets)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
@@ -0,0 +1,344 @@
+package cardano
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeBackend answers every query with its fields, after delay.
+type fakeBackend struct {
+	tip       NodeTip
+	utxos     []Utxo
+	err       error
+	submitErr error
+	delay     time.Duration
+	calls     int32
+	submitted int32
+}
+
+func (f *fakeBackend) wait(ctx context.Context) error {
+	atomic.AddInt32(&f.calls, 1)
+	if err := sleepContext(ctx, f.delay); err != nil {
+		return err
+	}
+	return f.err
+}
+
+func (f *fakeBackend) QueryUtxos(ctx context.Context, addr Address) ([]Utxo, error) {
+	if err := f.wait(ctx); err != nil {
+		return nil, err
+	}
+	return f.utxos, nil
+}
+
+func (f *fakeBackend) QueryTip(ctx context.Context) (NodeTip, error) {
+	if err := f.wait(ctx); err != nil {
+		return NodeTip{}, err
+	}
+	return f.tip, nil
+}
+
+func (f *fakeBackend) QueryProtocolParams(ctx context.Context) (ProtocolParams, error) {
+	if err := f.wait(ctx); err != nil {
+		return ProtocolParams{}, err
+	}
+	return ShelleyProtocol, nil
+}
+
+func (f *fakeBackend) QueryTxStatus(ctx context.Context, txId TransactionID) (TxStatus, error) {
+	return TxStatus{}, ErrNotSupported
+}
+
+func (f *fakeBackend) QueryRewardAccount(ctx context.Context, stakeAddr Address) (RewardAccount, error) {
+	if err := f.wait(ctx); err != nil {
+		return RewardAccount{}, err
+	}
+	return RewardAccount{Address: stakeAddr}, nil
+}
+
+func (f *fakeBackend) SubmitTx(ctx context.Context, tx Transaction) (TransactionID, error) {
+	if err := f.wait(ctx); err != nil {
+		return "", err
+	}
+	atomic.AddInt32(&f.submitted, 1)
+	if f.submitErr != nil {
+		return "", f.submitErr
+	}
+	return tx.ID(), nil
+}
+
+func TestMultiBackendFailover(t *testing.T) {
+	errDown := errors.New("backend down")
+	tests := []struct {
+		name        string
+		backends    []*fakeBackend
+		want        NodeTip
+		wantErr     error
+		wantHealthy []bool
+	}{
+		{
+			name:        "first healthy",
+			backends:    []*fakeBackend{{tip: NodeTip{Block: 1}}, {tip: NodeTip{Block: 2}}},
+			want:        NodeTip{Block: 1},
+			wantHealthy: []bool{true, true},
+		},
+		{
+			name:        "error",
+			backends:    []*fakeBackend{{err: errDown}, {tip: NodeTip{Block: 2}}},
+			want:        NodeTip{Block: 2},
+			wantHealthy: []bool{false, true},
+		},
+		{
+			name:        "timeout",
+			backends:    []*fakeBackend{{delay: time.Second}, {tip: NodeTip{Block: 2}}},
+			want:        NodeTip{Block: 2},
+			wantHealthy: []bool{false, true},
+		},
+		{
+			name:        "all down",
+			backends:    []*fakeBackend{{err: errDown}, {err: errDown}},
+			wantErr:     errDown,
+			wantHealthy: []bool{false, false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backends := make([]ChainBackend, len(tt.backends))
+			for i, backend := range tt.backends {
+				backends[i] = backend
+			}
+			multi, err := NewMultiBackend(backends, WithMultiBackendTimeout(20*time.Millisecond))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tip, err := multi.QueryTip(context.Background())
+			if tt.wantErr != nil {
+				var backendErrs BackendErrors
+				if !errors.Is(err, tt.wantErr) || !errors.As(err, &backendErrs) {
+					t.Fatalf("got error %v want %v", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := tip, tt.want; got != want {
+				t.Errorf("got tip %+v want %+v", got, want)
+			}
+			if got, want := multi.Healthy(), tt.wantHealthy; !reflect.DeepEqual(got, want) {
+				t.Errorf("got healthy %v want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestMultiBackendHealth(t *testing.T) {
+	first, second := &fakeBackend{err: errors.New("backend down")}, &fakeBackend{tip: NodeTip{Block: 2}}
+	multi, err := NewMultiBackend([]ChainBackend{first, second})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := multi.CheckHealth(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := multi.Healthy(), []bool{false, true}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got healthy %v want %v", got, want)
+	}
+
+	// Unhealthy backends are tried last
+	if _, err := multi.QueryProtocolParams(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := atomic.LoadInt32(&first.calls), int32(1); got != want {
+		t.Errorf("got %v calls to the unhealthy backend want %v", got, want)
+	}
+
+	// Calls not supported by a backend don't make it unhealthy
+	if _, err := multi.QueryTxStatus(context.Background(), ""); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("got error %v want %v", err, ErrNotSupported)
+	}
+	if got, want := multi.Healthy(), []bool{false, true}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got healthy %v want %v", got, want)
+	}
+
+	first.err = nil
+	if err := multi.CheckHealth(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := multi.Healthy(), []bool{true, true}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got healthy %v want %v", got, want)
+	}
+}
+
+func TestMultiBackendSubmitTx(t *testing.T) {
+	tx := Transaction{Body: TransactionBody{Inputs: []TransactionInput{{ID: make([]byte, 32)}}, Fee: 170000, Ttl: 100}}
+	rejected := &BadInputsError{}
+	tests := []struct {
+		name     string
+		backends []*fakeBackend
+		wantErr  interface{}
+	}{
+		{
+			name:     "any accepts",
+			backends: []*fakeBackend{{err: errors.New("backend down")}, {submitErr: rejected}, {}},
+		},
+		{
+			name:     "all reject",
+			backends: []*fakeBackend{{submitErr: rejected}, {submitErr: rejected}},
+			wantErr:  new(*BadInputsError),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backends := make([]ChainBackend, len(tt.backends))
+			for i, backend := range tt.backends {
+				backends[i] = backend
+			}
+			multi, err := NewMultiBackend(backends)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			txId, err := multi.SubmitTx(context.Background(), tx)
+			for i, backend := range tt.backends {
+				if backend.err == nil && atomic.LoadInt32(&backend.submitted) != 1 {
+					t.Errorf("backend %v got no submission", i)
+				}
+			}
+			if tt.wantErr != nil {
+				if !errors.As(err, tt.wantErr) {
+					t.Errorf("got error %v want %T", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := txId, tx.ID(); got != want {
+				t.Errorf("got tx id %v want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestMultiBackendQuorum(t *testing.T) {
+	utxos := []Utxo{{TxId: "00", Amount: 1}, {TxId: "01", Amount: 2}}
+	reordered := []Utxo{utxos[1], utxos[0]}
+	tests := []struct {
+		name     string
+		backends []*fakeBackend
+		quorum   int
+		wantErr  error
+	}{
+		{
+			name:     "agree",
+			backends: []*fakeBackend{{utxos: utxos}, {utxos: reordered}, {utxos: utxos[:1]}},
+			quorum:   2,
+		},
+		{
+			name:     "disagree",
+			backends: []*fakeBackend{{utxos: utxos}, {utxos: utxos[:1]}, {err: errors.New("backend down")}},
+			quorum:   2,
+			wantErr:  ErrNoQuorum,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backends := make([]ChainBackend, len(tt.backends))
+			for i, backend := range tt.backends {
+				backends[i] = backend
+			}
+			multi, err := NewMultiBackend(backends, WithMultiBackendQuorum(tt.quorum))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := multi.QueryUtxos(context.Background(), "")
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("got error %v want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if utxosDigest(got) != utxosDigest(utxos) {
+				t.Errorf("got utxos %v want %v", got, utxos)
+			}
+		})
+	}
+}
+
+func TestMultiBackendQuorumTip(t *testing.T) {
+	tests := []struct {
+		name    string
+		tips    []uint64
+		want    uint64
+		wantErr error
+	}{
+		{name: "same tip", tips: []uint64{100, 100, 90}, want: 100},
+		{name: "one block apart", tips: []uint64{101, 100, 90}, want: 100},
+		{name: "too far apart", tips: []uint64{110, 100, 90}, wantErr: ErrNoQuorum},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backends := make([]ChainBackend, len(tt.tips))
+			for i, block := range tt.tips {
+				backends[i] = &fakeBackend{tip: NodeTip{Block: block, Slot: block * 20}}
+			}
+			multi, err := NewMultiBackend(backends, WithMultiBackendQuorum(2))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tip, err := multi.QueryTip(context.Background())
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("got error %v want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := tip.Block, tt.want; got != want {
+				t.Errorf("got tip %v want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestMultiBackendQuorumRecovery(t *testing.T) {
+	tip := NodeTip{Block: 100}
+	backends := []*fakeBackend{{tip: tip, err: errors.New("backend down")}, {tip: tip}, {tip: tip}}
+	multi, err := NewMultiBackend([]ChainBackend{backends[0], backends[1], backends[2]}, WithMultiBackendQuorum(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := multi.QueryTip(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The unhealthy backend is still asked, and makes the quorum once back
+	backends[0].err = nil
+	backends[1].err = errors.New("backend down")
+	if _, err := multi.QueryTip(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := multi.Healthy(), []bool{true, false, true}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got health %v want %v", got, want)
+	}
+}
+
+func TestNewMultiBackendInvalidQuorum(t *testing.T) {
+	backends := []ChainBackend{&fakeBackend{}, &fakeBackend{}}
+	for _, quorum := range []int{-1, 3} {
+		if _, err := NewMultiBackend(backends, WithMultiBackendQuorum(quorum)); err == nil {
+			t.Errorf("got no error for quorum %v of %v backends", quorum, len(backends))
+		}
+	}
+}
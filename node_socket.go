@@ -0,0 +1,941 @@
+package cardano
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/echovl/bech32"
+	"github.com/fxamacker/cbor/v2"
+)
+
+// Network magics of the public networks.
+const (
+	MainnetMagic uint64 = 764824073
+	PreprodMagic uint64 = 1
+	PreviewMagic uint64 = 2
+)
+
+// Node-to-client mini protocol numbers.
+const (
+	n2cHandshake         uint16 = 0
+	n2cChainSync         uint16 = 5
+	n2cLocalTxSubmission uint16 = 6
+	n2cLocalStateQuery   uint16 = 7
+)
+
+// Node-to-client versions proposed in the handshake. The version data is the
+// network magic, or a [magic, query] pair from V_15 on.
+const (
+	n2cVersionMin       = 9
+	n2cVersionMax       = 16
+	n2cVersionQueryData = 15
+	n2cVersionFlag      = 0x8000
+)
+
+const (
+	muxHeaderSize    = 8
+	muxMaxPayload    = 12288
+	muxResponderFlag = 0x8000
+)
+
+// Hard fork combinator era indexes.
+const (
+	eraShelley = 1
+	eraBabbage = 5
+	eraConway  = 6
+)
+
+// TxRejectedError is returned when the node rejects a transaction for a
+// reason that has no typed error. Reason holds the cbor encoded failure.
+type TxRejectedError struct {
+	Reason []byte
+}
+
+func (e *TxRejectedError) Error() string {
+	return fmt.Sprintf("node socket: transaction rejected: %x", e.Reason)
+}
+
+// NodeSocket is a ChainBackend speaking the node-to-client protocol over the
+// Unix socket of a local cardano-node. The mini protocols are multiplexed
+// over a single connection, which is dialed again after it drops.
+//
+// NodeSocket is also the BlockSource of a single chain-sync cursor.
+type NodeSocket struct {
+	socketPath string
+	magic      uint64
+
+	mu          sync.Mutex
+	conn        *muxConn
+	queryMu     sync.Mutex
+	submitMu    sync.Mutex
+	chainSyncMu sync.Mutex
+	// cursorConn is the connection of the last FindIntersect, the chain-sync
+	// cursor being reset by a new connection.
+	cursorConn *muxConn
+}
+
+// NewNodeSocket creates a backend for the node socket at socketPath of the
+// network with the given magic. The connection is opened by the first
+// request.
+func NewNodeSocket(socketPath string, magic uint64) *NodeSocket {
+	return &NodeSocket{socketPath: socketPath, magic: magic}
+}
+
+// Network returns the network of the node's magic.
+func (n *NodeSocket) Network() Network {
+	if n.magic == MainnetMagic {
+		return Mainnet
+	}
+	return Testnet
+}
+
+// Close closes the connection to the node.
+func (n *NodeSocket) Close() error {
+	n.mu.Lock()
+	conn := n.conn
+	n.conn = nil
+	n.mu.Unlock()
+
+	if conn == nil {
+		return nil
+	}
+	conn.close(errors.New("node socket: connection closed"))
+	return nil
+}
+
+func (n *NodeSocket) connection(ctx context.Context) (*muxConn, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.conn != nil {
+		select {
+		case <-n.conn.done:
+			n.conn = nil
+		default:
+			return n.conn, nil
+		}
+	}
+
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "unix", n.socketPath)
+	if err != nil {
+		return nil, err
+	}
+	m := newMuxConn(conn, n2cHandshake, n2cChainSync, n2cLocalTxSubmission, n2cLocalStateQuery)
+	if err := n.handshake(ctx, m); err != nil {
+		m.close(err)
+		return nil, err
+	}
+	n.conn = m
+	return m, nil
+}
+
+// handshake proposes the supported versions and checks that the node
+// accepts one of them.
+func (n *NodeSocket) handshake(ctx context.Context, m *muxConn) error {
+	msg := append(cborHeader(cborMajorArray, 2), 0)
+	msg = append(msg, cborHeader(cborMajorMap, n2cVersionMax-n2cVersionMin+1)...)
+	for version := uint64(n2cVersionMin); version <= n2cVersionMax; version++ {
+		var data interface{} = n.magic
+		if version >= n2cVersionQueryData {
+			data = []interface{}{n.magic, false}
+		}
+		key, err := cbor.Marshal(version | n2cVersionFlag)
+		if err != nil {
+			return err
+		}
+		value, err := cbor.Marshal(data)
+		if err != nil {
+			return err
+		}
+		msg = append(msg, key...)
+		msg = append(msg, value...)
+	}
+
+	tag, fields, err := m.exchange(ctx, n2cHandshake, msg)
+	if err != nil {
+		return err
+	}
+	switch tag {
+	case 1:
+		return nil
+	case 2:
+		if len(fields) > 0 {
+			return fmt.Errorf("node socket: handshake refused: %x", []byte(fields[0]))
+		}
+		return fmt.Errorf("node socket: handshake refused")
+	default:
+		return fmt.Errorf("node socket: unexpected handshake message %v", tag)
+	}
+}
+
+// stateQuery runs queries against the ledger state acquired by withState.
+type stateQuery struct {
+	ctx    context.Context
+	m      *muxConn
+	era    uint64
+	broken bool
+}
+
+// withState acquires the ledger state at the tip of the node and releases it
+// once fn returns, so that every query of fn sees the same state.
+func (n *NodeSocket) withState(ctx context.Context, fn func(q *stateQuery) error) error {
+	n.queryMu.Lock()
+	defer n.queryMu.Unlock()
+
+	m, err := n.connection(ctx)
+	if err != nil {
+		return err
+	}
+	// MsgAcquire for the volatile tip
+	tag, fields, err := m.exchange(ctx, n2cLocalStateQuery, []byte{0x81, 0x08})
+	if err != nil {
+		m.close(err)
+		return err
+	}
+	switch tag {
+	case 1:
+	case 2:
+		var failure uint64
+		if len(fields) > 0 {
+			cbor.Unmarshal(fields[0], &failure)
+		}
+		return fmt.Errorf("node socket: failed to acquire the ledger state: %v", failure)
+	default:
+		err := fmt.Errorf("node socket: unexpected state query message %v", tag)
+		m.close(err)
+		return err
+	}
+
+	q := &stateQuery{ctx: ctx, m: m}
+	err = fn(q)
+	if q.broken {
+		return err
+	}
+	// MsgRelease
+	if sendErr := m.send(n2cLocalStateQuery, []byte{0x81, 0x05}); sendErr != nil {
+		m.close(sendErr)
+	}
+	return err
+}
+
+// query runs a query and decodes its result into out.
+func (q *stateQuery) query(query interface{}, out interface{}) error {
+	msg, err := cbor.Marshal([]interface{}{3, query})
+	if err != nil {
+		return err
+	}
+	tag, fields, err := q.m.exchange(q.ctx, n2cLocalStateQuery, msg)
+	if err != nil {
+		q.broken = true
+		q.m.close(err)
+		return err
+	}
+	if tag != 4 || len(fields) != 1 {
+		q.broken = true
+		err := fmt.Errorf("node socket: unexpected state query message %v", tag)
+		q.m.close(err)
+		return err
+	}
+	return cbor.Unmarshal(fields[0], out)
+}
+
+// currentEra returns the index of the era of the acquired ledger state.
+func (q *stateQuery) currentEra() (uint64, error) {
+	if q.era != 0 {
+		return q.era, nil
+	}
+	// BlockQuery (QueryHardFork GetCurrentEra)
+	if err := q.query([]interface{}{0, []interface{}{2, []interface{}{1}}}, &q.era); err != nil {
+		return 0, err
+	}
+	if q.era < eraShelley {
+		return 0, fmt.Errorf("node socket: unsupported era %v", q.era)
+	}
+	return q.era, nil
+}
+
+// eraQuery runs a query of the current era and decodes its result into out.
+func (q *stateQuery) eraQuery(query []interface{}, out interface{}) error {
+	era, err := q.currentEra()
+	if err != nil {
+		return err
+	}
+	// BlockQuery (QueryIfCurrent [era, query])
+	result := []cbor.RawMessage{}
+	if err := q.query([]interface{}{0, []interface{}{0, []interface{}{era, query}}}, &result); err != nil {
+		return err
+	}
+	// The result is [result], or an era mismatch if the era changed
+	if len(result) != 1 {
+		return fmt.Errorf("node socket: era mismatch")
+	}
+	return cbor.Unmarshal(result[0], out)
+}
+
+// QueryUtxos returns the unspent outputs locked by the address.
+func (n *NodeSocket) QueryUtxos(ctx context.Context, addr Address) ([]Utxo, error) {
+	utxos := []Utxo{}
+	err := n.withState(ctx, func(q *stateQuery) error {
+		result := cbor.RawMessage{}
+		if err := q.eraQuery([]interface{}{6, [][]byte{addr.Bytes()}}, &result); err != nil {
+			return err
+		}
+		return decodeCborMap(result, func(key, value cbor.RawMessage) error {
+			input := TransactionInput{}
+			if err := cbor.Unmarshal(key, &input); err != nil {
+				return err
+			}
+			output := TransactionOutput{}
+			if err := cbor.Unmarshal(value, &output); err != nil {
+				return err
+			}
+			utxos = append(utxos, Utxo{
+				Address: addr,
+				TxId:    TransactionID(hex.EncodeToString(input.ID)),
+				Index:   input.Index,
+				Amount:  output.Amount,
+				Assets:  output.Assets,
+			})
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return utxos, nil
+}
+
+// QueryTip returns the tip of the chain along with its block number and epoch.
+func (n *NodeSocket) QueryTip(ctx context.Context) (NodeTip, error) {
+	tip := NodeTip{}
+	err := n.withState(ctx, func(q *stateQuery) error {
+		// GetChainPoint, [] at the origin or [slot, hash]
+		point := []cbor.RawMessage{}
+		if err := q.query([]interface{}{3}, &point); err != nil {
+			return err
+		}
+		if len(point) > 0 {
+			if err := cbor.Unmarshal(point[0], &tip.Slot); err != nil {
+				return err
+			}
+		}
+		// GetChainBlockNo, [0] at the origin or [1, block]
+		block := []uint64{}
+		if err := q.query([]interface{}{2}, &block); err != nil {
+			return err
+		}
+		if len(block) > 1 {
+			tip.Block = block[1]
+		}
+		// GetEpochNo
+		return q.eraQuery([]interface{}{1}, &tip.Epoch)
+	})
+	if err != nil {
+		return NodeTip{}, err
+	}
+	return tip, nil
+}
+
+// QueryProtocolParams returns the current protocol parameters. Only the
+// Babbage and later eras are supported.
+func (n *NodeSocket) QueryProtocolParams(ctx context.Context) (ProtocolParams, error) {
+	var protocol ProtocolParams
+	err := n.withState(ctx, func(q *stateQuery) error {
+		era, err := q.currentEra()
+		if err != nil {
+			return err
+		}
+		if era < eraBabbage {
+			return fmt.Errorf("node socket: protocol parameters of era %v not supported", era)
+		}
+		// GetCurrentPParams
+		fields := []cbor.RawMessage{}
+		if err := q.eraQuery([]interface{}{3}, &fields); err != nil {
+			return err
+		}
+		protocol, err = decodeNodeProtocolParams(fields)
+		return err
+	})
+	return protocol, err
+}
+
+// decodeNodeProtocolParams decodes the protocol parameters array of the
+// Babbage and Conway eras. Babbage encodes the protocol version as two
+// fields and Conway as a single pair, shifting the fields that follow it.
+func decodeNodeProtocolParams(fields []cbor.RawMessage) (ProtocolParams, error) {
+	if len(fields) < 18 {
+		return ProtocolParams{}, fmt.Errorf("node socket: malformed protocol parameters")
+	}
+	offset := 0
+	if cborMajor(fields[12]) == 0 {
+		offset = 1
+	}
+
+	protocol := ProtocolParams{}
+	for _, field := range []struct {
+		index int
+		value *uint64
+	}{
+		{0, &protocol.MinFeeA},
+		{1, &protocol.MinFeeB},
+		{3, &protocol.MaxTxSize},
+		{5, &protocol.KeyDeposit},
+		{6, &protocol.PoolDeposit},
+		{14 + offset, &protocol.CoinsPerUTxOByte},
+	} {
+		if err := cbor.Unmarshal(fields[field.index], field.value); err != nil {
+			return ProtocolParams{}, err
+		}
+	}
+
+	prices := []cbor.RawTag{}
+	if err := cbor.Unmarshal(fields[16+offset], &prices); err != nil {
+		return ProtocolParams{}, err
+	}
+	if len(prices) != 2 {
+		return ProtocolParams{}, fmt.Errorf("node socket: malformed execution prices")
+	}
+	for i, price := range []*Rational{&protocol.PriceMem, &protocol.PriceStep} {
+		ratio := []uint64{}
+		if err := cbor.Unmarshal(prices[i].Content, &ratio); err != nil {
+			return ProtocolParams{}, err
+		}
+		if len(ratio) != 2 {
+			return ProtocolParams{}, fmt.Errorf("node socket: malformed execution prices")
+		}
+		*price = Rational{Num: ratio[0], Den: ratio[1]}
+	}
+
+	// Cost models are keyed by language id, PlutusV1 being 0
+	costModels := map[uint64][]int64{}
+	if err := cbor.Unmarshal(fields[15+offset], &costModels); err != nil {
+		return ProtocolParams{}, err
+	}
+	for language, costModel := range costModels {
+		if protocol.CostModels == nil {
+			protocol.CostModels = map[PlutusVersion][]int64{}
+		}
+		protocol.CostModels[PlutusVersion(language+1)] = costModel
+	}
+	return protocol, nil
+}
+
+// QueryTxStatus is not supported, the node can't look up transactions.
+func (n *NodeSocket) QueryTxStatus(ctx context.Context, txId TransactionID) (TxStatus, error) {
+	return TxStatus{}, ErrNotSupported
+}
+
+// QueryRewardAccount returns the rewards and delegation of the stake address.
+func (n *NodeSocket) QueryRewardAccount(ctx context.Context, stakeAddr Address) (RewardAccount, error) {
+	addrBytes := stakeAddr.Bytes()
+	if len(addrBytes) != 29 {
+		return RewardAccount{}, fmt.Errorf("invalid stake address %v", stakeAddr)
+	}
+	credential := []interface{}{uint64(addrBytes[0]>>4) & 1, addrBytes[1:]}
+
+	account := RewardAccount{Address: stakeAddr}
+	err := n.withState(ctx, func(q *stateQuery) error {
+		// GetFilteredDelegationsAndRewardAccounts, [delegations, rewards]
+		result := []cbor.RawMessage{}
+		if err := q.eraQuery([]interface{}{10, []interface{}{credential}}, &result); err != nil {
+			return err
+		}
+		if len(result) != 2 {
+			return fmt.Errorf("node socket: malformed reward accounts")
+		}
+		err := decodeCborMap(result[1], func(key, value cbor.RawMessage) error {
+			account.Registered = true
+			return cbor.Unmarshal(value, &account.Balance)
+		})
+		if err != nil {
+			return err
+		}
+		return decodeCborMap(result[0], func(key, value cbor.RawMessage) error {
+			var poolHash []byte
+			if err := cbor.Unmarshal(value, &poolHash); err != nil {
+				return err
+			}
+			poolID, err := bech32.EncodeFromBase256("pool", poolHash)
+			account.Delegation = poolID
+			return err
+		})
+	})
+	if err != nil {
+		return RewardAccount{}, err
+	}
+	return account, nil
+}
+
+// SubmitTx submits the transaction in the current era. Ledger rejections
+// are returned as typed errors when known, or as a TxRejectedError.
+func (n *NodeSocket) SubmitTx(ctx context.Context, tx Transaction) (TransactionID, error) {
+	var era uint64
+	err := n.withState(ctx, func(q *stateQuery) (err error) {
+		era, err = q.currentEra()
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	n.submitMu.Lock()
+	defer n.submitMu.Unlock()
+
+	m, err := n.connection(ctx)
+	if err != nil {
+		return "", err
+	}
+	msg, err := cbor.Marshal([]interface{}{0, []interface{}{era, cbor.Tag{Number: 24, Content: tx.Bytes()}}})
+	if err != nil {
+		return "", err
+	}
+	tag, fields, err := m.exchange(ctx, n2cLocalTxSubmission, msg)
+	if err != nil {
+		m.close(err)
+		return "", err
+	}
+	switch {
+	case tag == 1:
+		return tx.ID(), nil
+	case tag == 2 && len(fields) == 1:
+		return "", decodeTxRejection(fields[0])
+	default:
+		err := fmt.Errorf("node socket: unexpected tx submission message %v", tag)
+		m.close(err)
+		return "", err
+	}
+}
+
+// FindIntersect moves the chain-sync cursor to the most recent of the points
+// on the chain.
+func (n *NodeSocket) FindIntersect(ctx context.Context, points []ChainPoint) (ChainPoint, ChainPoint, bool, error) {
+	n.chainSyncMu.Lock()
+	defer n.chainSyncMu.Unlock()
+
+	m, err := n.connection(ctx)
+	if err != nil {
+		return ChainPoint{}, ChainPoint{}, false, err
+	}
+	encoded := make([]interface{}, len(points))
+	for i, point := range points {
+		encoded[i] = encodeChainPoint(point)
+	}
+	msg, err := cbor.Marshal([]interface{}{4, encoded})
+	if err != nil {
+		return ChainPoint{}, ChainPoint{}, false, err
+	}
+	tag, fields, err := m.exchange(ctx, n2cChainSync, msg)
+	if err != nil {
+		m.close(err)
+		return ChainPoint{}, ChainPoint{}, false, err
+	}
+
+	switch {
+	case tag == 5 && len(fields) == 2: // MsgIntersectFound
+		n.cursorConn = m
+		intersect, err := decodeChainPoint(fields[0])
+		if err != nil {
+			return ChainPoint{}, ChainPoint{}, false, err
+		}
+		tip, _, err := decodeChainTip(fields[1])
+		return intersect, tip, err == nil, err
+	case tag == 6 && len(fields) == 1: // MsgIntersectNotFound
+		tip, _, err := decodeChainTip(fields[0])
+		return ChainPoint{}, tip, false, err
+	default:
+		err := fmt.Errorf("node socket: unexpected chain sync message %v", tag)
+		m.close(err)
+		return ChainPoint{}, ChainPoint{}, false, err
+	}
+}
+
+// RequestNext returns the next chain-sync event, waiting for the next block
+// if the cursor is at the tip. The connection is closed if ctx is done while
+// waiting. It returns ErrCursorLost if the connection was dialed again since
+// the last FindIntersect, the node having reset the cursor to the origin.
+func (n *NodeSocket) RequestNext(ctx context.Context) (ChainEvent, error) {
+	n.chainSyncMu.Lock()
+	defer n.chainSyncMu.Unlock()
+
+	m, err := n.connection(ctx)
+	if err != nil {
+		return ChainEvent{}, err
+	}
+	if m != n.cursorConn {
+		return ChainEvent{}, ErrCursorLost
+	}
+	tag, fields, err := m.exchange(ctx, n2cChainSync, []byte{0x81, 0x00})
+	if err == nil && tag == 1 { // MsgAwaitReply
+		var reply cbor.RawMessage
+		if reply, err = m.recv(ctx, n2cChainSync); err == nil {
+			tag, fields = decodeTaggedArray(reply)
+		}
+	}
+	if err != nil {
+		m.close(err)
+		return ChainEvent{}, err
+	}
+
+	switch {
+	case tag == 2 && len(fields) == 2: // MsgRollForward
+		tip, tipBlock, err := decodeChainTip(fields[1])
+		if err != nil {
+			return ChainEvent{}, err
+		}
+		// The block is wrapped in cbor as [era, block]
+		var content []byte
+		if err := cbor.Unmarshal(cborUntag(fields[0]), &content); err != nil {
+			return ChainEvent{}, err
+		}
+		eraBlock := struct {
+			_     struct{} `cbor:",toarray"`
+			Era   uint64
+			Block cbor.RawMessage
+		}{}
+		if err := cbor.Unmarshal(content, &eraBlock); err != nil {
+			return ChainEvent{}, err
+		}
+		block, err := decodeBlock(eraBlock.Era, eraBlock.Block)
+		if err != nil {
+			return ChainEvent{}, err
+		}
+		return ChainEvent{Type: RollForward, Block: block, Point: block.Point, Tip: tip, TipBlock: tipBlock}, nil
+	case tag == 3 && len(fields) == 2: // MsgRollBackward
+		point, err := decodeChainPoint(fields[0])
+		if err != nil {
+			return ChainEvent{}, err
+		}
+		tip, tipBlock, err := decodeChainTip(fields[1])
+		if err != nil {
+			return ChainEvent{}, err
+		}
+		return ChainEvent{Type: RollBackward, Point: point, Tip: tip, TipBlock: tipBlock}, nil
+	default:
+		err := fmt.Errorf("node socket: unexpected chain sync message %v", tag)
+		m.close(err)
+		return ChainEvent{}, err
+	}
+}
+
+// encodeChainPoint encodes a point as [] for the origin or [slot, hash].
+func encodeChainPoint(point ChainPoint) []interface{} {
+	if point.IsOrigin() {
+		return []interface{}{}
+	}
+	return []interface{}{point.Slot, point.Hash}
+}
+
+func decodeChainPoint(data []byte) (ChainPoint, error) {
+	fields := []cbor.RawMessage{}
+	if err := cbor.Unmarshal(data, &fields); err != nil {
+		return ChainPoint{}, err
+	}
+	point := ChainPoint{}
+	if len(fields) == 0 {
+		return point, nil
+	}
+	if len(fields) != 2 {
+		return ChainPoint{}, fmt.Errorf("node socket: malformed point")
+	}
+	if err := cbor.Unmarshal(fields[0], &point.Slot); err != nil {
+		return ChainPoint{}, err
+	}
+	if err := cbor.Unmarshal(fields[1], &point.Hash); err != nil {
+		return ChainPoint{}, err
+	}
+	return point, nil
+}
+
+// decodeChainTip decodes a [point, block number] tip.
+func decodeChainTip(data []byte) (ChainPoint, uint64, error) {
+	fields := []cbor.RawMessage{}
+	if err := cbor.Unmarshal(data, &fields); err != nil {
+		return ChainPoint{}, 0, err
+	}
+	if len(fields) != 2 {
+		return ChainPoint{}, 0, fmt.Errorf("node socket: malformed tip")
+	}
+	var block uint64
+	if err := cbor.Unmarshal(fields[1], &block); err != nil {
+		return ChainPoint{}, 0, err
+	}
+	point, err := decodeChainPoint(fields[0])
+	return point, block, err
+}
+
+// decodeTxRejection maps the failures of a rejected Conway transaction to
+// typed errors. The reason is [[era, failures]], the era mismatch being
+// encoded otherwise.
+func decodeTxRejection(reason cbor.RawMessage) error {
+	wrapped := []cbor.RawMessage{}
+	if err := cbor.Unmarshal(reason, &wrapped); err != nil || len(wrapped) != 1 {
+		return &TxRejectedError{Reason: reason}
+	}
+	eraFailures := struct {
+		_        struct{} `cbor:",toarray"`
+		Era      uint64
+		Failures []cbor.RawMessage
+	}{}
+	if err := cbor.Unmarshal(wrapped[0], &eraFailures); err != nil || eraFailures.Era != eraConway || len(eraFailures.Failures) == 0 {
+		return &TxRejectedError{Reason: reason}
+	}
+
+	errs := ValidationErrors{}
+	for _, failure := range eraFailures.Failures {
+		err := decodeLedgerFailure(failure)
+		if err == nil {
+			err = &TxRejectedError{Reason: failure}
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	return errs
+}
+
+// decodeLedgerFailure returns the typed error of a Conway ledger failure, or
+// nil if it has none.
+func decodeLedgerFailure(failure cbor.RawMessage) error {
+	// ConwayUtxowFailure
+	tag, fields := decodeTaggedArray(failure)
+	if tag != 1 || len(fields) != 1 {
+		return nil
+	}
+	tag, fields = decodeTaggedArray(fields[0])
+	switch {
+	case tag == 0 && len(fields) == 1: // UtxoFailure
+	case tag == 1 && len(fields) == 1: // InvalidWitnessesUTXOW
+		vkeys := [][]byte{}
+		if err := cbor.Unmarshal(cborUntag(fields[0]), &vkeys); err != nil || len(vkeys) == 0 {
+			return nil
+		}
+		return &InvalidSignatureError{VKey: vkeys[0]}
+	case tag == 2 && len(fields) == 1: // MissingVKeyWitnessesUTXOW
+		missing := &MissingVKeyWitnessesError{}
+		if err := cbor.Unmarshal(cborUntag(fields[0]), &missing.KeyHashes); err != nil {
+			return nil
+		}
+		return missing
+	default:
+		return nil
+	}
+
+	tag, fields = decodeTaggedArray(fields[0])
+	switch {
+	case tag == 1 && len(fields) == 1: // BadInputsUTxO
+		badInputs := &BadInputsError{}
+		if err := cbor.Unmarshal(cborUntag(fields[0]), &badInputs.Inputs); err != nil {
+			return nil
+		}
+		return badInputs
+	case tag == 2 && len(fields) == 2: // OutsideValidityIntervalUTxO
+		interval := struct {
+			_             struct{} `cbor:",toarray"`
+			InvalidBefore []uint64
+			InvalidAfter  []uint64
+		}{}
+		outside := &OutsideValidityIntervalError{}
+		if err := cbor.Unmarshal(fields[0], &interval); err != nil {
+			return nil
+		}
+		if err := cbor.Unmarshal(fields[1], &outside.Slot); err != nil {
+			return nil
+		}
+		if len(interval.InvalidBefore) > 0 {
+			outside.ValidFrom = interval.InvalidBefore[0]
+		}
+		if len(interval.InvalidAfter) > 0 {
+			outside.TTL = interval.InvalidAfter[0]
+		}
+		return outside
+	case tag == 3 && len(fields) == 2: // MaxTxSizeUTxO
+		tooLarge := &MaxTxSizeExceededError{}
+		if cbor.Unmarshal(fields[0], &tooLarge.Size) != nil || cbor.Unmarshal(fields[1], &tooLarge.MaxSize) != nil {
+			return nil
+		}
+		return tooLarge
+	case tag == 5 && len(fields) == 2: // FeeTooSmallUTxO
+		feeTooSmall := &FeeTooSmallError{}
+		if cbor.Unmarshal(fields[0], &feeTooSmall.MinFee) != nil || cbor.Unmarshal(fields[1], &feeTooSmall.Fee) != nil {
+			return nil
+		}
+		return feeTooSmall
+	case tag == 6 && len(fields) == 2: // ValueNotConservedUTxO
+		notConserved := &ValueNotConservedError{}
+		if cbor.Unmarshal(fields[0], &notConserved.Consumed) != nil || cbor.Unmarshal(fields[1], &notConserved.Produced) != nil {
+			return nil
+		}
+		return notConserved
+	case tag == 9 && len(fields) == 1: // OutputTooSmallUTxO
+		outputs := []TransactionOutput{}
+		if err := cbor.Unmarshal(fields[0], &outputs); err != nil || len(outputs) == 0 {
+			return nil
+		}
+		return &OutputTooSmallError{Index: -1, Amount: outputs[0].Amount}
+	}
+	return nil
+}
+
+// decodeTaggedArray decodes a [tag, fields...] array. It returns a tag that
+// matches nothing if data isn't one.
+func decodeTaggedArray(data []byte) (uint64, []cbor.RawMessage) {
+	items := []cbor.RawMessage{}
+	if err := cbor.Unmarshal(data, &items); err != nil || len(items) == 0 {
+		return ^uint64(0), nil
+	}
+	var tag uint64
+	if err := cbor.Unmarshal(items[0], &tag); err != nil {
+		return ^uint64(0), nil
+	}
+	return tag, items[1:]
+}
+
+// muxConn multiplexes the mini protocols over a connection. Messages are
+// split into segments prefixed by a header holding a timestamp, the mini
+// protocol number and the payload length.
+type muxConn struct {
+	conn      net.Conn
+	start     time.Time
+	writeMu   sync.Mutex
+	protocols map[uint16]*muxProtocol
+
+	closeOnce sync.Once
+	done      chan struct{}
+	err       error
+}
+
+// muxProtocol holds the segments received for a mini protocol. buf is only
+// used by the goroutine running the mini protocol.
+type muxProtocol struct {
+	segments chan []byte
+	buf      []byte
+}
+
+func newMuxConn(conn net.Conn, protocols ...uint16) *muxConn {
+	m := &muxConn{
+		conn:      conn,
+		start:     time.Now(),
+		protocols: map[uint16]*muxProtocol{},
+		done:      make(chan struct{}),
+	}
+	for _, protocol := range protocols {
+		m.protocols[protocol] = &muxProtocol{segments: make(chan []byte, 16)}
+	}
+	go m.readLoop()
+	return m
+}
+
+func (m *muxConn) close(err error) {
+	m.closeOnce.Do(func() {
+		m.err = err
+		close(m.done)
+		m.conn.Close()
+	})
+}
+
+// readLoop dispatches the received segments to their mini protocol until
+// the connection drops.
+func (m *muxConn) readLoop() {
+	header := make([]byte, muxHeaderSize)
+	for {
+		if _, err := io.ReadFull(m.conn, header); err != nil {
+			m.close(err)
+			return
+		}
+		payload := make([]byte, binary.BigEndian.Uint16(header[6:]))
+		if _, err := io.ReadFull(m.conn, payload); err != nil {
+			m.close(err)
+			return
+		}
+
+		protocolNum := binary.BigEndian.Uint16(header[4:]) &^ muxResponderFlag
+		protocol, ok := m.protocols[protocolNum]
+		if !ok {
+			m.close(fmt.Errorf("node socket: unexpected mini protocol %v", protocolNum))
+			return
+		}
+		select {
+		case protocol.segments <- payload:
+		case <-m.done:
+			return
+		}
+	}
+}
+
+// send writes the message to the mini protocol, split in segments.
+func (m *muxConn) send(protocol uint16, msg []byte) error {
+	m.writeMu.Lock()
+	defer m.writeMu.Unlock()
+
+	for len(msg) > 0 {
+		n := len(msg)
+		if n > muxMaxPayload {
+			n = muxMaxPayload
+		}
+		segment := make([]byte, muxHeaderSize+n)
+		binary.BigEndian.PutUint32(segment, uint32(time.Since(m.start).Microseconds()))
+		binary.BigEndian.PutUint16(segment[4:], protocol)
+		binary.BigEndian.PutUint16(segment[6:], uint16(n))
+		copy(segment[muxHeaderSize:], msg[:n])
+		if _, err := m.conn.Write(segment); err != nil {
+			return err
+		}
+		msg = msg[n:]
+	}
+	return nil
+}
+
+// recv returns the next message of the mini protocol, reassembled from as
+// many segments as needed.
+func (m *muxConn) recv(ctx context.Context, protocolNum uint16) (cbor.RawMessage, error) {
+	protocol := m.protocols[protocolNum]
+	for {
+		if len(protocol.buf) > 0 {
+			dec := cbor.NewDecoder(bytes.NewReader(protocol.buf))
+			msg := cbor.RawMessage{}
+			err := dec.Decode(&msg)
+			if err == nil {
+				protocol.buf = protocol.buf[dec.NumBytesRead():]
+				return msg, nil
+			}
+			if err != io.EOF && err != io.ErrUnexpectedEOF {
+				return nil, err
+			}
+		}
+
+		select {
+		case segment := <-protocol.segments:
+			protocol.buf = append(protocol.buf, segment...)
+		case <-m.done:
+			return nil, m.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// exchange sends a message and returns the tag and fields of the reply.
+func (m *muxConn) exchange(ctx context.Context, protocol uint16, msg []byte) (uint64, []cbor.RawMessage, error) {
+	if err := m.send(protocol, msg); err != nil {
+		return 0, nil, err
+	}
+	reply, err := m.recv(ctx, protocol)
+	if err != nil {
+		return 0, nil, err
+	}
+	tag, fields := decodeTaggedArray(reply)
+	if tag == ^uint64(0) {
+		return 0, nil, fmt.Errorf("node socket: malformed message %x", []byte(reply))
+	}
+	return tag, fields, nil
+}
@@ -0,0 +1,311 @@
+package cardano
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// nodeSocketExchange is a recorded node-to-client exchange: the cbor hex
+// message sent by the client on a mini protocol and the node's reply, if any.
+type nodeSocketExchange struct {
+	protocol uint16
+	request  string
+	reply    string
+}
+
+const (
+	nodeSocketHandshake = "8200a8198009" + "1a4170cb17" + "19800a1a4170cb17" + "19800b1a4170cb17" + "19800c1a4170cb17" +
+		"19800d1a4170cb17" + "19800e1a4170cb17" + "19800f821a4170cb17f4" + "198010821a4170cb17f4"
+	nodeSocketAcquire = "8108"
+	nodeSocketRelease = "8105"
+	nodeSocketEra     = "8203820082028101"
+)
+
+var nodeSocketSession = []nodeSocketExchange{
+	{n2cHandshake, nodeSocketHandshake, "8301198010821a4170cb17f4"},
+	{n2cLocalStateQuery, nodeSocketAcquire, "8101"},
+	{n2cLocalStateQuery, nodeSocketEra, "820406"},
+}
+
+// newNodeSocketTestServer replays the exchanges on a Unix socket, splitting
+// each reply in two segments.
+func newNodeSocketTestServer(t *testing.T, exchanges []nodeSocketExchange) *NodeSocket {
+	socketPath := filepath.Join(t.TempDir(), "node.socket")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		buf := []byte{}
+		header := make([]byte, muxHeaderSize)
+		for _, exchange := range exchanges {
+			var msg cbor.RawMessage
+			for {
+				dec := cbor.NewDecoder(bytes.NewReader(buf))
+				if err := dec.Decode(&msg); err == nil {
+					buf = buf[dec.NumBytesRead():]
+					break
+				}
+				if _, err := io.ReadFull(conn, header); err != nil {
+					return
+				}
+				if got, want := binary.BigEndian.Uint16(header[4:]), exchange.protocol; got != want {
+					t.Errorf("got mini protocol %v want %v", got, want)
+				}
+				payload := make([]byte, binary.BigEndian.Uint16(header[6:]))
+				if _, err := io.ReadFull(conn, payload); err != nil {
+					return
+				}
+				buf = append(buf, payload...)
+			}
+			if got, want := hex.EncodeToString(msg), exchange.request; got != want {
+				t.Errorf("got message %v want %v", got, want)
+				return
+			}
+
+			reply, _ := hex.DecodeString(exchange.reply)
+			for _, segment := range [][]byte{reply[:len(reply)/2], reply[len(reply)/2:]} {
+				if len(segment) == 0 {
+					continue
+				}
+				binary.BigEndian.PutUint16(header[4:], exchange.protocol|muxResponderFlag)
+				binary.BigEndian.PutUint16(header[6:], uint16(len(segment)))
+				conn.Write(append(header, segment...))
+			}
+		}
+		// Wait for the client to hang up
+		io.Copy(io.Discard, conn)
+	}()
+
+	node := NewNodeSocket(socketPath, testnetMagic)
+	t.Cleanup(func() { node.Close() })
+	return node
+}
+
+func TestNodeSocketQueryUtxos(t *testing.T) {
+	node := newNodeSocketTestServer(t, append(nodeSocketSession,
+		nodeSocketExchange{
+			n2cLocalStateQuery,
+			"8203820082008206820681581d601126bd6f500a447f12737076dd6558b48b242006fd96429880edfedb",
+			"820481a2825820000000000000000000000000000000000000000000000000000000000000000100a200581d601126bd6f500a447f12737076dd6558b48b242006fd96429880edfedb011a001e8480825820000000000000000000000000000000000000000000000000000000000000000201" +
+				"82581d601126bd6f500a447f12737076dd6558b48b242006fd96429880edfedb821a0016e360a1581c1d7f33bd23d85e1a25d87d86fac4f199c3197a2f7afeb662a0f34e1ea145776f726c640c",
+		},
+		nodeSocketExchange{protocol: n2cLocalStateQuery, request: nodeSocketRelease},
+	))
+
+	utxos, err := node.QueryUtxos(context.Background(), koiosTestAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(utxos), 2; got != want {
+		t.Fatalf("got %v utxos want %v", got, want)
+	}
+	if got, want := utxos[0], (Utxo{Address: koiosTestAddr, TxId: TransactionID(fmt.Sprintf("%064x", 1)), Amount: 2000000}); got.Address != want.Address || got.TxId != want.TxId || got.Amount != want.Amount || got.Index != want.Index {
+		t.Errorf("got utxo %+v want %+v", got, want)
+	}
+	if got, want := utxos[1].Index, uint64(1); got != want {
+		t.Errorf("got index %v want %v", got, want)
+	}
+	if got, want := utxos[1].Assets.Quantity("1d7f33bd23d85e1a25d87d86fac4f199c3197a2f7afeb662a0f34e1e", "776f726c64"), uint64(12); got != want {
+		t.Errorf("got asset quantity %v want %v", got, want)
+	}
+}
+
+func TestNodeSocketQueryProtocolParams(t *testing.T) {
+	node := newNodeSocketTestServer(t, append(nodeSocketSession,
+		nodeSocketExchange{
+			n2cLocalStateQuery,
+			"82038200820082068103",
+			"820481981f182c1a00025ef51a0001600019400019044c1a001e84801a1dcd6500121901f4d81e82030ad81e82031903e8d81e8201058209001a0a21fe801910d6a10183010220" +
+				"82d81e82190241192710d81e821902d11a00989680821a00d59f801b00000002540be400821a03b20b801b00000004a817c80019138818960385d81e8218331864" +
+				"d81e8218331864d81e8218331864d81e8218331864d81e82183318648ad81e8218431864d81e8218431864d81e820305d81e820304d81e820305d81e8218431864" +
+				"d81e8218431864d81e8218431864d81e820304d81e8218431864071892061b000000174876e8001a1dcd650014d81e820f01",
+		},
+		nodeSocketExchange{protocol: n2cLocalStateQuery, request: nodeSocketRelease},
+	))
+
+	protocol, err := node.QueryProtocolParams(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ProtocolParams{
+		MinFeeA:          44,
+		MinFeeB:          155381,
+		MaxTxSize:        16384,
+		KeyDeposit:       2000000,
+		PoolDeposit:      500000000,
+		CoinsPerUTxOByte: 4310,
+		PriceMem:         Rational{Num: 577, Den: 10000},
+		PriceStep:        Rational{Num: 721, Den: 10000000},
+		CostModels:       map[PlutusVersion][]int64{PlutusV2: {1, 2, -1}},
+	}
+	if !reflect.DeepEqual(protocol, want) {
+		t.Errorf("got %+v want %+v", protocol, want)
+	}
+}
+
+func TestNodeSocketQueryRewardAccount(t *testing.T) {
+	node := newNodeSocketTestServer(t, append(nodeSocketSession,
+		nodeSocketExchange{
+			n2cLocalStateQuery,
+			"8203820082008206820a818200581c13cf55d175ea848b87deb3e914febd7e028e2bf6534475d52fb9c3d0",
+			"82048182a18200581c13cf55d175ea848b87deb3e914febd7e028e2bf6534475d52fb9c3d0581c0f000000000000000000000000000000000000000000000000000000" +
+				"a18200581c13cf55d175ea848b87deb3e914febd7e028e2bf6534475d52fb9c3d0197a69",
+		},
+		nodeSocketExchange{protocol: n2cLocalStateQuery, request: nodeSocketRelease},
+	))
+
+	account, err := node.QueryRewardAccount(context.Background(), "stake_test1uqfu74w3wh4gfzu8m6e7j987h4lq9r3t7ef5gaw497uu85qsqfy27")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !account.Registered || account.Balance != 31337 || account.Delegation != "pool1puqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqh9ru9t" {
+		t.Errorf("got account %+v", account)
+	}
+}
+
+func TestNodeSocketSubmitTx(t *testing.T) {
+	tx := Transaction{Body: TransactionBody{Inputs: []TransactionInput{{ID: make([]byte, 32)}}, Fee: 170000, Ttl: 100}}
+	submit := "82008206d818583583a4008182582000000000000000000000000000000000000000000000000000000000000000000001f6021a00029810031864a0f6"
+	node := newNodeSocketTestServer(t, append(nodeSocketSession,
+		nodeSocketExchange{protocol: n2cLocalStateQuery, request: nodeSocketRelease},
+		nodeSocketExchange{n2cLocalTxSubmission, submit, "8101"},
+		nodeSocketExchange{n2cLocalStateQuery, nodeSocketAcquire, "8101"},
+		nodeSocketExchange{n2cLocalStateQuery, nodeSocketEra, "820406"},
+		nodeSocketExchange{protocol: n2cLocalStateQuery, request: nodeSocketRelease},
+		// FeeTooSmallUTxO, BadInputsUTxO and an unknown failure
+		nodeSocketExchange{
+			n2cLocalTxSubmission,
+			submit,
+			"8202818206838201820083051a00030d401a00029810820182008201d9010281825820000000000000000000000000000000000000000000000000000000000000000000" +
+				"82038100",
+		},
+	))
+
+	txId, err := node.SubmitTx(context.Background(), tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := txId, tx.ID(); got != want {
+		t.Errorf("got tx id %v want %v", got, want)
+	}
+
+	_, err = node.SubmitTx(context.Background(), tx)
+	var feeErr *FeeTooSmallError
+	if !errors.As(err, &feeErr) || feeErr.Fee != 170000 || feeErr.MinFee != 200000 {
+		t.Errorf("got error %v want fee too small", err)
+	}
+	var badInputsErr *BadInputsError
+	if !errors.As(err, &badInputsErr) || len(badInputsErr.Inputs) != 1 {
+		t.Errorf("got error %v want bad inputs", err)
+	}
+	var rejectedErr *TxRejectedError
+	if !errors.As(err, &rejectedErr) || hex.EncodeToString(rejectedErr.Reason) != "82038100" {
+		t.Errorf("got error %v want rejected", err)
+	}
+}
+
+func TestNodeSocketChainSync(t *testing.T) {
+	node := newNodeSocketTestServer(t, []nodeSocketExchange{
+		nodeSocketSession[0],
+		{
+			n2cChainSync,
+			"820481821903e858200100000000000000000000000000000000000000000000000000000000000000",
+			"8305821903e85820010000000000000000000000000000000000000000000000000000000000000082821907d0582002000000000000000000000000000000000000000000000000000000000000001832",
+		},
+		// MsgAwaitReply then MsgRollForward
+		{
+			n2cChainSync,
+			"8100",
+			"81018302d81858ca8206858283182b1903fc5820010000000000000000000000000000000000000000000000000000000000000058400000000000000000000000000000" +
+				"000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000081a400818258200000000000000000000000" +
+				"00000000000000000000000000000000000000000000018182581d00000000000000000000000000000000000000000000000000000000001a000f4240021a0002981003" +
+				"186481a0a08082821907d0582002000000000000000000000000000000000000000000000000000000000000001832",
+		},
+		{
+			n2cChainSync,
+			"8100",
+			"8303821903e85820010000000000000000000000000000000000000000000000000000000000000082821907d0582002000000000000000000000000000000000000000000000000000000000000001832",
+		},
+	})
+
+	hash := make([]byte, 32)
+	hash[0] = 1
+	intersect, tip, found, err := node.FindIntersect(context.Background(), []ChainPoint{{Slot: 1000, Hash: hash}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found || intersect.Slot != 1000 || tip.Slot != 2000 {
+		t.Errorf("got intersect %v tip %v found %v", intersect, tip, found)
+	}
+
+	event, err := node.RequestNext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Type != RollForward || event.Block.Number != 43 || event.Point.Slot != 1020 || event.TipBlock != 50 {
+		t.Errorf("got event %+v want roll forward to block 43", event)
+	}
+	if got, want := event.Block.TxIDs, []TransactionID{"2539e6ee4a1a888d24c68b24d98b9b91cd1c2801080645acb3c479bb6f87fd78"}; len(got) != 1 || got[0] != want[0] {
+		t.Errorf("got tx ids %v want %v", got, want)
+	}
+
+	event, err = node.RequestNext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Type != RollBackward || !event.Point.Equal(intersect) {
+		t.Errorf("got event %+v want roll backward to %v", event, intersect)
+	}
+}
+
+func TestNodeSocketChainSyncCursorLost(t *testing.T) {
+	// A connection dialed by a query holds no chain-sync cursor
+	node := newNodeSocketTestServer(t, nodeSocketSession[:1])
+	if _, err := node.RequestNext(context.Background()); !errors.Is(err, ErrCursorLost) {
+		t.Errorf("got error %v want %v", err, ErrCursorLost)
+	}
+}
+
+func TestWithSocket(t *testing.T) {
+	tests := []struct {
+		name  string
+		opt   Options
+		magic uint64
+	}{
+		{name: "testnet", opt: WithSocket("node.socket"), magic: testnetMagic},
+		{name: "preview", opt: WithNetworkSocket("node.socket", PreviewMagic), magic: PreviewMagic},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient(WithDB(&MockDB{}), tt.opt)
+			defer client.Close()
+			node, ok := client.node.(*NodeSocket)
+			if !ok {
+				t.Fatalf("got backend %T want *NodeSocket", client.node)
+			}
+			if got, want := node.magic, tt.magic; got != want {
+				t.Errorf("got magic %v want %v", got, want)
+			}
+		})
+	}
+}
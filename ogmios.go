@@ -0,0 +1,554 @@
+package cardano
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// errOgmiosDisconnected is returned for the requests still waiting for a
+// response when the connection drops.
+var errOgmiosDisconnected = errors.New("ogmios: connection closed")
+
+// OgmiosError is a JSON-RPC error returned by Ogmios.
+type OgmiosError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+func (e *OgmiosError) Error() string {
+	return fmt.Sprintf("ogmios: %v %v", e.Code, e.Message)
+}
+
+// Ogmios is a ChainBackend and an Evaluator speaking the Ogmios v6 JSON-RPC
+// protocol. Requests are multiplexed over a single WebSocket connection,
+// which is dialed again after it drops.
+type Ogmios struct {
+	url    string
+	mu     sync.Mutex
+	conn   *ogmiosConn
+	nextID uint64
+}
+
+// NewOgmios creates an Ogmios backend for the given WebSocket url, like
+// ws://localhost:1337. The connection is opened by the first request.
+func NewOgmios(url string) *Ogmios {
+	return &Ogmios{url: url}
+}
+
+// Close closes the connection to Ogmios.
+func (o *Ogmios) Close() error {
+	o.mu.Lock()
+	conn := o.conn
+	o.conn = nil
+	o.mu.Unlock()
+
+	if conn == nil {
+		return nil
+	}
+	return conn.ws.Close()
+}
+
+type ogmiosRequest struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+	ID      uint64      `json:"id"`
+}
+
+type ogmiosResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *OgmiosError    `json:"error"`
+	ID     *uint64         `json:"id"`
+}
+
+type ogmiosConn struct {
+	ws      *websocket.Conn
+	mu      sync.Mutex
+	pending map[uint64]chan ogmiosResponse
+	closed  bool
+}
+
+func (o *Ogmios) connection(ctx context.Context) (*ogmiosConn, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.conn != nil {
+		return o.conn, nil
+	}
+	ws, err := dialWebsocket(ctx, o.url)
+	if err != nil {
+		return nil, err
+	}
+	conn := &ogmiosConn{ws: ws, pending: map[uint64]chan ogmiosResponse{}}
+	o.conn = conn
+	go o.readLoop(conn)
+	return conn, nil
+}
+
+// readLoop dispatches the responses of the connection to the waiting
+// requests until the connection drops.
+func (o *Ogmios) readLoop(conn *ogmiosConn) {
+	for {
+		resp := ogmiosResponse{}
+		if err := websocket.JSON.Receive(conn.ws, &resp); err != nil {
+			break
+		}
+		if resp.ID == nil {
+			continue
+		}
+		conn.mu.Lock()
+		ch, ok := conn.pending[*resp.ID]
+		delete(conn.pending, *resp.ID)
+		conn.mu.Unlock()
+		if ok {
+			ch <- resp
+		}
+	}
+
+	o.mu.Lock()
+	if o.conn == conn {
+		o.conn = nil
+	}
+	o.mu.Unlock()
+
+	conn.ws.Close()
+	conn.mu.Lock()
+	conn.closed = true
+	for id, ch := range conn.pending {
+		close(ch)
+		delete(conn.pending, id)
+	}
+	conn.mu.Unlock()
+}
+
+// call sends a request and decodes its result into out. Queries are sent
+// again on a new connection if the connection drops before the response.
+func (o *Ogmios) call(ctx context.Context, method string, params, out interface{}) error {
+	retry := strings.HasPrefix(method, "query")
+	for {
+		err := o.callOnce(ctx, method, params, out)
+		if err == errOgmiosDisconnected && retry {
+			retry = false
+			continue
+		}
+		return err
+	}
+}
+
+func (o *Ogmios) callOnce(ctx context.Context, method string, params, out interface{}) error {
+	conn, err := o.connection(ctx)
+	if err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+	o.nextID++
+	id := o.nextID
+	o.mu.Unlock()
+
+	ch := make(chan ogmiosResponse, 1)
+	conn.mu.Lock()
+	if conn.closed {
+		conn.mu.Unlock()
+		return errOgmiosDisconnected
+	}
+	conn.pending[id] = ch
+	err = websocket.JSON.Send(conn.ws, ogmiosRequest{JSONRPC: "2.0", Method: method, Params: params, ID: id})
+	conn.mu.Unlock()
+	if err != nil {
+		conn.ws.Close()
+		return errOgmiosDisconnected
+	}
+
+	select {
+	case <-ctx.Done():
+		conn.mu.Lock()
+		delete(conn.pending, id)
+		conn.mu.Unlock()
+		return ctx.Err()
+	case resp, ok := <-ch:
+		if !ok {
+			return errOgmiosDisconnected
+		}
+		if resp.Error != nil {
+			return resp.Error
+		}
+		return json.Unmarshal(resp.Result, out)
+	}
+}
+
+func dialWebsocket(ctx context.Context, rawurl string) (*websocket.Conn, error) {
+	config, err := websocket.NewConfig(rawurl, "http://localhost/")
+	if err != nil {
+		return nil, err
+	}
+	host := config.Location.Host
+	if config.Location.Port() == "" {
+		port := "80"
+		if config.Location.Scheme == "wss" {
+			port = "443"
+		}
+		host = net.JoinHostPort(config.Location.Hostname(), port)
+	}
+
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, err
+	}
+	if config.Location.Scheme == "wss" {
+		conn = tls.Client(conn, &tls.Config{ServerName: config.Location.Hostname()})
+	}
+
+	// Bound the handshake by the context deadline
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	ws, err := websocket.NewClient(config, conn)
+	if err != nil {
+		conn.Close()
+		return nil, &url.Error{Op: "dial", URL: rawurl, Err: err}
+	}
+	conn.SetDeadline(time.Time{})
+	return ws, nil
+}
+
+type ogmiosValue map[string]map[string]uint64
+
+func (v ogmiosValue) toValue() Value {
+	value := Value{Coin: v["ada"]["lovelace"]}
+	for policyID, assets := range v {
+		if policyID == "ada" {
+			continue
+		}
+		for assetName, quantity := range assets {
+			value.Assets = value.Assets.Add(NewMultiAsset(policyID, assetName, quantity))
+		}
+	}
+	return value
+}
+
+type ogmiosLovelace struct {
+	Ada struct {
+		Lovelace uint64 `json:"lovelace"`
+	} `json:"ada"`
+}
+
+type ogmiosUtxo struct {
+	Transaction struct {
+		ID string `json:"id"`
+	} `json:"transaction"`
+	Index   uint64      `json:"index"`
+	Address string      `json:"address"`
+	Value   ogmiosValue `json:"value"`
+}
+
+type ogmiosTip struct {
+	Slot uint64 `json:"slot"`
+	ID   string `json:"id"`
+}
+
+type ogmiosProtocolParams struct {
+	MinFeeCoefficient  uint64         `json:"minFeeCoefficient"`
+	MinFeeConstant     ogmiosLovelace `json:"minFeeConstant"`
+	MaxTransactionSize struct {
+		Bytes uint64 `json:"bytes"`
+	} `json:"maxTransactionSize"`
+	StakeCredentialDeposit    ogmiosLovelace `json:"stakeCredentialDeposit"`
+	StakePoolDeposit          ogmiosLovelace `json:"stakePoolDeposit"`
+	MinUtxoDepositCoefficient uint64         `json:"minUtxoDepositCoefficient"`
+	ScriptExecutionPrices     struct {
+		Memory string `json:"memory"`
+		CPU    string `json:"cpu"`
+	} `json:"scriptExecutionPrices"`
+	PlutusCostModels map[string]json.RawMessage `json:"plutusCostModels"`
+}
+
+type ogmiosRewardAccountSummary struct {
+	Delegate struct {
+		ID string `json:"id"`
+	} `json:"delegate"`
+	Rewards ogmiosLovelace `json:"rewards"`
+}
+
+type ogmiosEvaluation struct {
+	Validator struct {
+		Purpose string `json:"purpose"`
+		Index   uint64 `json:"index"`
+	} `json:"validator"`
+	Budget struct {
+		Memory uint64 `json:"memory"`
+		CPU    uint64 `json:"cpu"`
+	} `json:"budget"`
+}
+
+type ogmiosTransaction struct {
+	Transaction struct {
+		CBOR string `json:"cbor,omitempty"`
+		ID   string `json:"id,omitempty"`
+	} `json:"transaction"`
+}
+
+var ogmiosPurposes = map[string]RedeemerTag{
+	"spend":    RedeemerTagSpend,
+	"mint":     RedeemerTagMint,
+	"publish":  RedeemerTagCert,
+	"withdraw": RedeemerTagReward,
+}
+
+// QueryUtxos returns the unspent outputs locked by the address.
+func (o *Ogmios) QueryUtxos(ctx context.Context, addr Address) ([]Utxo, error) {
+	ogmiosUtxos := []ogmiosUtxo{}
+	params := map[string]interface{}{"addresses": []Address{addr}}
+	if err := o.call(ctx, "queryLedgerState/utxo", params, &ogmiosUtxos); err != nil {
+		return nil, err
+	}
+
+	utxos := make([]Utxo, len(ogmiosUtxos))
+	for i, ogmiosUtxo := range ogmiosUtxos {
+		value := ogmiosUtxo.Value.toValue()
+		utxos[i] = Utxo{
+			Address: Address(ogmiosUtxo.Address),
+			TxId:    TransactionID(ogmiosUtxo.Transaction.ID),
+			Index:   ogmiosUtxo.Index,
+			Amount:  value.Coin,
+			Assets:  value.Assets,
+		}
+	}
+	return utxos, nil
+}
+
+// QueryTip returns the tip of the chain along with its block height and epoch.
+func (o *Ogmios) QueryTip(ctx context.Context) (NodeTip, error) {
+	tip := ogmiosTip{}
+	if err := o.call(ctx, "queryNetwork/tip", nil, &tip); err != nil {
+		return NodeTip{}, err
+	}
+	var block, epoch uint64
+	if err := o.call(ctx, "queryNetwork/blockHeight", nil, &block); err != nil {
+		return NodeTip{}, err
+	}
+	if err := o.call(ctx, "queryLedgerState/epoch", nil, &epoch); err != nil {
+		return NodeTip{}, err
+	}
+	return NodeTip{Epoch: epoch, Block: block, Slot: tip.Slot}, nil
+}
+
+// QueryProtocolParams returns the current protocol parameters.
+func (o *Ogmios) QueryProtocolParams(ctx context.Context) (ProtocolParams, error) {
+	params := ogmiosProtocolParams{}
+	if err := o.call(ctx, "queryLedgerState/protocolParameters", nil, &params); err != nil {
+		return ProtocolParams{}, err
+	}
+
+	priceMem, err := parseRational(params.ScriptExecutionPrices.Memory)
+	if err != nil {
+		return ProtocolParams{}, err
+	}
+	priceStep, err := parseRational(params.ScriptExecutionPrices.CPU)
+	if err != nil {
+		return ProtocolParams{}, err
+	}
+	return ProtocolParams{
+		PoolDeposit:      params.StakePoolDeposit.Ada.Lovelace,
+		KeyDeposit:       params.StakeCredentialDeposit.Ada.Lovelace,
+		MinFeeA:          params.MinFeeCoefficient,
+		MinFeeB:          params.MinFeeConstant.Ada.Lovelace,
+		MaxTxSize:        params.MaxTransactionSize.Bytes,
+		CoinsPerUTxOByte: params.MinUtxoDepositCoefficient,
+		PriceMem:         priceMem,
+		PriceStep:        priceStep,
+		CostModels:       parseCostModels(params.PlutusCostModels),
+	}, nil
+}
+
+// QueryTxStatus is not supported, Ogmios can't look up transactions.
+func (o *Ogmios) QueryTxStatus(ctx context.Context, txId TransactionID) (TxStatus, error) {
+	return TxStatus{}, ErrNotSupported
+}
+
+// QueryRewardAccount returns the rewards and delegation of the stake address.
+func (o *Ogmios) QueryRewardAccount(ctx context.Context, stakeAddr Address) (RewardAccount, error) {
+	summaries := map[string]ogmiosRewardAccountSummary{}
+	params := map[string]interface{}{"keys": []Address{stakeAddr}}
+	if err := o.call(ctx, "queryLedgerState/rewardAccountSummaries", params, &summaries); err != nil {
+		return RewardAccount{}, err
+	}
+
+	account := RewardAccount{Address: stakeAddr}
+	summary, ok := summaries[stakeCredentialKey(stakeAddr.Bytes())]
+	if !ok {
+		return account, nil
+	}
+	account.Registered = true
+	account.Balance = summary.Rewards.Ada.Lovelace
+	account.Delegation = summary.Delegate.ID
+	return account, nil
+}
+
+// SubmitTx submits the transaction. Ledger rejections are returned as typed
+// errors when known, or as an OgmiosError.
+func (o *Ogmios) SubmitTx(ctx context.Context, tx Transaction) (TransactionID, error) {
+	params := ogmiosTransaction{}
+	params.Transaction.CBOR = tx.CborHex()
+	result := ogmiosTransaction{}
+	err := o.call(ctx, "submitTransaction", params, &result)
+	if ogmiosErr, ok := err.(*OgmiosError); ok {
+		return "", ogmiosSubmitError(ogmiosErr)
+	}
+	if err != nil {
+		return "", err
+	}
+	return TransactionID(result.Transaction.ID), nil
+}
+
+// EvaluateTx returns the execution units spent by each redeemer of the
+// transaction.
+func (o *Ogmios) EvaluateTx(ctx context.Context, tx *Transaction) (map[RedeemerKey]ExUnits, error) {
+	params := ogmiosTransaction{}
+	params.Transaction.CBOR = tx.CborHex()
+	evaluations := []ogmiosEvaluation{}
+	if err := o.call(ctx, "evaluateTransaction", params, &evaluations); err != nil {
+		return nil, err
+	}
+
+	units := map[RedeemerKey]ExUnits{}
+	for _, evaluation := range evaluations {
+		tag, ok := ogmiosPurposes[evaluation.Validator.Purpose]
+		if !ok {
+			return nil, fmt.Errorf("ogmios: unknown validator purpose %q", evaluation.Validator.Purpose)
+		}
+		key := RedeemerKey{Tag: tag, Index: evaluation.Validator.Index}
+		units[key] = ExUnits{Mem: evaluation.Budget.Memory, Steps: evaluation.Budget.CPU}
+	}
+	return units, nil
+}
+
+// Ogmios submitTransaction error codes.
+const (
+	ogmiosInvalidSignatories         = 3100
+	ogmiosMissingSignatories         = 3101
+	ogmiosUnknownOutputReferences    = 3117
+	ogmiosOutsideOfValidityInterval  = 3118
+	ogmiosTransactionTooLarge        = 3119
+	ogmiosTransactionFeeTooSmall     = 3122
+	ogmiosValueNotConserved          = 3123
+	ogmiosInsufficientlyFundedOutput = 3125
+)
+
+// ogmiosSubmitError maps the known submission failures to typed errors.
+func ogmiosSubmitError(ogmiosErr *OgmiosError) error {
+	var err error
+	switch ogmiosErr.Code {
+	case ogmiosInvalidSignatories:
+		data := struct {
+			InvalidSignatories []string `json:"invalidSignatories"`
+		}{}
+		if err = json.Unmarshal(ogmiosErr.Data, &data); err == nil && len(data.InvalidSignatories) > 0 {
+			vkey, _ := hex.DecodeString(data.InvalidSignatories[0])
+			return &InvalidSignatureError{VKey: vkey}
+		}
+	case ogmiosMissingSignatories:
+		data := struct {
+			MissingSignatories []string `json:"missingSignatories"`
+		}{}
+		if err = json.Unmarshal(ogmiosErr.Data, &data); err == nil {
+			missing := &MissingVKeyWitnessesError{}
+			for _, signatory := range data.MissingSignatories {
+				hash, _ := hex.DecodeString(signatory)
+				missing.KeyHashes = append(missing.KeyHashes, hash)
+			}
+			return missing
+		}
+	case ogmiosUnknownOutputReferences:
+		data := struct {
+			UnknownOutputReferences []struct {
+				Transaction struct {
+					ID string `json:"id"`
+				} `json:"transaction"`
+				Index uint64 `json:"index"`
+			} `json:"unknownOutputReferences"`
+		}{}
+		if err = json.Unmarshal(ogmiosErr.Data, &data); err == nil {
+			badInputs := &BadInputsError{}
+			for _, ref := range data.UnknownOutputReferences {
+				id, _ := hex.DecodeString(ref.Transaction.ID)
+				badInputs.Inputs = append(badInputs.Inputs, TransactionInput{ID: id, Index: ref.Index})
+			}
+			return badInputs
+		}
+	case ogmiosOutsideOfValidityInterval:
+		data := struct {
+			ValidityInterval struct {
+				InvalidBefore uint64 `json:"invalidBefore"`
+				InvalidAfter  uint64 `json:"invalidAfter"`
+			} `json:"validityInterval"`
+			CurrentSlot uint64 `json:"currentSlot"`
+		}{}
+		if err = json.Unmarshal(ogmiosErr.Data, &data); err == nil {
+			return &OutsideValidityIntervalError{
+				Slot:      data.CurrentSlot,
+				ValidFrom: data.ValidityInterval.InvalidBefore,
+				TTL:       data.ValidityInterval.InvalidAfter,
+			}
+		}
+	case ogmiosTransactionTooLarge:
+		data := struct {
+			MeasuredTransactionSize struct {
+				Bytes uint64 `json:"bytes"`
+			} `json:"measuredTransactionSize"`
+			MaximumTransactionSize struct {
+				Bytes uint64 `json:"bytes"`
+			} `json:"maximumTransactionSize"`
+		}{}
+		if err = json.Unmarshal(ogmiosErr.Data, &data); err == nil {
+			return &MaxTxSizeExceededError{Size: data.MeasuredTransactionSize.Bytes, MaxSize: data.MaximumTransactionSize.Bytes}
+		}
+	case ogmiosTransactionFeeTooSmall:
+		data := struct {
+			MinimumRequiredFee ogmiosLovelace `json:"minimumRequiredFee"`
+			ProvidedFee        ogmiosLovelace `json:"providedFee"`
+		}{}
+		if err = json.Unmarshal(ogmiosErr.Data, &data); err == nil {
+			return &FeeTooSmallError{Fee: data.ProvidedFee.Ada.Lovelace, MinFee: data.MinimumRequiredFee.Ada.Lovelace}
+		}
+	case ogmiosValueNotConserved:
+		data := struct {
+			ConsumedValue ogmiosValue `json:"consumedValue"`
+			ProducedValue ogmiosValue `json:"producedValue"`
+		}{}
+		if err = json.Unmarshal(ogmiosErr.Data, &data); err == nil {
+			return &ValueNotConservedError{Consumed: data.ConsumedValue.toValue(), Produced: data.ProducedValue.toValue()}
+		}
+	case ogmiosInsufficientlyFundedOutput:
+		data := struct {
+			InsufficientlyFundedOutputs []struct {
+				Output struct {
+					Value ogmiosValue `json:"value"`
+				} `json:"output"`
+				MinimumRequiredValue ogmiosLovelace `json:"minimumRequiredValue"`
+			} `json:"insufficientlyFundedOutputs"`
+		}{}
+		if err = json.Unmarshal(ogmiosErr.Data, &data); err == nil && len(data.InsufficientlyFundedOutputs) > 0 {
+			output := data.InsufficientlyFundedOutputs[0]
+			return &OutputTooSmallError{
+				Index:     -1,
+				Amount:    output.Output.Value.toValue().Coin,
+				MinAmount: output.MinimumRequiredValue.Ada.Lovelace,
+			}
+		}
+	}
+	return ogmiosErr
+}
@@ -0,0 +1,252 @@
+package cardano
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+type ogmiosTestRequest struct {
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	ID     uint64          `json:"id"`
+}
+
+// newOgmiosTestServer starts a WebSocket stand-in answering each request with
+// the result or error returned by handler. Requests are answered concurrently.
+func newOgmiosTestServer(t *testing.T, handler func(ws *websocket.Conn, req ogmiosTestRequest) (interface{}, *OgmiosError)) *Ogmios {
+	server := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		var mu sync.Mutex
+		for {
+			req := ogmiosTestRequest{}
+			if err := websocket.JSON.Receive(ws, &req); err != nil {
+				return
+			}
+			go func() {
+				result, rpcErr := handler(ws, req)
+				resp := map[string]interface{}{"jsonrpc": "2.0", "method": req.Method, "id": req.ID}
+				if rpcErr != nil {
+					resp["error"] = rpcErr
+				} else {
+					resp["result"] = result
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				websocket.JSON.Send(ws, resp)
+			}()
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	ogmios := NewOgmios("ws" + strings.TrimPrefix(server.URL, "http"))
+	t.Cleanup(func() { ogmios.Close() })
+	return ogmios
+}
+
+func TestOgmiosQueryUtxosConcurrent(t *testing.T) {
+	ogmios := newOgmiosTestServer(t, func(ws *websocket.Conn, req ogmiosTestRequest) (interface{}, *OgmiosError) {
+		params := struct {
+			Addresses []string `json:"addresses"`
+		}{}
+		json.Unmarshal(req.Params, &params)
+		// Answer out of order
+		if strings.HasSuffix(params.Addresses[0], "0") {
+			time.Sleep(20 * time.Millisecond)
+		}
+		return json.RawMessage(fmt.Sprintf(`[{
+			"transaction": {"id": "%064x"}, "index": 1, "address": "%v",
+			"value": {"ada": {"lovelace": 5000000}, "1d7f33bd23d85e1a25d87d86fac4f199c3197a2f7afeb662a0f34e1e": {"776f726c64": 3}}
+		}]`, 7, params.Addresses[0])), nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			addr := Address(fmt.Sprintf("addr_test%v", i))
+			utxos, err := ogmios.QueryUtxos(context.Background(), addr)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if len(utxos) != 1 || utxos[0].Address != addr {
+				t.Errorf("got utxos %+v want one utxo of %v", utxos, addr)
+				return
+			}
+			if got, want := utxos[0].Amount, uint64(5000000); got != want {
+				t.Errorf("got amount %v want %v", got, want)
+			}
+			if got, want := utxos[0].Assets.Quantity("1d7f33bd23d85e1a25d87d86fac4f199c3197a2f7afeb662a0f34e1e", "776f726c64"), uint64(3); got != want {
+				t.Errorf("got asset quantity %v want %v", got, want)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestOgmiosReconnect(t *testing.T) {
+	var mu sync.Mutex
+	dropped := false
+	ogmios := newOgmiosTestServer(t, func(ws *websocket.Conn, req ogmiosTestRequest) (interface{}, *OgmiosError) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !dropped {
+			dropped = true
+			ws.Close()
+			return nil, nil
+		}
+		switch req.Method {
+		case "queryNetwork/tip":
+			return map[string]interface{}{"slot": 1000, "id": "ab"}, nil
+		case "queryNetwork/blockHeight":
+			return 50, nil
+		case "queryLedgerState/epoch":
+			return 2, nil
+		}
+		return nil, &OgmiosError{Code: -32601, Message: "method not found"}
+	})
+
+	tip, err := ogmios.QueryTip(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := tip, (NodeTip{Epoch: 2, Block: 50, Slot: 1000}); got != want {
+		t.Errorf("got tip %+v want %+v", got, want)
+	}
+}
+
+func TestOgmiosQueryProtocolParams(t *testing.T) {
+	ogmios := newOgmiosTestServer(t, func(ws *websocket.Conn, req ogmiosTestRequest) (interface{}, *OgmiosError) {
+		return json.RawMessage(`{
+			"minFeeCoefficient": 44,
+			"minFeeConstant": {"ada": {"lovelace": 155381}},
+			"maxTransactionSize": {"bytes": 16384},
+			"stakeCredentialDeposit": {"ada": {"lovelace": 2000000}},
+			"stakePoolDeposit": {"ada": {"lovelace": 500000000}},
+			"minUtxoDepositCoefficient": 4310,
+			"scriptExecutionPrices": {"memory": "577/10000", "cpu": "721/10000000"},
+			"plutusCostModels": {"plutus:v1": [205665, -1], "plutus:v3": [100788, 420]}
+		}`), nil
+	})
+
+	protocol, err := ogmios.QueryProtocolParams(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ProtocolParams{
+		MinFeeA:          44,
+		MinFeeB:          155381,
+		MaxTxSize:        16384,
+		KeyDeposit:       2000000,
+		PoolDeposit:      500000000,
+		CoinsPerUTxOByte: 4310,
+		PriceMem:         Rational{Num: 577, Den: 10000},
+		PriceStep:        Rational{Num: 721, Den: 10000000},
+		CostModels:       map[PlutusVersion][]int64{PlutusV1: {205665, -1}, PlutusV3: {100788, 420}},
+	}
+	if !reflect.DeepEqual(protocol, want) {
+		t.Errorf("got %+v want %+v", protocol, want)
+	}
+}
+
+func TestOgmiosSubmitTx(t *testing.T) {
+	tx := Transaction{Body: TransactionBody{Inputs: []TransactionInput{{ID: make([]byte, 32)}}, Fee: 170000, Ttl: 100}}
+	tests := []struct {
+		name    string
+		err     *OgmiosError
+		wantErr interface{}
+	}{
+		{
+			name: "accepted",
+		},
+		{
+			name: "unknown output references",
+			err: &OgmiosError{Code: 3117, Message: "unknown output references", Data: json.RawMessage(fmt.Sprintf(
+				`{"unknownOutputReferences": [{"transaction": {"id": "%064x"}, "index": 0}]}`, 0))},
+			wantErr: new(*BadInputsError),
+		},
+		{
+			name:    "fee too small",
+			err:     &OgmiosError{Code: 3122, Message: "fee too small", Data: json.RawMessage(`{"minimumRequiredFee": {"ada": {"lovelace": 171000}}, "providedFee": {"ada": {"lovelace": 170000}}}`)},
+			wantErr: new(*FeeTooSmallError),
+		},
+		{
+			name:    "outside validity interval",
+			err:     &OgmiosError{Code: 3118, Message: "outside of validity interval", Data: json.RawMessage(`{"validityInterval": {"invalidAfter": 100}, "currentSlot": 200}`)},
+			wantErr: new(*OutsideValidityIntervalError),
+		},
+		{
+			name:    "invalid signatories",
+			err:     &OgmiosError{Code: 3100, Message: "invalid signatories", Data: json.RawMessage(fmt.Sprintf(`{"invalidSignatories": ["%064x"]}`, 1))},
+			wantErr: new(*InvalidSignatureError),
+		},
+		{
+			name:    "unknown failure",
+			err:     &OgmiosError{Code: 3005, Message: "era mismatch"},
+			wantErr: new(*OgmiosError),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ogmios := newOgmiosTestServer(t, func(ws *websocket.Conn, req ogmiosTestRequest) (interface{}, *OgmiosError) {
+				if req.Method != "submitTransaction" || !strings.Contains(string(req.Params), tx.CborHex()) {
+					return nil, &OgmiosError{Code: -32602, Message: "invalid params"}
+				}
+				if tt.err != nil {
+					return nil, tt.err
+				}
+				return map[string]interface{}{"transaction": map[string]string{"id": string(tx.ID())}}, nil
+			})
+
+			txId, err := ogmios.SubmitTx(context.Background(), tx)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("SubmitTx() error = %v", err)
+				}
+				if got, want := txId, tx.ID(); got != want {
+					t.Errorf("got tx id %v want %v", got, want)
+				}
+				return
+			}
+			if !errors.As(err, tt.wantErr) {
+				t.Errorf("SubmitTx() error = %v, want %T", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOgmiosEvaluateTx(t *testing.T) {
+	ogmios := newOgmiosTestServer(t, func(ws *websocket.Conn, req ogmiosTestRequest) (interface{}, *OgmiosError) {
+		return json.RawMessage(`[
+			{"validator": {"purpose": "spend", "index": 0}, "budget": {"memory": 1700, "cpu": 476468}},
+			{"validator": {"purpose": "mint", "index": 1}, "budget": {"memory": 200, "cpu": 300}}
+		]`), nil
+	})
+
+	units, err := ogmios.EvaluateTx(context.Background(), &Transaction{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[RedeemerKey]ExUnits{
+		{Tag: RedeemerTagSpend, Index: 0}: {Mem: 1700, Steps: 476468},
+		{Tag: RedeemerTagMint, Index: 1}:  {Mem: 200, Steps: 300},
+	}
+	if len(units) != len(want) {
+		t.Fatalf("got %v want %v", units, want)
+	}
+	for key, exUnits := range want {
+		if units[key] != exUnits {
+			t.Errorf("got %v for %v want %v", units[key], key, exUnits)
+		}
+	}
+}
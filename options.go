@@ -16,13 +16,24 @@ func WithDB(db DB) Options {
 	})
 }
 
+// WithSocket connects the Client to the testnet node listening on the Unix
+// socket at socketPath. Use WithNetworkSocket for other networks.
 func WithSocket(socketPath string) Options {
+	return WithNetworkSocket(socketPath, testnetMagic)
+}
+
+// WithNetworkSocket connects the Client to the node listening on the Unix
+// socket at socketPath, on the network with the given magic, like
+// MainnetMagic or PreprodMagic.
+func WithNetworkSocket(socketPath string, magic uint64) Options {
 	return optionFunc(func(client *Client) {
-		client.socketPath = socketPath
+		client.node = NewNodeSocket(socketPath, magic)
 	})
 }
 
-func WithNode(node cardanoNode) Options {
+// WithNode sets the backend used by the Client and its Wallets to query and
+// submit to the blockchain.
+func WithNode(node ChainBackend) Options {
 	return optionFunc(func(client *Client) {
 		client.node = node
 	})
@@ -0,0 +1,197 @@
+package cardano
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/qredo/cardano-go/coinselection"
+)
+
+const (
+	// messageLabel is the metadata label of transaction messages, as defined
+	// by CIP-20.
+	messageLabel = 674
+	// maxReferenceLength is the maximum length in bytes of a message line.
+	maxReferenceLength = 64
+)
+
+// Payment is an output paid by Wallet.TransferMany.
+type Payment struct {
+	Receiver Address
+	Amount   uint64
+	Assets   MultiAsset
+	// Reference is an optional message, like an invoice number, added to the
+	// CIP-20 metadata of the payment's transaction.
+	Reference string
+}
+
+func (p Payment) output() TransactionOutput {
+	return TransactionOutput{Address: p.Receiver.Bytes(), Amount: p.Amount, Assets: p.Assets}
+}
+
+// TransferMany pays the payments in as few transactions as the transaction
+// size limit allows and returns the id of the transaction of each payment, in
+// the same order. The change of a transaction is only spent by the next ones
+// once confirmed, or right away if transaction chaining is enabled. If a
+// transaction can't be submitted, it returns the ids of the payments
+// submitted so far along with the error.
+func (a *Account) TransferMany(payments []Payment) ([]TransactionID, error) {
+	return a.TransferManyContext(context.Background(), payments)
+}
+
+// TransferManyContext is like TransferMany, with a context bounding the
+// queries to the node, the signatures and the submissions.
+func (a *Account) TransferManyContext(ctx context.Context, payments []Payment) ([]TransactionID, error) {
+	protocol, err := a.wallet.node.QueryProtocolParams(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i, payment := range payments {
+		if len(payment.Reference) > maxReferenceLength {
+			return nil, fmt.Errorf("payment %v: reference longer than %v bytes", i, maxReferenceLength)
+		}
+		if min := minUtxoValue(payment.output(), protocol); payment.Amount < min {
+			return nil, &OutputTooSmallError{Index: i, Amount: payment.Amount, MinAmount: min}
+		}
+	}
+
+	// Change addresses are of the same kind as the receive ones
+	changeAddress := a.Addresses()[0]
+	ids := make([]TransactionID, len(payments))
+	spent := map[string]bool{}
+	for start := 0; start < len(payments); {
+		size, err := batchSize(payments[start:], changeAddress, protocol)
+		if err != nil {
+			return ids, err
+		}
+		end := start + size
+		for {
+			tx, err := a.payBatch(ctx, payments[start:end], spent, protocol)
+			sizeErr := &MaxTxSizeExceededError{}
+			if (errors.Is(err, coinselection.ErrMaxInputCountExceeded) || errors.As(err, &sizeErr)) && end-start > 1 {
+				end = start + (end-start)/2
+				continue
+			}
+			if err != nil {
+				return ids, err
+			}
+			for _, input := range tx.Body.Inputs {
+				spent[utxoKey(input.ID, input.Index)] = true
+			}
+			for i := start; i < end; i++ {
+				ids[i] = tx.ID()
+			}
+			break
+		}
+		start = end
+	}
+	return ids, nil
+}
+
+// payBatch submits a transaction paying the payments, without spending the
+// spent utxos.
+func (a *Account) payBatch(ctx context.Context, payments []Payment, spent map[string]bool, protocol ProtocolParams) (*Transaction, error) {
+	changeAddress, err := a.nextChangeAddress()
+	if err != nil {
+		return nil, err
+	}
+	var submitted *Transaction
+	_, err = a.submit(ctx, func(utxos []Utxo, tip NodeTip) (*Transaction, error) {
+		unspent := []Utxo{}
+		for _, utxo := range utxos {
+			if !spent[utxoKey(utxo.TxId.Bytes(), utxo.Index)] {
+				unspent = append(unspent, utxo)
+			}
+		}
+
+		body := TransactionBody{Ttl: tip.Slot + 1200}
+		for _, payment := range payments {
+			body.Outputs = append(body.Outputs, payment.output())
+		}
+		metadata := paymentsMetadata(payments)
+		picked, body, err := selectCoins(body, metadata, unspent, changeAddress, protocol, nil)
+		if err != nil {
+			return nil, err
+		}
+		tx, err := a.sign(ctx, body, metadata, picked, protocol)
+		if err != nil {
+			return nil, err
+		}
+		if size := uint64(len(tx.Bytes())); protocol.MaxTxSize > 0 && size > protocol.MaxTxSize {
+			return nil, &MaxTxSizeExceededError{Size: size, MaxSize: protocol.MaxTxSize}
+		}
+		submitted = tx
+		return tx, nil
+	})
+	if err != nil {
+		a.releaseChangeAddress(changeAddress)
+	}
+	return submitted, err
+}
+
+// batchSize returns how many of the payments fit in a transaction, along with
+// their metadata, a change output to an address like changeAddress and, as
+// coin selection spends at least an input per output, an input and its
+// witness per payment. A payment too large on its own is a batch of one, left
+// to fail on its submission.
+func batchSize(payments []Payment, changeAddress Address, protocol ProtocolParams) (int, error) {
+	if protocol.MaxTxSize == 0 {
+		return len(payments), nil
+	}
+	var sizeErr error
+	size := sort.Search(len(payments), func(i int) bool {
+		txSize, err := batchTxSize(payments[:i+1], changeAddress)
+		if err != nil {
+			sizeErr = err
+			return true
+		}
+		return txSize > protocol.MaxTxSize
+	})
+	if sizeErr != nil {
+		return 0, sizeErr
+	}
+	if size == 0 {
+		return 1, nil
+	}
+	return size, nil
+}
+
+// batchTxSize returns the estimated size of the transaction paying the
+// payments.
+func batchTxSize(payments []Payment, changeAddress Address) (uint64, error) {
+	// Set a temporary realistic fee and ttl, and the largest change amount
+	body := TransactionBody{Fee: 200000, Ttl: math.MaxUint32}
+	for _, payment := range payments {
+		body.Outputs = append(body.Outputs, payment.output())
+	}
+	body.Outputs = append(body.Outputs, TransactionOutput{Address: changeAddress.Bytes(), Amount: math.MaxUint64})
+	tx := &Transaction{Body: body}
+	if metadata := paymentsMetadata(payments); len(metadata) > 0 {
+		tx.Body.MetadataHash = metadata.hash()
+		tx.Metadata = &metadata
+	}
+	data, err := cbor.Marshal(tx)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(len(data) + len(payments)*txInputSize), nil
+}
+
+// paymentsMetadata returns the CIP-20 metadata holding the references of the
+// payments, or nil if they have none.
+func paymentsMetadata(payments []Payment) transactionMetadata {
+	references := []string{}
+	for _, payment := range payments {
+		if payment.Reference != "" {
+			references = append(references, payment.Reference)
+		}
+	}
+	if len(references) == 0 {
+		return nil
+	}
+	return transactionMetadata{messageLabel: map[string]interface{}{"msg": references}}
+}
@@ -0,0 +1,118 @@
+package cardano
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/qredo/cardano-go/crypto"
+)
+
+func TestWalletTransferMany(t *testing.T) {
+	protocol := ProtocolParams{MinimumUtxoValue: 1000000, MinFeeA: 44, MinFeeB: 155381, MaxTxSize: 1200}
+	client := NewClient(WithDB(&MockDB{}))
+	defer client.Close()
+	w, _, err := client.CreateWallet("test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.SetNetwork(Testnet)
+	emulator := NewEmulator(protocol, map[Address]uint64{w.Addresses()[0]: 1000 * protocol.MinimumUtxoValue})
+	w.node = emulator
+
+	payments := make([]Payment, 16)
+	for i := range payments {
+		key := crypto.NewExtendedSigningKey([]byte(fmt.Sprintf("receiver %v", i)), "")
+		payments[i] = Payment{
+			Receiver:  NewEnterpriseAddress(key.ExtendedVerificationKey(), Testnet),
+			Amount:    uint64(i+1) * protocol.MinimumUtxoValue,
+			Reference: fmt.Sprintf("withdrawal %v", i),
+		}
+	}
+	ids, err := w.TransferMany(payments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	txs := map[TransactionID]bool{}
+	for i, payment := range payments {
+		txs[ids[i]] = true
+		utxos, err := emulator.QueryUtxos(context.Background(), payment.Receiver)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(utxos) != 1 || utxos[0].Amount != payment.Amount || utxos[0].TxId != ids[i] {
+			t.Errorf("payment %v: got utxos %+v want %v in %v", i, utxos, payment.Amount, ids[i])
+		}
+	}
+	if len(txs) < 2 {
+		t.Errorf("got %v transactions want the payments split by the size limit", len(txs))
+	}
+
+	tx, err := emulator.Transaction(ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Metadata == nil || !reflect.DeepEqual(tx.Body.MetadataHash, tx.Metadata.hash()) {
+		t.Errorf("got metadata %v with hash %x", tx.Metadata, tx.Body.MetadataHash)
+	}
+}
+
+func TestWalletTransferManyOutputTooSmall(t *testing.T) {
+	client := NewClient(WithDB(&MockDB{}))
+	defer client.Close()
+	w, _, err := client.CreateWallet("test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.node = NewEmulator(ShelleyProtocol, nil)
+
+	_, err = w.TransferMany([]Payment{{Receiver: w.Addresses()[0], Amount: 1}})
+	if got, want := err, (&OutputTooSmallError{Index: 0, Amount: 1, MinAmount: ShelleyProtocol.MinimumUtxoValue}); !reflect.DeepEqual(got, want) {
+		t.Errorf("got error %v want %v", got, want)
+	}
+}
+
+func TestBatchSize(t *testing.T) {
+	key := crypto.NewExtendedSigningKey([]byte("change"), "")
+	changeAddress := NewEnterpriseAddress(key.ExtendedVerificationKey(), Testnet)
+	payments := make([]Payment, 16)
+	for i := range payments {
+		payments[i] = Payment{Receiver: changeAddress, Amount: 1000000, Reference: fmt.Sprintf("withdrawal %v", i)}
+	}
+
+	tests := []struct {
+		name      string
+		maxTxSize uint64
+	}{
+		{name: "no limit", maxTxSize: 0},
+		{name: "split", maxTxSize: 1200},
+		{name: "too small", maxTxSize: 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			size, err := batchSize(payments, changeAddress, ProtocolParams{MaxTxSize: tt.maxTxSize})
+			if err != nil {
+				t.Fatal(err)
+			}
+			switch {
+			case tt.maxTxSize == 0:
+				if size != len(payments) {
+					t.Errorf("got batch of %v want %v", size, len(payments))
+				}
+			case size == 1:
+				if txSize, _ := batchTxSize(payments[:2], changeAddress); txSize <= tt.maxTxSize {
+					t.Errorf("got batch of 1, 2 payments take %v of %v bytes", txSize, tt.maxTxSize)
+				}
+			default:
+				if txSize, _ := batchTxSize(payments[:size], changeAddress); txSize > tt.maxTxSize {
+					t.Errorf("got batch of %v taking %v of %v bytes", size, txSize, tt.maxTxSize)
+				}
+				if txSize, _ := batchTxSize(payments[:size+1], changeAddress); size < len(payments) && txSize <= tt.maxTxSize {
+					t.Errorf("got batch of %v, %v payments take %v of %v bytes", size, size+1, txSize, tt.maxTxSize)
+				}
+			}
+		})
+	}
+}
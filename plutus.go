@@ -0,0 +1,227 @@
+package cardano
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"github.com/fxamacker/cbor/v2"
+	"golang.org/x/crypto/blake2b"
+)
+
+// PlutusVersion is the plutus language version of a script, which is also
+// the tag prepended to the script when hashing it.
+type PlutusVersion uint8
+
+const (
+	PlutusV1 PlutusVersion = 1
+	PlutusV2 PlutusVersion = 2
+	PlutusV3 PlutusVersion = 3
+)
+
+var plutusEnvelopeTypes = map[string]PlutusVersion{
+	"PlutusScriptV1": PlutusV1,
+	"PlutusScriptV2": PlutusV2,
+	"PlutusScriptV3": PlutusV3,
+}
+
+// costModelLanguages are the names of the plutus languages in the cost models
+// returned by the backends.
+var costModelLanguages = map[string]PlutusVersion{
+	"PlutusV1":  PlutusV1,
+	"PlutusV2":  PlutusV2,
+	"PlutusV3":  PlutusV3,
+	"plutus:v1": PlutusV1,
+	"plutus:v2": PlutusV2,
+	"plutus:v3": PlutusV3,
+}
+
+// PlutusScript is a plutus program of a given language version.
+type PlutusScript struct {
+	Version PlutusVersion
+	Program []byte // flat encoded program
+}
+
+// NewPlutusV1Script creates a PlutusV1 script from its flat encoded program.
+func NewPlutusV1Script(program []byte) PlutusScript {
+	return PlutusScript{Version: PlutusV1, Program: program}
+}
+
+// NewPlutusV2Script creates a PlutusV2 script from its flat encoded program.
+func NewPlutusV2Script(program []byte) PlutusScript {
+	return PlutusScript{Version: PlutusV2, Program: program}
+}
+
+// NewPlutusV3Script creates a PlutusV3 script from its flat encoded program.
+func NewPlutusV3Script(program []byte) PlutusScript {
+	return PlutusScript{Version: PlutusV3, Program: program}
+}
+
+// Bytes returns the serialized script as it appears in the witness set, that
+// is the program wrapped in a cbor byte string.
+func (script PlutusScript) Bytes() []byte {
+	bytes, err := cbor.Marshal(script.Program)
+	if err != nil {
+		panic(err)
+	}
+	return bytes
+}
+
+// Hash returns the blake2b-224 hash of the language tag followed by the
+// serialized script.
+func (script PlutusScript) Hash() []byte {
+	hash, err := blake2b.New(224/8, nil)
+	if err != nil {
+		panic(err)
+	}
+	hash.Write([]byte{byte(script.Version)})
+	hash.Write(script.Bytes())
+	return hash.Sum(nil)
+}
+
+type textEnvelope struct {
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	CborHex     string `json:"cborHex"`
+}
+
+// ReadPlutusScript reads a plutus script from a cardano-cli text envelope
+// (.plutus) file.
+func ReadPlutusScript(path string) (PlutusScript, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return PlutusScript{}, err
+	}
+	return DecodePlutusScript(data)
+}
+
+// DecodePlutusScript decodes a plutus script from a cardano-cli text envelope.
+func DecodePlutusScript(envelope []byte) (PlutusScript, error) {
+	te := textEnvelope{}
+	if err := json.Unmarshal(envelope, &te); err != nil {
+		return PlutusScript{}, err
+	}
+	version, ok := plutusEnvelopeTypes[te.Type]
+	if !ok {
+		return PlutusScript{}, fmt.Errorf("unknown plutus script type %q", te.Type)
+	}
+	data, err := hex.DecodeString(te.CborHex)
+	if err != nil {
+		return PlutusScript{}, err
+	}
+
+	// The envelope holds the serialized script wrapped in another byte string,
+	// although some tools only wrap it once.
+	var serialized []byte
+	if err := cbor.Unmarshal(data, &serialized); err != nil {
+		return PlutusScript{}, err
+	}
+	program := serialized
+	var inner []byte
+	if err := cbor.Unmarshal(serialized, &inner); err == nil {
+		program = inner
+	}
+
+	return PlutusScript{Version: version, Program: program}, nil
+}
+
+// parseCostModels parses the cost models given as arrays of parameters by
+// language name. Cost models given in another form, like the named parameters
+// of older APIs, are left out.
+func parseCostModels(models map[string]json.RawMessage) map[PlutusVersion][]int64 {
+	costModels := map[PlutusVersion][]int64{}
+	for name, model := range models {
+		version, ok := costModelLanguages[name]
+		if !ok {
+			continue
+		}
+		params := []int64{}
+		if err := json.Unmarshal(model, &params); err != nil {
+			continue
+		}
+		costModels[version] = params
+	}
+	if len(costModels) == 0 {
+		return nil
+	}
+	return costModels
+}
+
+// languageViews encodes the cost models of the languages as hashed in the
+// script data hash. PlutusV1 keeps the encoding of its first implementation,
+// with its language id and its cost model, an indefinite length array, both
+// wrapped in byte strings.
+func languageViews(versions []PlutusVersion, costModels map[PlutusVersion][]int64) ([]byte, error) {
+	type view struct{ key, value []byte }
+	views := []view{}
+	for _, version := range versions {
+		costModel, ok := costModels[version]
+		if !ok {
+			return nil, fmt.Errorf("missing cost model of plutus v%v", version)
+		}
+		var key, value []byte
+		var err error
+		if version == PlutusV1 {
+			params := []byte{0x9f}
+			for _, param := range costModel {
+				encoded, err := cbor.Marshal(param)
+				if err != nil {
+					return nil, err
+				}
+				params = append(params, encoded...)
+			}
+			params = append(params, 0xff)
+			if key, err = cbor.Marshal([]byte{0}); err != nil {
+				return nil, err
+			}
+			value, err = cbor.Marshal(params)
+		} else {
+			if key, err = cbor.Marshal(uint64(version - 1)); err != nil {
+				return nil, err
+			}
+			value, err = cbor.Marshal(costModel)
+		}
+		if err != nil {
+			return nil, err
+		}
+		views = append(views, view{key, value})
+	}
+
+	// Canonical map order, shorter keys first
+	sort.Slice(views, func(i, j int) bool {
+		if len(views[i].key) != len(views[j].key) {
+			return len(views[i].key) < len(views[j].key)
+		}
+		return bytes.Compare(views[i].key, views[j].key) < 0
+	})
+	encoded := []byte{0xa0 | byte(len(views))}
+	for _, view := range views {
+		encoded = append(append(encoded, view.key...), view.value...)
+	}
+	return encoded, nil
+}
+
+// scriptDataHash returns the hash of the redeemers, the datums and the
+// language views of a transaction. The datums are left out if there are
+// none, and transactions without redeemers hash an empty redeemer array.
+func scriptDataHash(redeemers []Redeemer, datums []cbor.RawMessage, views []byte) ([]byte, error) {
+	data := []byte{0x80}
+	if len(redeemers) > 0 {
+		var err error
+		if data, err = cbor.Marshal(redeemers); err != nil {
+			return nil, err
+		}
+	}
+	if len(datums) > 0 {
+		encoded, err := cbor.Marshal(datums)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, encoded...)
+	}
+	hash := blake2b.Sum256(append(data, views...))
+	return hash[:], nil
+}
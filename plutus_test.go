@@ -0,0 +1,139 @@
+package cardano
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/qredo/cardano-go/crypto"
+	"golang.org/x/crypto/blake2b"
+)
+
+func TestPlutusScript(t *testing.T) {
+	tests := []struct {
+		name       string
+		envelope   string
+		version    PlutusVersion
+		hash       string
+		enterprise Address
+	}{
+		{
+			name:       "always succeeds v1",
+			envelope:   `{"type": "PlutusScriptV1", "description": "", "cborHex": "4e4d01000033222220051200120011"}`,
+			version:    PlutusV1,
+			hash:       "67f33146617a5e61936081db3b2117cbf59bd2123748f58ac9678656",
+			enterprise: "addr_test1wpnlxv2xv9a9ucvnvzqakwepzl9ltx7jzgm53av2e9ncv4sysemm8",
+		},
+		{
+			name:       "single wrapped envelope",
+			envelope:   `{"type": "PlutusScriptV1", "description": "", "cborHex": "4d01000033222220051200120011"}`,
+			version:    PlutusV1,
+			hash:       "67f33146617a5e61936081db3b2117cbf59bd2123748f58ac9678656",
+			enterprise: "addr_test1wpnlxv2xv9a9ucvnvzqakwepzl9ltx7jzgm53av2e9ncv4sysemm8",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "script.plutus")
+			if err := ioutil.WriteFile(path, []byte(tt.envelope), 0644); err != nil {
+				t.Fatal(err)
+			}
+			script, err := ReadPlutusScript(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := script.Version, tt.version; got != want {
+				t.Errorf("got version %v want %v", got, want)
+			}
+			if got, want := hex.EncodeToString(script.Hash()), tt.hash; got != want {
+				t.Errorf("got hash %v want %v", got, want)
+			}
+			if got, want := NewScriptEnterpriseAddress(script, Testnet), tt.enterprise; got != want {
+				t.Errorf("got address %v want %v", got, want)
+			}
+		})
+	}
+
+	if _, err := DecodePlutusScript([]byte(`{"type": "SimpleScript", "cborHex": "00"}`)); err == nil {
+		t.Errorf("expected error for unknown script type")
+	}
+	if _, err := ReadPlutusScript(filepath.Join(os.TempDir(), "missing.plutus")); err == nil {
+		t.Errorf("expected error for missing file")
+	}
+}
+
+func TestScriptBaseAddress(t *testing.T) {
+	script := NewPlutusV2Script([]byte{0x01, 0x00, 0x00})
+	stakeKey := crypto.NewExtendedSigningKey([]byte("stake key"), "foo")
+	addr := NewScriptBaseAddress(script, stakeKey.ExtendedVerificationKey(), Mainnet)
+
+	data := addr.Bytes()
+	if got, want := len(data), 57; got != want {
+		t.Fatalf("got length %v want %v", got, want)
+	}
+	if got, want := data[0], byte(0x11); got != want {
+		t.Errorf("got header %x want %x", got, want)
+	}
+	if got, want := hex.EncodeToString(data[1:29]), hex.EncodeToString(script.Hash()); got != want {
+		t.Errorf("got payment hash %v want %v", got, want)
+	}
+	if got, want := hex.EncodeToString(data[29:]), hex.EncodeToString(keyHash(stakeKey.ExtendedVerificationKey())); got != want {
+		t.Errorf("got stake hash %v want %v", got, want)
+	}
+}
+
+func TestLanguageViews(t *testing.T) {
+	costModels := map[PlutusVersion][]int64{PlutusV1: {1, -1}, PlutusV2: {2}}
+	tests := []struct {
+		name     string
+		versions []PlutusVersion
+		want     string
+	}{
+		{name: "none", versions: nil, want: "a0"},
+		{name: "v1", versions: []PlutusVersion{PlutusV1}, want: "a14100449f0120ff"},
+		{name: "v2", versions: []PlutusVersion{PlutusV2}, want: "a1018102"},
+		{name: "v1 and v2", versions: []PlutusVersion{PlutusV1, PlutusV2}, want: "a20181024100449f0120ff"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			views, err := languageViews(tt.versions, costModels)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := hex.EncodeToString(views), tt.want; got != want {
+				t.Errorf("got %v want %v", got, want)
+			}
+		})
+	}
+	if _, err := languageViews([]PlutusVersion{PlutusV3}, costModels); err == nil {
+		t.Errorf("expected error for missing cost model")
+	}
+}
+
+func TestScriptDataHash(t *testing.T) {
+	datum := cbor.RawMessage{0xd8, 0x79, 0x80} // Constr 0 []
+	hash, err := scriptDataHash(nil, []cbor.RawMessage{datum}, []byte{0xa0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Empty redeemers, the datums and empty language views
+	want := blake2b.Sum256([]byte{0x80, 0x81, 0xd8, 0x79, 0x80, 0xa0})
+	if !bytes.Equal(hash, want[:]) {
+		t.Errorf("got %x want %x", hash, want)
+	}
+
+	parsed := parseCostModels(map[string]json.RawMessage{
+		"PlutusV1":  json.RawMessage(`[1, -1]`),
+		"plutus:v2": json.RawMessage(`[2]`),
+		"PlutusV3":  json.RawMessage(`{"addInteger-cpu-arguments-intercept": 1}`),
+	})
+	if got, want := parsed, map[PlutusVersion][]int64{PlutusV1: {1, -1}, PlutusV2: {2}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got cost models %v want %v", got, want)
+	}
+}
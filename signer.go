@@ -0,0 +1,64 @@
+package cardano
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/qredo/cardano-go/crypto"
+)
+
+// ErrUnknownKey is returned by a Signer asked to sign with a key it doesn't
+// hold.
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// Signer signs transactions with the key identified by its key hash, the
+// blake2b-224 hash of its verification key as used in addresses. Keys can be
+// held by an MPC protocol, an HSM or a remote signing service, in which case
+// SignTx blocks until the witness is ready or the context is done.
+type Signer interface {
+	// SignTx returns the vkey witness of the transaction body hash signed
+	// with the key.
+	SignTx(ctx context.Context, bodyHash, keyHash []byte) (VKeyWitness, error)
+}
+
+// KeySigner is a Signer of software keys held in memory. It's the default
+// signer of TXBuilder and Wallet.
+type KeySigner struct {
+	mu   sync.Mutex
+	keys map[string]crypto.ExtendedSigningKey
+}
+
+// NewKeySigner returns a KeySigner holding the keys.
+func NewKeySigner(keys ...crypto.ExtendedSigningKey) *KeySigner {
+	signer := &KeySigner{keys: map[string]crypto.ExtendedSigningKey{}}
+	for _, key := range keys {
+		signer.AddKey(key)
+	}
+	return signer
+}
+
+// AddKey adds a signing key to the signer.
+func (s *KeySigner) AddKey(xsk crypto.ExtendedSigningKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[hex.EncodeToString(keyHash(xsk.ExtendedVerificationKey()))] = xsk
+}
+
+// SignTx implements Signer.
+func (s *KeySigner) SignTx(ctx context.Context, bodyHash, keyHash []byte) (VKeyWitness, error) {
+	xsk, ok := s.key(keyHash)
+	if !ok {
+		return VKeyWitness{}, fmt.Errorf("%w %x", ErrUnknownKey, keyHash)
+	}
+	return VKeyWitness{VKey: xsk.ExtendedVerificationKey()[:32], Signature: xsk.Sign(bodyHash)}, nil
+}
+
+func (s *KeySigner) key(keyHash []byte) (crypto.ExtendedSigningKey, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	xsk, ok := s.keys[hex.EncodeToString(keyHash)]
+	return xsk, ok
+}
@@ -0,0 +1,72 @@
+package cardano
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/qredo/cardano-go/crypto"
+)
+
+// remoteSigner signs asynchronously with its keys, like a remote signing
+// service.
+type remoteSigner struct {
+	keys  *KeySigner
+	mu    sync.Mutex
+	calls int
+}
+
+func (s *remoteSigner) SignTx(ctx context.Context, bodyHash, keyHash []byte) (VKeyWitness, error) {
+	if err := ctx.Err(); err != nil {
+		return VKeyWitness{}, err
+	}
+	s.mu.Lock()
+	s.calls++
+	s.mu.Unlock()
+	type result struct {
+		witness VKeyWitness
+		err     error
+	}
+	done := make(chan result, 1)
+	go func() {
+		witness, err := s.keys.SignTx(ctx, bodyHash, keyHash)
+		done <- result{witness, err}
+	}()
+	select {
+	case <-ctx.Done():
+		return VKeyWitness{}, ctx.Err()
+	case res := <-done:
+		return res.witness, res.err
+	}
+}
+
+// hangingSigner never answers, ignoring the context like a stuck remote
+// signing service, until released.
+type hangingSigner struct {
+	release chan struct{}
+}
+
+func (s hangingSigner) SignTx(ctx context.Context, bodyHash, keyHash []byte) (VKeyWitness, error) {
+	<-s.release
+	return VKeyWitness{}, ErrUnknownKey
+}
+
+func TestKeySigner(t *testing.T) {
+	key := crypto.NewExtendedSigningKey([]byte("signer key"), "")
+	other := crypto.NewExtendedSigningKey([]byte("other key"), "")
+	xvk := key.ExtendedVerificationKey()
+	signer := NewKeySigner(key)
+	bodyHash := []byte("body hash")
+
+	witness, err := signer.SignTx(context.Background(), bodyHash, keyHash(xvk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !xvk.Verify(bodyHash, witness.Signature) {
+		t.Errorf("got invalid signature %x", witness.Signature)
+	}
+	if _, err := signer.SignTx(context.Background(), bodyHash, keyHash(other.ExtendedVerificationKey())); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("got error %v want %v", err, ErrUnknownKey)
+	}
+}
@@ -3,6 +3,10 @@ package cardano
 import (
 	"encoding/hex"
 	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/echovl/bech32"
 	"github.com/echovl/ed25519"
 	"github.com/fxamacker/cbor/v2"
 	"github.com/qredo/cardano-go/crypto"
@@ -15,6 +19,36 @@ type ProtocolParams struct {
 	KeyDeposit       uint64
 	MinFeeA          uint64
 	MinFeeB          uint64
+	MaxTxSize        uint64
+	CoinsPerUTxOByte uint64
+	PriceMem         Rational // lovelace per unit of memory
+	PriceStep        Rational // lovelace per CPU step
+	// CostModels are the cost model parameters of each plutus language,
+	// hashed in the script data hash of transactions running scripts.
+	CostModels map[PlutusVersion][]int64
+}
+
+// Rational is a non-negative fraction, used by protocol parameters that
+// aren't whole numbers.
+type Rational struct {
+	Num uint64
+	Den uint64
+}
+
+// parseRational parses a decimal or fraction string, like "0.0577" or
+// "577/10000". An empty string is parsed as zero.
+func parseRational(s string) (Rational, error) {
+	if s == "" {
+		return Rational{}, nil
+	}
+	rat, ok := new(big.Rat).SetString(s)
+	if !ok || rat.Sign() < 0 || !rat.Num().IsUint64() || !rat.Denom().IsUint64() {
+		return Rational{}, fmt.Errorf("invalid rational %q", s)
+	}
+	if rat.Sign() == 0 {
+		return Rational{}, nil
+	}
+	return Rational{Num: rat.Num().Uint64(), Den: rat.Denom().Uint64()}, nil
 }
 
 type TransactionID string
@@ -71,7 +105,12 @@ func CalculateFee(tx *Transaction, protocol ProtocolParams) uint64 {
 
 type TransactionWitnessSet struct {
 	VKeyWitnessSet []VKeyWitness `cbor:"0,keyasint,omitempty"`
-	// TODO: add optional fields 1-4
+	// TODO: add optional fields 1-2
+	PlutusV1Scripts [][]byte          `cbor:"3,keyasint,omitempty"`
+	PlutusData      []cbor.RawMessage `cbor:"4,keyasint,omitempty"` // datums
+	Redeemers       []Redeemer        `cbor:"5,keyasint,omitempty"`
+	PlutusV2Scripts [][]byte          `cbor:"6,keyasint,omitempty"`
+	PlutusV3Scripts [][]byte          `cbor:"7,keyasint,omitempty"`
 }
 
 type VKeyWitness struct {
@@ -80,21 +119,75 @@ type VKeyWitness struct {
 	Signature []byte   // ed25519 signature
 }
 
-// Cbor map
-type transactionMetadata map[uint64]transactionMetadatum
+// ExUnits are the memory and CPU steps spent executing a script.
+type ExUnits struct {
+	_     struct{} `cbor:",toarray"`
+	Mem   uint64
+	Steps uint64
+}
+
+// RedeemerTag is the purpose of the script a redeemer is passed to.
+type RedeemerTag uint8
+
+const (
+	RedeemerTagSpend  RedeemerTag = 0
+	RedeemerTagMint   RedeemerTag = 1
+	RedeemerTagCert   RedeemerTag = 2
+	RedeemerTagReward RedeemerTag = 3
+)
+
+type Redeemer struct {
+	_       struct{} `cbor:",toarray"`
+	Tag     RedeemerTag
+	Index   uint64          // index of the input, policy, certificate or withdrawal
+	Data    cbor.RawMessage // cbor encoded plutus data
+	ExUnits ExUnits
+}
+
+// transactionMetadata maps labels to metadatums, which can be maps, arrays,
+// integers, bytes or text.
+type transactionMetadata map[uint64]interface{}
 
-// This could be cbor map, array, int, bytes or a text
-type transactionMetadatum struct{}
+// metadataEncMode encodes the metadata canonically, so that its hash doesn't
+// depend on the iteration order of maps.
+var metadataEncMode, _ = cbor.CanonicalEncOptions().EncMode()
+
+func (m transactionMetadata) MarshalCBOR() ([]byte, error) {
+	return metadataEncMode.Marshal(map[uint64]interface{}(m))
+}
+
+// hash returns the blake2b-256 hash of the metadata, set as the metadata hash
+// of the transaction body.
+func (m transactionMetadata) hash() []byte {
+	bytes, err := m.MarshalCBOR()
+	if err != nil {
+		panic(err)
+	}
+	hash := blake2b.Sum256(bytes)
+	return hash[:]
+}
 
 type TransactionBody struct {
 	Inputs       []TransactionInput  `cbor:"0,keyasint"`
 	Outputs      []TransactionOutput `cbor:"1,keyasint"`
 	Fee          uint64              `cbor:"2,keyasint"`
 	Ttl          uint64              `cbor:"3,keyasint"`
-	Certificates []Certificate       `cbor:"4,keyasint,omitempty"` // Omit for now
-	Withdrawals  *uint               `cbor:"5,keyasint,omitempty"` // Omit for now
-	Update       *uint               `cbor:"6,keyasint,omitempty"` // Omit for now
-	MetadataHash *uint               `cbor:"7,keyasint,omitempty"` // Omit for now
+	Certificates []Certificate       `cbor:"4,keyasint,omitempty"`
+	Withdrawals  Withdrawals         `cbor:"5,keyasint,omitempty"`
+	Update       cbor.RawMessage     `cbor:"6,keyasint,omitempty"` // Protocol parameter update proposal, kept encoded
+	MetadataHash []byte              `cbor:"7,keyasint,omitempty"`
+	// First slot in which the transaction is valid, the ttl being the last one.
+	ValidityIntervalStart uint64 `cbor:"8,keyasint,omitempty"`
+	// Hash of the redeemers, datums and cost models of the scripts run by
+	// the transaction.
+	ScriptDataHash []byte `cbor:"11,keyasint,omitempty"`
+	// Inputs spent instead of the inputs if a script fails.
+	Collateral []TransactionInput `cbor:"13,keyasint,omitempty"`
+	// Key hashes that must witness the transaction besides the inputs' owners.
+	RequiredSigners  [][]byte           `cbor:"14,keyasint,omitempty"`
+	NetworkID        *Network           `cbor:"15,keyasint,omitempty"`
+	CollateralReturn *TransactionOutput `cbor:"16,keyasint,omitempty"`
+	TotalCollateral  uint64             `cbor:"17,keyasint,omitempty"`
 }
 
 func (body *TransactionBody) Bytes() []byte {
@@ -114,20 +207,39 @@ func (body *TransactionBody) AddSignatures(publicKeys [][]byte, signatures [][]b
 	if len(publicKeys) != len(signatures) {
 		return nil, fmt.Errorf("missmatch length of publicKeys and signatures")
 	}
-	if len(signatures) != len(body.Inputs) {
-		return nil, fmt.Errorf("missmatch length of signatures and inputs")
-	}
 
+	// A key owning several inputs, or owning inputs and being a required
+	// signer, witnesses the transaction once.
 	witnessSet := TransactionWitnessSet{}
-
+	keys := map[string]bool{}
 	for i := 0; i < len(publicKeys); i++ {
+		if len(publicKeys[i]) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid public key length %v", len(publicKeys[i]))
+		}
 		if len(signatures[i]) != ed25519.SignatureSize {
 			return nil, fmt.Errorf("invalid signature length %v", len(signatures[i]))
 		}
+		hash := hex.EncodeToString(keyHash(publicKeys[i]))
+		if keys[hash] {
+			continue
+		}
+		keys[hash] = true
 		witness := VKeyWitness{VKey: publicKeys[i], Signature: signatures[i]}
 		witnessSet.VKeyWitnessSet = append(witnessSet.VKeyWitnessSet, witness)
 	}
 
+	signers := map[string]bool{}
+	for _, signer := range body.RequiredSigners {
+		hash := hex.EncodeToString(signer)
+		if !keys[hash] {
+			return nil, fmt.Errorf("missing signature of required signer %v", hash)
+		}
+		signers[hash] = true
+	}
+	if len(keys) > len(body.Inputs)+len(signers) || (len(body.Inputs) > 0 && len(keys) == 0) {
+		return nil, fmt.Errorf("missmatch number of signing keys %v and inputs %v", len(keys), len(body.Inputs))
+	}
+
 	return &Transaction{
 		Body:       *body,
 		WitnessSet: witnessSet,
@@ -135,29 +247,54 @@ func (body *TransactionBody) AddSignatures(publicKeys [][]byte, signatures [][]b
 	}, nil
 }
 
-func (body *TransactionBody) calculateMinFee(protocol ProtocolParams) uint64 {
+// witnessCount returns the number of vkey witnesses of the payment keys of
+// the spent outputs, one for each distinct payment credential.
+func witnessCount(spent []Utxo) int {
+	credentials := map[string]bool{}
+	for _, utxo := range spent {
+		credential := string(utxo.Address)
+		// Shelley addresses hold their payment credential after the header
+		if _, addr, err := bech32.DecodeToBase256(string(utxo.Address)); err == nil && len(addr) > addressHashSize && addr[0]>>4 < 8 {
+			credential = string(addr[1 : 1+addressHashSize])
+		}
+		credentials[credential] = true
+	}
+	return len(credentials)
+}
+
+// estimatedTx returns the transaction of the body, with the scripts, datums
+// and redeemers of the witness set and the given number of vkey witnesses.
+func (body *TransactionBody) estimatedTx(witnessSet TransactionWitnessSet, witnesses int, metadata transactionMetadata) *Transaction {
 	fakeXSigningKey := crypto.NewExtendedSigningKey([]byte{
 		0x0c, 0xcb, 0x74, 0xf3, 0x6b, 0x7d, 0xa1, 0x64, 0x9a, 0x81, 0x44, 0x67, 0x55, 0x22, 0xd4, 0xd8, 0x09, 0x7c, 0x64, 0x12,
 	}, "")
 
-	witnessSet := TransactionWitnessSet{}
-	for range body.Inputs {
+	witnessSet.VKeyWitnessSet = nil
+	for i := 0; i < witnesses; i++ {
 		witness := VKeyWitness{VKey: fakeXSigningKey.ExtendedVerificationKey()[:32], Signature: fakeXSigningKey.Sign(fakeXSigningKey.ExtendedVerificationKey())}
 		witnessSet.VKeyWitnessSet = append(witnessSet.VKeyWitnessSet, witness)
 	}
 
-	return CalculateFee(&Transaction{
-		Body:       *body,
-		WitnessSet: witnessSet,
-		Metadata:   nil,
-	}, protocol)
+	tx := &Transaction{Body: *body, WitnessSet: witnessSet}
+	if len(metadata) > 0 {
+		tx.Metadata = &metadata
+	}
+	return tx
+}
+
+// calculateMinFee returns the fee of the transaction of the body, with the
+// scripts, datums and redeemers of the witness set and the given number of
+// vkey witnesses.
+func (body *TransactionBody) calculateMinFee(protocol ProtocolParams, witnessSet TransactionWitnessSet, witnesses int, metadata transactionMetadata) uint64 {
+	tx := body.estimatedTx(witnessSet, witnesses, metadata)
+	return CalculateFee(tx, protocol) + scriptFee(witnessSet.Redeemers, protocol)
 }
 
-func (body *TransactionBody) addFee(inputAmount uint64, changeAddress Address, protocol ProtocolParams) error {
+func (body *TransactionBody) addFee(inputAmount uint64, changeAddress Address, protocol ProtocolParams, witnessSet TransactionWitnessSet, witnesses int, metadata transactionMetadata) error {
 	// Set a temporary realistic fee in order to serialize a valid transaction
 	body.Fee = 200000
 
-	minFee := body.calculateMinFee(protocol)
+	minFee := body.calculateMinFee(protocol, witnessSet, witnesses, metadata)
 
 	outputAmount := uint64(0)
 	for _, txOut := range body.Outputs {
@@ -175,21 +312,19 @@ func (body *TransactionBody) addFee(inputAmount uint64, changeAddress Address, p
 	}
 
 	change := inputAmount - outputWithFeeAmount
-	if change < protocol.MinimumUtxoValue {
+	minChange := minUtxoValue(TransactionOutput{Address: changeAddress.Bytes(), Amount: change}, protocol)
+	if change < minChange {
 		body.Fee = minFee + change // burn change
 		return nil
 	}
 
-	newBody := TransactionBody{
-		Inputs: body.Inputs,
-		Outputs: append([]TransactionOutput{{
-			Address: changeAddress.Bytes(),
-			Amount:  change, // set a temporary value
-		}}, body.Outputs...), // change will always be outputs[0] if present
-		Ttl: body.Ttl,
-	}
-	newMinFee := newBody.calculateMinFee(protocol)
-	if change+minFee-newMinFee < protocol.MinimumUtxoValue {
+	newBody := *body
+	newBody.Outputs = append([]TransactionOutput{{
+		Address: changeAddress.Bytes(),
+		Amount:  change, // set a temporary value
+	}}, body.Outputs...) // change will always be outputs[0] if present
+	newMinFee := newBody.calculateMinFee(protocol, witnessSet, witnesses, metadata)
+	if change+minFee-newMinFee < minChange {
 		body.Fee = minFee + change // burn change
 		return nil
 	}
@@ -199,6 +334,17 @@ func (body *TransactionBody) addFee(inputAmount uint64, changeAddress Address, p
 	return nil
 }
 
+// keyHash returns the blake2b-224 hash of a verification key, as used in
+// addresses and required signers.
+func keyHash(xvk crypto.ExtendedVerificationKey) []byte {
+	hash, err := blake2b.New(224/8, nil)
+	if err != nil {
+		panic(err)
+	}
+	hash.Write(xvk[:32])
+	return hash.Sum(nil)
+}
+
 type TransactionInput struct {
 	_     struct{} `cbor:",toarray"`
 	ID    []byte   // HashKey 32 bytes
@@ -206,18 +352,174 @@ type TransactionInput struct {
 }
 
 type TransactionOutput struct {
-	_       struct{} `cbor:",toarray"`
 	Address []byte
 	Amount  uint64
+	Assets  MultiAsset
 }
 
-// TODO: This should a cbor array with one element:
-//
-//	 stake_registration
-//		stake_deregistration
-//		stake_delegation
-//		pool_registration
-//		pool_retirement
-//		genesis_key_delegation
-//		move_instantaneous_rewards_cert
-type Certificate struct{}
+// Value returns the lovelace and native assets held by the output.
+func (out TransactionOutput) Value() Value {
+	return Value{Coin: out.Amount, Assets: out.Assets}
+}
+
+// MarshalCBOR encodes the output as [address, amount], the amount being a
+// [coin, multiasset] pair if the output holds native assets.
+func (out TransactionOutput) MarshalCBOR() ([]byte, error) {
+	if out.Assets.IsEmpty() {
+		return cbor.Marshal([]interface{}{out.Address, out.Amount})
+	}
+	return cbor.Marshal([]interface{}{out.Address, []interface{}{out.Amount, out.Assets}})
+}
+
+// UnmarshalCBOR decodes both the legacy array and the post-alonzo map output
+// formats. Datums and reference scripts are ignored.
+func (out *TransactionOutput) UnmarshalCBOR(data []byte) error {
+	var address, value cbor.RawMessage
+	switch cborMajor(data) {
+	case cborMajorArray:
+		fields := []cbor.RawMessage{}
+		if err := cbor.Unmarshal(data, &fields); err != nil {
+			return err
+		}
+		if len(fields) < 2 {
+			return fmt.Errorf("malformed transaction output")
+		}
+		address, value = fields[0], fields[1]
+	case cborMajorMap:
+		fields := map[uint64]cbor.RawMessage{}
+		if err := cbor.Unmarshal(data, &fields); err != nil {
+			return err
+		}
+		address, value = fields[0], fields[1]
+	default:
+		return fmt.Errorf("malformed transaction output")
+	}
+
+	*out = TransactionOutput{}
+	if err := cbor.Unmarshal(address, &out.Address); err != nil {
+		return err
+	}
+	amount := Value{}
+	if err := cbor.Unmarshal(value, &amount); err != nil {
+		return err
+	}
+	out.Amount, out.Assets = amount.Coin, amount.Assets
+	return nil
+}
+
+// Withdrawals maps hex encoded reward addresses to the withdrawn lovelace.
+type Withdrawals map[string]uint64
+
+func (w Withdrawals) MarshalCBOR() ([]byte, error) {
+	addresses := make([]string, 0, len(w))
+	for addr := range w {
+		addresses = append(addresses, addr)
+	}
+	sort.Strings(addresses)
+
+	out := cborHeader(cborMajorMap, uint64(len(addresses)))
+	for _, addr := range addresses {
+		key, err := marshalHexKey(addr)
+		if err != nil {
+			return nil, err
+		}
+		value, err := cbor.Marshal(w[addr])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, key...)
+		out = append(out, value...)
+	}
+	return out, nil
+}
+
+func (w *Withdrawals) UnmarshalCBOR(data []byte) error {
+	*w = Withdrawals{}
+	return decodeCborMap(data, func(key, value cbor.RawMessage) error {
+		var addr []byte
+		if err := cbor.Unmarshal(key, &addr); err != nil {
+			return err
+		}
+		var amount uint64
+		if err := cbor.Unmarshal(value, &amount); err != nil {
+			return err
+		}
+		(*w)[hex.EncodeToString(addr)] = amount
+		return nil
+	})
+}
+
+type CertificateType uint64
+
+const (
+	StakeRegistration   CertificateType = 0
+	StakeDeregistration CertificateType = 1
+	StakeDelegation     CertificateType = 2
+)
+
+type StakeCredentialType uint64
+
+const (
+	KeyStakeCredential    StakeCredentialType = 0
+	ScriptStakeCredential StakeCredentialType = 1
+)
+
+type StakeCredential struct {
+	_    struct{} `cbor:",toarray"`
+	Type StakeCredentialType
+	Hash []byte
+}
+
+// Certificate is a stake registration, deregistration or delegation
+// certificate. Other certificates are decoded as is and only keep their type.
+type Certificate struct {
+	Type            CertificateType
+	StakeCredential StakeCredential
+	PoolKeyHash     []byte // only used by stake delegation
+	raw             cbor.RawMessage
+}
+
+func (cert Certificate) MarshalCBOR() ([]byte, error) {
+	switch cert.Type {
+	case StakeRegistration, StakeDeregistration:
+		return cbor.Marshal([]interface{}{cert.Type, cert.StakeCredential})
+	case StakeDelegation:
+		return cbor.Marshal([]interface{}{cert.Type, cert.StakeCredential, cert.PoolKeyHash})
+	}
+	if cert.raw == nil {
+		return nil, fmt.Errorf("unsupported certificate type %v", cert.Type)
+	}
+	return cert.raw, nil
+}
+
+func (cert *Certificate) UnmarshalCBOR(data []byte) error {
+	fields := []cbor.RawMessage{}
+	if err := cbor.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	if len(fields) == 0 {
+		return fmt.Errorf("malformed certificate")
+	}
+	*cert = Certificate{}
+	if err := cbor.Unmarshal(fields[0], &cert.Type); err != nil {
+		return err
+	}
+	switch cert.Type {
+	case StakeRegistration, StakeDeregistration, StakeDelegation:
+		if len(fields) < 2 {
+			return fmt.Errorf("malformed certificate")
+		}
+		if err := cbor.Unmarshal(fields[1], &cert.StakeCredential); err != nil {
+			return err
+		}
+		if cert.Type == StakeDelegation {
+			if len(fields) < 3 {
+				return fmt.Errorf("malformed certificate")
+			}
+			return cbor.Unmarshal(fields[2], &cert.PoolKeyHash)
+		}
+	default:
+		cert.raw = append(cbor.RawMessage{}, data...)
+	}
+	return nil
+}
@@ -1,7 +1,13 @@
 package cardano
 
 import (
+	"bytes"
+	"context"
 	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"github.com/fxamacker/cbor/v2"
 	"github.com/qredo/cardano-go/crypto"
 	"golang.org/x/crypto/blake2b"
 )
@@ -19,21 +25,31 @@ type TXBuilderOutput struct {
 }
 
 type TXBuilder struct {
-	tx       Transaction
-	protocol ProtocolParams
-	inputs   []TXBuilderInput
-	outputs  []TransactionOutput
-	ttl      uint64
-	fee      uint64
-	vkeys    map[string]crypto.ExtendedVerificationKey
-	pkeys    map[string]crypto.ExtendedSigningKey
+	tx               Transaction
+	protocol         ProtocolParams
+	inputs           []TXBuilderInput
+	outputs          []TransactionOutput
+	ttl              uint64
+	fee              uint64
+	signers          [][]byte
+	networkID        *Network
+	redeemers        []Redeemer
+	collateral       []TXBuilderInput
+	collateralReturn *TransactionOutput
+	scripts          []PlutusScript
+	datums           []cbor.RawMessage
+	metadata         transactionMetadata
+	evaluator        Evaluator
+	vkeys            map[string]crypto.ExtendedVerificationKey
+	keys             *KeySigner
+	signer           Signer
 }
 
 func NewTxBuilder(protocol ProtocolParams) *TXBuilder {
 	return &TXBuilder{
 		protocol: protocol,
 		vkeys:    map[string]crypto.ExtendedVerificationKey{},
-		pkeys:    map[string]crypto.ExtendedSigningKey{},
+		keys:     NewKeySigner(),
 	}
 }
 
@@ -56,6 +72,74 @@ func (builder *TXBuilder) AddOutput(address Address, amount uint64) {
 	builder.outputs = append(builder.outputs, output)
 }
 
+// AddRequiredSigner requires the given key to witness the transaction, even if
+// it doesn't own any of the inputs.
+func (builder *TXBuilder) AddRequiredSigner(xvk crypto.ExtendedVerificationKey) {
+	builder.signers = append(builder.signers, keyHash(xvk))
+
+	vkeyHashBytes := blake2b.Sum256(xvk)
+	vkeyHashString := hex.EncodeToString(vkeyHashBytes[:])
+	builder.vkeys[vkeyHashString] = xvk
+}
+
+// SetNetworkID binds the transaction to the given network so it can't be
+// replayed on a different one.
+func (builder *TXBuilder) SetNetworkID(network Network) {
+	builder.networkID = &network
+}
+
+// AddRedeemer passes the cbor encoded plutus data to the script identified by
+// tag and index. Its execution units are set by the builder's evaluator.
+func (builder *TXBuilder) AddRedeemer(tag RedeemerTag, index uint64, data []byte) {
+	builder.redeemers = append(builder.redeemers, Redeemer{Tag: tag, Index: index, Data: data})
+}
+
+// AddCollateral adds an input owned by the key as collateral, spent instead of
+// the inputs if a script of the transaction fails. Transactions with
+// redeemers need collateral.
+func (builder *TXBuilder) AddCollateral(xvk crypto.ExtendedVerificationKey, txId TransactionID, index, amount uint64) {
+	input := TXBuilderInput{input: TransactionInput{ID: txId.Bytes(), Index: index}, amount: amount}
+	builder.collateral = append(builder.collateral, input)
+
+	vkeyHashBytes := blake2b.Sum256(xvk)
+	vkeyHashString := hex.EncodeToString(vkeyHashBytes[:])
+	builder.vkeys[vkeyHashString] = xvk
+}
+
+// SetCollateralReturn pays the amount of the collateral back to the address
+// if a script fails, the rest being collected as the total collateral.
+func (builder *TXBuilder) SetCollateralReturn(address Address, amount uint64) {
+	builder.collateralReturn = &TransactionOutput{Address: address.Bytes(), Amount: amount}
+}
+
+// AddPlutusScript adds a script run by the redeemers to the witness set. The
+// cost models of the languages of the scripts are taken from the protocol
+// parameters.
+func (builder *TXBuilder) AddPlutusScript(script PlutusScript) {
+	builder.scripts = append(builder.scripts, script)
+}
+
+// AddDatum adds the cbor encoded plutus data of a datum hash of a spent
+// output to the witness set.
+func (builder *TXBuilder) AddDatum(data []byte) {
+	builder.datums = append(builder.datums, data)
+}
+
+// AddMetadata adds a metadatum to the transaction under the label. The value
+// can be a map, a slice, an integer, a byte slice or a string.
+func (builder *TXBuilder) AddMetadata(label uint64, value interface{}) {
+	if builder.metadata == nil {
+		builder.metadata = transactionMetadata{}
+	}
+	builder.metadata[label] = value
+}
+
+// SetEvaluator sets the evaluator used to compute the execution units of the
+// redeemers when the fee is added.
+func (builder *TXBuilder) SetEvaluator(evaluator Evaluator) {
+	builder.evaluator = evaluator
+}
+
 func (builder *TXBuilder) SetTtl(ttl uint64) {
 	builder.ttl = ttl
 }
@@ -66,13 +150,29 @@ func (builder *TXBuilder) SetFee(fee uint64) {
 
 // This assumes that the builder inputs and outputs are defined
 func (builder *TXBuilder) AddFee(address Address) error {
+	return builder.AddFeeContext(context.Background(), address)
+}
+
+// AddFeeContext is like AddFee, with the context bounding the evaluation of
+// the redeemers. Without a deadline, the evaluation times out after 30s.
+func (builder *TXBuilder) AddFeeContext(ctx context.Context, address Address) error {
 	inputAmount := uint64(0)
 	for _, txIn := range builder.inputs {
 		inputAmount += txIn.amount
 	}
-	body := builder.buildBody()
+	body, err := builder.buildBody()
+	if err != nil {
+		return err
+	}
 
-	if err := body.addFee(inputAmount, address, builder.protocol); err != nil {
+	if err := builder.evaluate(ctx, body); err != nil {
+		return err
+	}
+	// The script data hash covers the evaluated execution units
+	if body.ScriptDataHash, err = builder.scriptDataHash(); err != nil {
+		return err
+	}
+	if err := body.addFee(inputAmount, address, builder.protocol, builder.witnessSet(), len(builder.vkeys), builder.metadata); err != nil {
 		return err
 	}
 	builder.outputs = body.Outputs
@@ -80,31 +180,133 @@ func (builder *TXBuilder) AddFee(address Address) error {
 	return nil
 }
 
+// evaluate sets the execution units of the redeemers using the builder's
+// evaluator. Redeemers keep their units if there is no evaluator.
+func (builder *TXBuilder) evaluate(ctx context.Context, body TransactionBody) error {
+	if builder.evaluator == nil || len(builder.redeemers) == 0 {
+		return nil
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, evaluateTimeout)
+		defer cancel()
+	}
+
+	// Set a temporary realistic fee in order to evaluate a valid transaction
+	body.Fee = 200000
+	tx := &Transaction{Body: body, WitnessSet: builder.witnessSet()}
+	units, err := builder.evaluator.EvaluateTx(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	for i, redeemer := range builder.redeemers {
+		exUnits, ok := units[RedeemerKey{Tag: redeemer.Tag, Index: redeemer.Index}]
+		if !ok {
+			return fmt.Errorf("missing execution units for redeemer %v:%v", redeemer.Tag, redeemer.Index)
+		}
+		builder.redeemers[i].ExUnits = exUnits
+	}
+	return nil
+}
+
 func (builder *TXBuilder) Sign(xsk crypto.ExtendedSigningKey) {
-	pkeyHashBytes := blake2b.Sum256(xsk)
-	pkeyHashString := hex.EncodeToString(pkeyHashBytes[:])
-	builder.pkeys[pkeyHashString] = xsk
+	builder.keys.AddKey(xsk)
 }
 
+// SetSigner sets the signer of the keys added by AddInput and
+// AddRequiredSigner whose signing key isn't given to Sign.
+func (builder *TXBuilder) SetSigner(signer Signer) {
+	builder.signer = signer
+}
+
+// Build builds the signed transaction. It panics if a key can't sign it, use
+// BuildContext with signers that can fail, or if the transaction has
+// redeemers without collateral, scripts or the cost models of their
+// languages.
 func (builder *TXBuilder) Build() Transaction {
-	if len(builder.pkeys) != len(builder.vkeys) {
-		panic("missing signatures")
+	tx, err := builder.BuildContext(context.Background())
+	if err != nil {
+		panic(err)
 	}
+	return tx
+}
 
-	body := builder.buildBody()
-	witnessSet := TransactionWitnessSet{}
+// BuildContext builds the signed transaction. The witnesses of its keys are
+// requested concurrently, from the keys given to Sign or the builder's signer
+// otherwise. It returns when the context is done, even if a signer doesn't.
+func (builder *TXBuilder) BuildContext(ctx context.Context) (Transaction, error) {
+	body, err := builder.buildBody()
+	if err != nil {
+		return Transaction{}, err
+	}
 	txHash := blake2b.Sum256(body.Bytes())
-	for _, pkey := range builder.pkeys {
-		publicKey := pkey.ExtendedVerificationKey()[:32]
-		signature := pkey.Sign(txHash[:])
-		witness := VKeyWitness{VKey: publicKey, Signature: signature}
-		witnessSet.VKeyWitnessSet = append(witnessSet.VKeyWitnessSet, witness)
+
+	ids := make([]string, 0, len(builder.vkeys))
+	for id := range builder.vkeys {
+		ids = append(ids, id)
 	}
+	sort.Strings(ids)
+	type result struct {
+		index   int
+		witness VKeyWitness
+		err     error
+	}
+	results := make(chan result, len(ids))
+	for i, id := range ids {
+		go func(i int, xvk crypto.ExtendedVerificationKey) {
+			witness, err := builder.witness(ctx, txHash[:], xvk)
+			results <- result{i, witness, err}
+		}(i, builder.vkeys[id])
+	}
+	witnesses := make([]VKeyWitness, len(ids))
+	for range ids {
+		select {
+		case res := <-results:
+			if res.err != nil {
+				return Transaction{}, res.err
+			}
+			witnesses[res.index] = res.witness
+		case <-ctx.Done():
+			// Signers not returning when the context is done are left
+			// behind
+			return Transaction{}, ctx.Err()
+		}
+	}
+
+	witnessSet := builder.witnessSet()
+	witnessSet.VKeyWitnessSet = witnesses
+	tx := Transaction{Body: body, WitnessSet: witnessSet}
+	if len(builder.metadata) > 0 {
+		tx.Metadata = &builder.metadata
+	}
+	return tx, nil
+}
 
-	return Transaction{Body: body, WitnessSet: witnessSet, Metadata: nil}
+// witness returns the witness of the key signing the body hash, checked
+// against the key.
+func (builder *TXBuilder) witness(ctx context.Context, bodyHash []byte, xvk crypto.ExtendedVerificationKey) (VKeyWitness, error) {
+	hash := keyHash(xvk)
+	var signer Signer = builder.signer
+	if builder.keys != nil {
+		if _, ok := builder.keys.key(hash); ok {
+			signer = builder.keys
+		}
+	}
+	if signer == nil {
+		return VKeyWitness{}, fmt.Errorf("missing signature of key %x", hash)
+	}
+	witness, err := signer.SignTx(ctx, bodyHash, hash)
+	if err != nil {
+		return VKeyWitness{}, err
+	}
+	if !bytes.Equal(witness.VKey, xvk[:32]) || !xvk.Verify(bodyHash, witness.Signature) {
+		return VKeyWitness{}, fmt.Errorf("invalid witness of key %x", hash)
+	}
+	return witness, nil
 }
 
-func (builder *TXBuilder) buildBody() TransactionBody {
+func (builder *TXBuilder) buildBody() (TransactionBody, error) {
 	inputs := make([]TransactionInput, len(builder.inputs))
 	for i, txInput := range builder.inputs {
 		inputs[i] = TransactionInput{
@@ -113,10 +315,80 @@ func (builder *TXBuilder) buildBody() TransactionBody {
 		}
 	}
 
-	return TransactionBody{
-		Inputs:  inputs,
-		Outputs: builder.outputs,
-		Fee:     builder.fee,
-		Ttl:     builder.ttl,
+	body := TransactionBody{
+		Inputs:          inputs,
+		Outputs:         builder.outputs,
+		Fee:             builder.fee,
+		Ttl:             builder.ttl,
+		RequiredSigners: builder.signers,
+		NetworkID:       builder.networkID,
+	}
+	if len(builder.metadata) > 0 {
+		body.MetadataHash = builder.metadata.hash()
+	}
+
+	if len(builder.redeemers) > 0 && len(builder.collateral) == 0 {
+		return TransactionBody{}, fmt.Errorf("missing collateral of a transaction with redeemers")
+	}
+	collateralAmount := uint64(0)
+	for _, txInput := range builder.collateral {
+		body.Collateral = append(body.Collateral, txInput.input)
+		collateralAmount += txInput.amount
+	}
+	if builder.collateralReturn != nil {
+		if builder.collateralReturn.Amount > collateralAmount {
+			return TransactionBody{}, fmt.Errorf("collateral return %v exceeds the collateral %v", builder.collateralReturn.Amount, collateralAmount)
+		}
+		body.CollateralReturn = builder.collateralReturn
+		body.TotalCollateral = collateralAmount - builder.collateralReturn.Amount
+	}
+
+	var err error
+	if body.ScriptDataHash, err = builder.scriptDataHash(); err != nil {
+		return TransactionBody{}, err
+	}
+	return body, nil
+}
+
+// scriptDataHash returns the script data hash of the redeemers and datums of
+// the transaction, if any.
+func (builder *TXBuilder) scriptDataHash() ([]byte, error) {
+	if len(builder.redeemers) == 0 && len(builder.datums) == 0 {
+		return nil, nil
+	}
+	// Only the languages of the scripts run by redeemers are hashed
+	versions := []PlutusVersion{}
+	if len(builder.redeemers) > 0 {
+		if len(builder.scripts) == 0 {
+			return nil, fmt.Errorf("missing plutus scripts of the redeemers")
+		}
+		seen := map[PlutusVersion]bool{}
+		for _, script := range builder.scripts {
+			if !seen[script.Version] {
+				seen[script.Version] = true
+				versions = append(versions, script.Version)
+			}
+		}
+	}
+	views, err := languageViews(versions, builder.protocol.CostModels)
+	if err != nil {
+		return nil, err
+	}
+	return scriptDataHash(builder.redeemers, builder.datums, views)
+}
+
+// witnessSet returns the scripts, datums and redeemers of the transaction.
+func (builder *TXBuilder) witnessSet() TransactionWitnessSet {
+	witnessSet := TransactionWitnessSet{PlutusData: builder.datums, Redeemers: builder.redeemers}
+	for _, script := range builder.scripts {
+		switch script.Version {
+		case PlutusV1:
+			witnessSet.PlutusV1Scripts = append(witnessSet.PlutusV1Scripts, script.Bytes())
+		case PlutusV2:
+			witnessSet.PlutusV2Scripts = append(witnessSet.PlutusV2Scripts, script.Bytes())
+		case PlutusV3:
+			witnessSet.PlutusV3Scripts = append(witnessSet.PlutusV3Scripts, script.Bytes())
+		}
 	}
+	return witnessSet
 }
@@ -1,8 +1,15 @@
 package cardano
 
 import (
-	"github.com/qredo/cardano-go/crypto"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
 	"testing"
+	"time"
+
+	"github.com/qredo/cardano-go/crypto"
+	"golang.org/x/crypto/blake2b"
 )
 
 func TestTXBuilder_AddFee(t *testing.T) {
@@ -156,11 +163,16 @@ func TestTXBuilder_AddFee(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			// The inputs are owned by a single key
+			inputSigningKey := crypto.NewExtendedSigningKey([]byte("input key"), "foo")
+			inputKey := inputSigningKey.ExtendedVerificationKey()
+			inputKeyHash := blake2b.Sum256(inputKey)
 			builder := &TXBuilder{
 				protocol: tt.fields.protocol,
 				inputs:   tt.fields.inputs,
 				outputs:  tt.fields.outputs,
 				ttl:      tt.fields.ttl,
+				vkeys:    map[string]crypto.ExtendedVerificationKey{hex.EncodeToString(inputKeyHash[:]): inputKey},
 			}
 			key := crypto.NewExtendedSigningKey([]byte("change address"), "foo")
 			change := NewEnterpriseAddress(key.ExtendedVerificationKey(), Testnet)
@@ -195,3 +207,281 @@ func TestTXBuilder_AddFee(t *testing.T) {
 		})
 	}
 }
+
+func TestTXBuilder_RequiredSigners(t *testing.T) {
+	inputKey := crypto.NewExtendedSigningKey([]byte("input key"), "foo")
+	signerKey := crypto.NewExtendedSigningKey([]byte("required signer"), "foo")
+	receiver := NewEnterpriseAddress(inputKey.ExtendedVerificationKey(), Testnet)
+
+	newBuilder := func() *TXBuilder {
+		builder := NewTxBuilder(ShelleyProtocol)
+		builder.AddInput(inputKey.ExtendedVerificationKey(), TransactionID(hex.EncodeToString(make([]byte, 32))), 0, 3*ShelleyProtocol.MinimumUtxoValue)
+		builder.AddOutput(receiver, ShelleyProtocol.MinimumUtxoValue)
+		builder.SetTtl(100)
+		return builder
+	}
+
+	plain := newBuilder()
+	if err := plain.AddFee(receiver); err != nil {
+		t.Fatal(err)
+	}
+
+	builder := newBuilder()
+	builder.AddRequiredSigner(signerKey.ExtendedVerificationKey())
+	builder.SetNetworkID(Testnet)
+	if err := builder.AddFee(receiver); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := builder.fee, plain.fee; got <= want {
+		t.Errorf("got fee %v want greater than %v", got, want)
+	}
+
+	builder.Sign(inputKey)
+	builder.Sign(signerKey)
+	tx := builder.Build()
+
+	decoded, err := DecodeTransaction(tx.CborHex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(decoded.Body.RequiredSigners), 1; got != want {
+		t.Fatalf("got %v required signers want %v", got, want)
+	}
+	if got, want := decoded.Body.RequiredSigners[0], keyHash(signerKey.ExtendedVerificationKey()); !bytes.Equal(got, want) {
+		t.Errorf("got signer %x want %x", got, want)
+	}
+	if decoded.Body.NetworkID == nil || *decoded.Body.NetworkID != Testnet {
+		t.Errorf("got network id %v want %v", decoded.Body.NetworkID, Testnet)
+	}
+	if got, want := len(decoded.WitnessSet.VKeyWitnessSet), 2; got != want {
+		t.Errorf("got %v witnesses want %v", got, want)
+	}
+}
+
+type fakeEvaluator struct {
+	units map[RedeemerKey]ExUnits
+}
+
+func (e *fakeEvaluator) EvaluateTx(ctx context.Context, tx *Transaction) (map[RedeemerKey]ExUnits, error) {
+	return e.units, nil
+}
+
+// silentEvaluator never answers, like a server that stopped responding.
+type silentEvaluator struct{}
+
+func (silentEvaluator) EvaluateTx(ctx context.Context, tx *Transaction) (map[RedeemerKey]ExUnits, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestTXBuilder_Evaluator(t *testing.T) {
+	key := crypto.NewExtendedSigningKey([]byte("input key"), "foo")
+	receiver := NewEnterpriseAddress(key.ExtendedVerificationKey(), Testnet)
+	protocol := ShelleyProtocol
+	protocol.PriceMem = Rational{Num: 577, Den: 10000}
+	protocol.PriceStep = Rational{Num: 721, Den: 10000000}
+	protocol.CostModels = map[PlutusVersion][]int64{PlutusV2: {205665, 812, 1}}
+	exUnits := ExUnits{Mem: 1700, Steps: 476468}
+	unitData := []byte{0xd8, 0x79, 0x80} // Constr 0 []
+	txId := TransactionID(hex.EncodeToString(make([]byte, 32)))
+
+	newBuilder := func() *TXBuilder {
+		builder := NewTxBuilder(protocol)
+		builder.AddInput(key.ExtendedVerificationKey(), txId, 0, 3*protocol.MinimumUtxoValue)
+		builder.AddCollateral(key.ExtendedVerificationKey(), txId, 1, 2*protocol.MinimumUtxoValue)
+		builder.AddPlutusScript(NewPlutusV2Script([]byte{0x01, 0x00, 0x00, 0x20, 0x01}))
+		return builder
+	}
+
+	builder := newBuilder()
+	builder.AddOutput(receiver, protocol.MinimumUtxoValue)
+	builder.AddRedeemer(RedeemerTagSpend, 0, unitData)
+	builder.SetEvaluator(&fakeEvaluator{units: map[RedeemerKey]ExUnits{{Tag: RedeemerTagSpend, Index: 0}: exUnits}})
+	if err := builder.AddFee(receiver); err != nil {
+		t.Fatal(err)
+	}
+	builder.Sign(key)
+	tx := builder.Build()
+
+	if got, want := len(tx.WitnessSet.Redeemers), 1; got != want {
+		t.Fatalf("got %v redeemers want %v", got, want)
+	}
+	if got, want := tx.WitnessSet.Redeemers[0].ExUnits, exUnits; got != want {
+		t.Errorf("got ex units %v want %v", got, want)
+	}
+
+	// ceil(0.0577 * 1700 + 0.0000721 * 476468) = ceil(132.4327...) = 133
+	if got, want := scriptFee(tx.WitnessSet.Redeemers, protocol), uint64(133); got != want {
+		t.Errorf("got script fee %v want %v", got, want)
+	}
+	if got, want := tx.Body.Fee, tx.Body.calculateMinFee(protocol, tx.WitnessSet, len(tx.WitnessSet.VKeyWitnessSet), nil); got != want {
+		t.Errorf("got fee %v want %v", got, want)
+	}
+
+	builder = newBuilder()
+	builder.AddRedeemer(RedeemerTagMint, 0, unitData)
+	builder.SetEvaluator(&fakeEvaluator{})
+	if err := builder.AddFee(receiver); err == nil {
+		t.Errorf("expected error for redeemer without execution units")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	builder.SetEvaluator(silentEvaluator{})
+	if err := builder.AddFeeContext(ctx, receiver); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestTXBuilder_ScriptData(t *testing.T) {
+	key := crypto.NewExtendedSigningKey([]byte("input key"), "foo")
+	receiver := NewEnterpriseAddress(key.ExtendedVerificationKey(), Testnet)
+	protocol := ShelleyProtocol
+	protocol.CostModels = map[PlutusVersion][]int64{PlutusV1: {205665, 812}, PlutusV2: {205665, 812, 1}}
+	txId := TransactionID(hex.EncodeToString(make([]byte, 32)))
+	unitData := []byte{0xd8, 0x79, 0x80} // Constr 0 []
+	script := NewPlutusV2Script([]byte{0x01, 0x00, 0x00, 0x20, 0x01})
+
+	newBuilder := func() *TXBuilder {
+		builder := NewTxBuilder(protocol)
+		builder.AddInput(key.ExtendedVerificationKey(), txId, 0, 3*protocol.MinimumUtxoValue)
+		builder.AddOutput(receiver, protocol.MinimumUtxoValue)
+		builder.AddRedeemer(RedeemerTagSpend, 0, unitData)
+		builder.SetTtl(100)
+		return builder
+	}
+
+	builder := newBuilder()
+	builder.AddPlutusScript(script)
+	if err := builder.AddFee(receiver); err == nil {
+		t.Error("got fee of a transaction without collateral want error")
+	}
+	builder = newBuilder()
+	builder.AddCollateral(key.ExtendedVerificationKey(), txId, 1, 2*protocol.MinimumUtxoValue)
+	if err := builder.AddFee(receiver); err == nil {
+		t.Error("got fee of a transaction without scripts want error")
+	}
+	builder.AddPlutusScript(NewPlutusV3Script(script.Program))
+	if err := builder.AddFee(receiver); err == nil {
+		t.Error("got fee of a transaction without cost model want error")
+	}
+
+	builder = newBuilder()
+	builder.AddCollateral(key.ExtendedVerificationKey(), txId, 1, 2*protocol.MinimumUtxoValue)
+	builder.SetCollateralReturn(receiver, 3*protocol.MinimumUtxoValue)
+	builder.AddPlutusScript(script)
+	builder.AddDatum(unitData)
+	if err := builder.AddFee(receiver); err == nil {
+		t.Error("got fee of a collateral return exceeding the collateral want error")
+	}
+	builder.SetCollateralReturn(receiver, protocol.MinimumUtxoValue)
+	if err := builder.AddFee(receiver); err != nil {
+		t.Fatal(err)
+	}
+	builder.Sign(key)
+	tx, err := builder.BuildContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := DecodeTransaction(tx.CborHex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(decoded.Body.Collateral), 1; got != want {
+		t.Fatalf("got %v collateral inputs want %v", got, want)
+	}
+	if got, want := decoded.Body.Collateral[0].Index, uint64(1); got != want {
+		t.Errorf("got collateral index %v want %v", got, want)
+	}
+	if decoded.Body.CollateralReturn == nil || decoded.Body.CollateralReturn.Amount != protocol.MinimumUtxoValue {
+		t.Errorf("got collateral return %v want %v", decoded.Body.CollateralReturn, protocol.MinimumUtxoValue)
+	}
+	if got, want := decoded.Body.TotalCollateral, protocol.MinimumUtxoValue; got != want {
+		t.Errorf("got total collateral %v want %v", got, want)
+	}
+	if got, want := len(decoded.WitnessSet.PlutusV2Scripts), 1; got != want {
+		t.Fatalf("got %v scripts want %v", got, want)
+	}
+	if got, want := decoded.WitnessSet.PlutusV2Scripts[0], script.Bytes(); !bytes.Equal(got, want) {
+		t.Errorf("got script %x want %x", got, want)
+	}
+	if got, want := len(decoded.WitnessSet.PlutusData), 1; got != want {
+		t.Fatalf("got %v datums want %v", got, want)
+	}
+
+	views, err := languageViews([]PlutusVersion{PlutusV2}, protocol.CostModels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := scriptDataHash(decoded.WitnessSet.Redeemers, decoded.WitnessSet.PlutusData, views)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := decoded.Body.ScriptDataHash, hash; !bytes.Equal(got, want) {
+		t.Errorf("got script data hash %x want %x", got, want)
+	}
+	if got, want := tx.Body.Fee, tx.Body.calculateMinFee(protocol, tx.WitnessSet, len(tx.WitnessSet.VKeyWitnessSet), nil); got != want {
+		t.Errorf("got fee %v want %v", got, want)
+	}
+}
+
+func TestTXBuilder_Signer(t *testing.T) {
+	inputKey := crypto.NewExtendedSigningKey([]byte("input key"), "foo")
+	remoteKey := crypto.NewExtendedSigningKey([]byte("remote key"), "foo")
+	receiver := NewEnterpriseAddress(inputKey.ExtendedVerificationKey(), Testnet)
+
+	newBuilder := func() *TXBuilder {
+		builder := NewTxBuilder(ShelleyProtocol)
+		builder.AddInput(inputKey.ExtendedVerificationKey(), TransactionID(hex.EncodeToString(make([]byte, 32))), 0, 3*ShelleyProtocol.MinimumUtxoValue)
+		builder.AddRequiredSigner(remoteKey.ExtendedVerificationKey())
+		builder.AddOutput(receiver, ShelleyProtocol.MinimumUtxoValue)
+		builder.SetTtl(100)
+		if err := builder.AddFee(receiver); err != nil {
+			t.Fatal(err)
+		}
+		builder.Sign(inputKey)
+		return builder
+	}
+
+	signer := &remoteSigner{keys: NewKeySigner(remoteKey)}
+	builder := newBuilder()
+	builder.SetSigner(signer)
+	tx, err := builder.BuildContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := signer.calls, 1; got != want {
+		t.Errorf("got %v remote signatures want %v", got, want)
+	}
+	if got, want := len(tx.WitnessSet.VKeyWitnessSet), 2; got != want {
+		t.Fatalf("got %v witnesses want %v", got, want)
+	}
+	txHash := blake2b.Sum256(tx.Body.Bytes())
+	for _, key := range []crypto.ExtendedSigningKey{inputKey, remoteKey} {
+		xvk := key.ExtendedVerificationKey()
+		found := false
+		for _, witness := range tx.WitnessSet.VKeyWitnessSet {
+			found = found || (bytes.Equal(witness.VKey, xvk[:32]) && xvk.Verify(txHash[:], witness.Signature))
+		}
+		if !found {
+			t.Errorf("missing witness of key %x", keyHash(xvk))
+		}
+	}
+
+	if _, err := newBuilder().BuildContext(context.Background()); err == nil {
+		t.Error("got transaction without signer want error")
+	}
+	builder = newBuilder()
+	builder.SetSigner(&remoteSigner{keys: NewKeySigner(inputKey)})
+	if _, err := builder.BuildContext(context.Background()); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("got error %v want %v", err, ErrUnknownKey)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	builder = newBuilder()
+	builder.SetSigner(&remoteSigner{keys: NewKeySigner(remoteKey)})
+	if _, err := builder.BuildContext(ctx); err == nil {
+		t.Error("got transaction from a canceled signer want error")
+	}
+}
@@ -1,7 +1,12 @@
 package cardano
 
 import (
+	"bytes"
+	"encoding/hex"
 	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/qredo/cardano-go/crypto"
 )
 
 func TestAddress(t *testing.T) {
@@ -19,3 +24,137 @@ func TestAddress(t *testing.T) {
 		}
 	}
 }
+
+func TestTransactionBodyRoundTrip(t *testing.T) {
+	policyID := "1d7f33bd23d85e1a25d87d86fac4f199c3197a2f7afeb662a0f34e1e"
+	body := TransactionBody{
+		Inputs: []TransactionInput{{ID: make([]byte, 32), Index: 1}},
+		Outputs: []TransactionOutput{
+			{Address: []byte{0x60, 0x01}, Amount: 1000000},
+			{Address: []byte{0x60, 0x02}, Amount: 2000000, Assets: NewMultiAsset(policyID, "776f726c646d6f62696c65746f6b656e", 7)},
+		},
+		Fee: 170000,
+		Ttl: 1000,
+		Certificates: []Certificate{
+			{Type: StakeRegistration, StakeCredential: StakeCredential{Type: KeyStakeCredential, Hash: make([]byte, 28)}},
+			{Type: StakeDelegation, StakeCredential: StakeCredential{Type: KeyStakeCredential, Hash: make([]byte, 28)}, PoolKeyHash: make([]byte, 28)},
+		},
+		Withdrawals: Withdrawals{"e0" + policyID: 42},
+		// Proposal of a max tx size of 1000 by a genesis key at epoch 5
+		Update:                cbor.RawMessage(append(append([]byte{0x82, 0xa1, 0x58, 0x1c}, make([]byte, 28)...), 0xa1, 0x03, 0x19, 0x03, 0xe8, 0x05)),
+		ValidityIntervalStart: 10,
+	}
+
+	tx := &Transaction{Body: body}
+	decoded, err := DecodeTransaction(tx.CborHex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := decoded.CborHex(), tx.CborHex(); got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+	if got, want := decoded.Body.Outputs[1].Value(), body.Outputs[1].Value(); !got.Equal(want) {
+		t.Errorf("got %v want %v", got, want)
+	}
+	if got, want := decoded.Body.Withdrawals["e0"+policyID], uint64(42); got != want {
+		t.Errorf("got %v want %v", got, want)
+	}
+	if got, want := decoded.Body.Update, body.Update; !bytes.Equal(got, want) {
+		t.Errorf("got update %x want %x", got, want)
+	}
+}
+
+func TestDecodeTransactionOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		cborHex string
+		want    TransactionOutput
+	}{
+		{
+			name:    "legacy with datum hash",
+			cborHex: "8342600119053958200000000000000000000000000000000000000000000000000000000000000000",
+			want:    TransactionOutput{Address: []byte{0x60, 0x01}, Amount: 1337},
+		},
+		{
+			name:    "post alonzo map",
+			cborHex: "a200426001018219053aa1414aa1414205",
+			want:    TransactionOutput{Address: []byte{0x60, 0x01}, Amount: 1338, Assets: NewMultiAsset("4a", "42", 5)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := hex.DecodeString(tt.cborHex)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := TransactionOutput{}
+			if err := got.UnmarshalCBOR(data); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got.Address, tt.want.Address) || !got.Value().Equal(tt.want.Value()) {
+				t.Errorf("got %+v want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddSignatures(t *testing.T) {
+	inputKey := crypto.NewExtendedSigningKey([]byte("input key"), "")
+	signerKey := crypto.NewExtendedSigningKey([]byte("signer key"), "")
+	inputXvk, signerXvk := inputKey.ExtendedVerificationKey(), signerKey.ExtendedVerificationKey()
+	inputs := []TransactionInput{{ID: make([]byte, 32), Index: 0}, {ID: make([]byte, 32), Index: 1}}
+
+	tests := []struct {
+		name    string
+		signers [][]byte
+		keys    []crypto.ExtendedSigningKey
+		wantLen int
+		wantErr bool
+	}{
+		{
+			name:    "one key owning both inputs",
+			keys:    []crypto.ExtendedSigningKey{inputKey, inputKey},
+			wantLen: 1,
+		},
+		{
+			name:    "required signer owning an input",
+			signers: [][]byte{keyHash(inputXvk)},
+			keys:    []crypto.ExtendedSigningKey{inputKey},
+			wantLen: 1,
+		},
+		{
+			name:    "required signer without inputs",
+			signers: [][]byte{keyHash(signerXvk)},
+			keys:    []crypto.ExtendedSigningKey{inputKey, signerKey},
+			wantLen: 2,
+		},
+		{
+			name:    "missing required signer",
+			signers: [][]byte{keyHash(signerXvk)},
+			keys:    []crypto.ExtendedSigningKey{inputKey},
+			wantErr: true,
+		},
+		{
+			name:    "too many keys",
+			keys:    []crypto.ExtendedSigningKey{inputKey, signerKey, crypto.NewExtendedSigningKey([]byte("other key"), "")},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := TransactionBody{Inputs: inputs, RequiredSigners: tt.signers}
+			publicKeys, signatures := [][]byte{}, [][]byte{}
+			for _, key := range tt.keys {
+				publicKeys = append(publicKeys, key.ExtendedVerificationKey()[:32])
+				signatures = append(signatures, key.Sign(body.Bytes()))
+			}
+			tx, err := body.AddSignatures(publicKeys, signatures)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v want error %v", err, tt.wantErr)
+			}
+			if err == nil && len(tx.WitnessSet.VKeyWitnessSet) != tt.wantLen {
+				t.Errorf("got %v witnesses want %v", len(tx.WitnessSet.VKeyWitnessSet), tt.wantLen)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package cardano
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const txTrackerPollInterval = 20 * time.Second
+
+type TxTrackerOption func(*TxTracker)
+
+// WithTxTrackerPollInterval sets how often the backend is polled, 20 seconds
+// by default.
+func WithTxTrackerPollInterval(interval time.Duration) TxTrackerOption {
+	return func(t *TxTracker) {
+		t.pollInterval = interval
+	}
+}
+
+// WithTxTrackerTimeout sets how long Wait waits for the confirmations before
+// returning context.DeadlineExceeded. There is no timeout by default.
+func WithTxTrackerTimeout(timeout time.Duration) TxTrackerOption {
+	return func(t *TxTracker) {
+		t.timeout = timeout
+	}
+}
+
+// TxTracker waits for submitted transactions to be confirmed by polling a
+// ChainBackend.
+type TxTracker struct {
+	node         ChainBackend
+	pollInterval time.Duration
+	timeout      time.Duration
+}
+
+// NewTxTracker creates a TxTracker polling the backend.
+func NewTxTracker(node ChainBackend, opts ...TxTrackerOption) *TxTracker {
+	t := &TxTracker{node: node, pollInterval: txTrackerPollInterval}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// Wait waits until the transaction is included in the chain and followed by
+// confirmations-1 blocks, and returns its status. It returns a TxExpiredError
+// once the tip of the chain passed the TTL of the transaction without it being
+// included, in which case its inputs can be spent again safely.
+//
+// Backends that can't look up transactions are supported by watching the
+// outputs of the transaction, its block being the tip at which they were
+// first seen. As the outputs may be spent before being seen, the transaction
+// is only reported expired if its inputs are still unspent, and a
+// TxInputsSpentError is returned otherwise.
+func (t *TxTracker) Wait(ctx context.Context, tx *Transaction, confirmations uint64) (TxStatus, error) {
+	if t.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.timeout)
+		defer cancel()
+	}
+	if confirmations == 0 {
+		confirmations = 1
+	}
+
+	txId := tx.ID()
+	seen := TxStatus{}
+	lookup := true
+	for {
+		// The tip is queried first so that a transaction not included at a tip
+		// past its TTL can't be included anymore.
+		tip, err := t.node.QueryTip(ctx)
+		if err != nil {
+			return TxStatus{}, err
+		}
+		status, err := t.node.QueryTxStatus(ctx, txId)
+		if errors.Is(err, ErrNotSupported) {
+			lookup = false
+			status, err = t.outputsStatus(ctx, tx, txId, tip, seen)
+			seen = status
+		}
+		if err != nil {
+			return TxStatus{}, err
+		}
+
+		switch {
+		case status.Confirmed && tip.Block >= status.Block && tip.Block-status.Block+1 >= confirmations:
+			return status, nil
+		case !status.Confirmed && tx.Body.Ttl != 0 && tip.Slot >= tx.Body.Ttl:
+			if !lookup {
+				unspent, err := t.inputsUnspent(ctx, tx)
+				if err != nil {
+					return TxStatus{}, err
+				}
+				if !unspent {
+					return TxStatus{}, &TxInputsSpentError{ID: txId, Slot: tip.Slot}
+				}
+			}
+			return TxStatus{}, &TxExpiredError{ID: txId, TTL: tx.Body.Ttl, Slot: tip.Slot}
+		}
+
+		if err := sleepContext(ctx, t.pollInterval); err != nil {
+			return TxStatus{}, err
+		}
+	}
+}
+
+// outputsStatus looks for the outputs of the transaction in the UTxO set. The
+// transaction stays confirmed once seen, as spent outputs can't be told apart
+// from rolled back ones.
+func (t *TxTracker) outputsStatus(ctx context.Context, tx *Transaction, txId TransactionID, tip NodeTip, seen TxStatus) (TxStatus, error) {
+	if seen.Confirmed {
+		return seen, nil
+	}
+	if len(tx.Body.Outputs) == 0 {
+		return TxStatus{}, ErrNotSupported
+	}
+	queried := map[Address]bool{}
+	for _, output := range tx.Body.Outputs {
+		if len(output.Address) == 0 {
+			continue
+		}
+		addr, err := BytesToAddress(output.Address, Network(output.Address[0]&0x0F))
+		if err != nil {
+			return TxStatus{}, err
+		}
+		if queried[addr] {
+			continue
+		}
+		queried[addr] = true
+		utxos, err := t.node.QueryUtxos(ctx, addr)
+		if err != nil {
+			return TxStatus{}, err
+		}
+		for _, utxo := range utxos {
+			if utxo.TxId == txId {
+				return TxStatus{Confirmed: true, Block: tip.Block, Slot: tip.Slot}, nil
+			}
+		}
+	}
+	return TxStatus{}, nil
+}
+
+// inputsUnspent returns whether every input of the transaction is still in the
+// UTxO set. The inputs are looked up at the enterprise addresses of the keys
+// witnessing the transaction, and at their base addresses staked with the
+// stake keys of the outputs, where a wallet receives its change. An input
+// found at none of them is deemed spent.
+func (t *TxTracker) inputsUnspent(ctx context.Context, tx *Transaction) (bool, error) {
+	network := Testnet
+	for _, output := range tx.Body.Outputs {
+		if len(output.Address) > 0 {
+			network = Network(output.Address[0] & 0x0F)
+			break
+		}
+	}
+	stakeHashes := map[string][]byte{}
+	for _, output := range tx.Body.Outputs {
+		// Base addresses whose stake credential is a key hash
+		if len(output.Address) == 1+2*addressHashSize && output.Address[0]>>4 <= 1 {
+			stakeHash := output.Address[1+addressHashSize:]
+			stakeHashes[string(stakeHash)] = stakeHash
+		}
+	}
+
+	unspent := map[string]bool{}
+	for _, witness := range tx.WitnessSet.VKeyWitnessSet {
+		addresses := []Address{newAddress(enterpriseKeyHeader, network, keyHash(witness.VKey))}
+		for _, stakeHash := range stakeHashes {
+			addresses = append(addresses, newAddress(baseKeyKeyHeader, network, keyHash(witness.VKey), stakeHash))
+		}
+		for _, addr := range addresses {
+			utxos, err := t.node.QueryUtxos(ctx, addr)
+			if err != nil {
+				return false, err
+			}
+			for _, utxo := range utxos {
+				unspent[utxoKey(utxo.TxId.Bytes(), utxo.Index)] = true
+			}
+		}
+	}
+	for _, input := range tx.Body.Inputs {
+		if !unspent[utxoKey(input.ID, input.Index)] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,161 @@
+package cardano
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/qredo/cardano-go/crypto"
+)
+
+// trackerTestNode is an Emulator whose chain moves forward by a block and 20
+// slots every time its tip is queried.
+type trackerTestNode struct {
+	*Emulator
+	noStatus bool
+	blocks   uint64
+}
+
+func (n *trackerTestNode) QueryTip(ctx context.Context) (NodeTip, error) {
+	n.AdvanceSlots(20)
+	n.blocks++
+	tip, err := n.Emulator.QueryTip(ctx)
+	tip.Block += n.blocks
+	return tip, err
+}
+
+func (n *trackerTestNode) QueryTxStatus(ctx context.Context, txId TransactionID) (TxStatus, error) {
+	if n.noStatus {
+		return TxStatus{}, ErrNotSupported
+	}
+	return n.Emulator.QueryTxStatus(ctx, txId)
+}
+
+func TestTxTrackerWait(t *testing.T) {
+	key := crypto.NewExtendedSigningKey([]byte("sender"), "")
+	stakeKey := crypto.NewExtendedSigningKey([]byte("sender stake"), "")
+	sender := NewBaseAddress(key.ExtendedVerificationKey(), stakeKey.ExtendedVerificationKey(), Testnet)
+
+	tests := []struct {
+		name       string
+		noStatus   bool
+		submit     bool
+		conflict   bool
+		ttl        uint64
+		timeout    time.Duration
+		cancel     bool
+		want       TxStatus
+		wantErr    error
+		wantExpiry bool
+		wantSpent  bool
+	}{
+		{
+			name:   "confirmed",
+			submit: true,
+			want:   TxStatus{Confirmed: true, Block: 1},
+		},
+		{
+			name:     "confirmed without tx lookup",
+			noStatus: true,
+			submit:   true,
+			want:     TxStatus{Confirmed: true, Block: 2, Slot: 20},
+		},
+		{
+			name:       "expired",
+			ttl:        50,
+			wantExpiry: true,
+		},
+		{
+			name:       "expired without tx lookup",
+			noStatus:   true,
+			ttl:        50,
+			wantExpiry: true,
+		},
+		{
+			name:      "inputs spent without tx lookup",
+			noStatus:  true,
+			conflict:  true,
+			ttl:       50,
+			wantSpent: true,
+		},
+		{
+			name:    "timeout",
+			timeout: 20 * time.Millisecond,
+			wantErr: context.DeadlineExceeded,
+		},
+		{
+			name:    "canceled",
+			cancel:  true,
+			wantErr: context.Canceled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			emulator := NewEmulator(ShelleyProtocol, map[Address]uint64{sender: 5 * ShelleyProtocol.MinimumUtxoValue})
+			node := &trackerTestNode{Emulator: emulator, noStatus: tt.noStatus}
+
+			utxos, err := emulator.QueryUtxos(context.Background(), sender)
+			if err != nil {
+				t.Fatal(err)
+			}
+			build := func(amount uint64) Transaction {
+				builder := NewTxBuilder(ShelleyProtocol)
+				builder.AddInput(key.ExtendedVerificationKey(), utxos[0].TxId, utxos[0].Index, utxos[0].Amount)
+				builder.AddOutput(sender, amount)
+				builder.SetTtl(tt.ttl)
+				if err := builder.AddFee(sender); err != nil {
+					t.Fatal(err)
+				}
+				builder.Sign(key)
+				return builder.Build()
+			}
+			tx := build(ShelleyProtocol.MinimumUtxoValue)
+			if tt.conflict {
+				if _, err := emulator.SubmitTx(context.Background(), build(2*ShelleyProtocol.MinimumUtxoValue)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.submit {
+				if _, err := emulator.SubmitTx(context.Background(), tx); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancel {
+				cancel()
+			}
+			tracker := NewTxTracker(node, WithTxTrackerPollInterval(time.Millisecond), WithTxTrackerTimeout(tt.timeout))
+			status, err := tracker.Wait(ctx, &tx, 3)
+
+			if tt.wantSpent {
+				var spent *TxInputsSpentError
+				if !errors.As(err, &spent) || spent.ID != tx.ID() {
+					t.Fatalf("got error %v want %T", err, spent)
+				}
+				return
+			}
+			if tt.wantExpiry {
+				var expired *TxExpiredError
+				if !errors.As(err, &expired) || expired.ID != tx.ID() || expired.Slot < tt.ttl {
+					t.Fatalf("got error %v want %T", err, expired)
+				}
+				return
+			}
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got error %v want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := status, tt.want; got != want {
+				t.Errorf("got status %+v want %+v", got, want)
+			}
+		})
+	}
+}
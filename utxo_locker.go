@@ -0,0 +1,197 @@
+package cardano
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+)
+
+// ErrUtxoLocked is returned by UtxoLocker.Reserve when an input is already
+// reserved by another transaction.
+var ErrUtxoLocked = errors.New("utxo locked by a pending transaction")
+
+// UtxoLockStore persists the reservations of UtxoLockers.
+type UtxoLockStore interface {
+	UtxoLocks(walletID string) ([]byte, error)
+	SaveUtxoLocks(walletID string, locks []byte) error
+}
+
+// utxoReservation holds the inputs spent by a pending transaction and the
+// outputs it pays back to the wallet.
+type utxoReservation struct {
+	TxID   TransactionID
+	Inputs []TransactionInput
+	Change []Utxo
+	TTL    uint64
+}
+
+// UtxoLocker keeps track of the outputs of a wallet spent by submitted but
+// unconfirmed transactions, so that concurrent transfers don't select them.
+//
+// A reservation lasts until its inputs are gone from the UTxO set, which
+// means the transaction was included, or until the tip passes the
+// transaction's TTL. It's safe for concurrent use.
+type UtxoLocker struct {
+	walletID string
+	store    UtxoLockStore
+
+	mu           sync.Mutex
+	reservations []utxoReservation
+	chaining     bool
+}
+
+// NewUtxoLocker creates the UtxoLocker of a wallet persisting its
+// reservations in store.
+func NewUtxoLocker(walletID string, store UtxoLockStore) (*UtxoLocker, error) {
+	l := &UtxoLocker{walletID: walletID, store: store}
+	data, err := store.UtxoLocks(walletID)
+	if err != nil {
+		return nil, err
+	}
+	if data != nil {
+		if err := json.Unmarshal(data, &l.reservations); err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// SetChaining sets whether the change outputs of pending transactions can be
+// spent, chaining transactions before they are confirmed. It's disabled by
+// default.
+func (l *UtxoLocker) SetChaining(enabled bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.chaining = enabled
+}
+
+// Available returns the outputs of utxos, the UTxO set of the wallet, that
+// are not reserved, followed by the change outputs of pending transactions if
+// chaining is enabled. Reservations confirmed or expired at the slot are
+// dropped.
+func (l *UtxoLocker) Available(utxos []Utxo, slot uint64) ([]Utxo, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	unspent := map[string]bool{}
+	for _, utxo := range utxos {
+		unspent[utxoKey(utxo.TxId.Bytes(), utxo.Index)] = true
+	}
+	pendingChange := map[string]bool{}
+	for _, reservation := range l.reservations {
+		for _, utxo := range reservation.Change {
+			pendingChange[utxoKey(utxo.TxId.Bytes(), utxo.Index)] = true
+		}
+	}
+
+	pending := l.reservations[:0]
+	for _, reservation := range l.reservations {
+		if reservation.TTL != 0 && slot >= reservation.TTL {
+			continue
+		}
+		confirmed := true
+		for _, input := range reservation.Inputs {
+			key := utxoKey(input.ID, input.Index)
+			if unspent[key] || pendingChange[key] {
+				confirmed = false
+				break
+			}
+		}
+		if !confirmed {
+			pending = append(pending, reservation)
+		}
+	}
+	pruned := len(pending) != len(l.reservations)
+	l.reservations = pending
+	if pruned {
+		if err := l.save(); err != nil {
+			return nil, err
+		}
+	}
+
+	locked := l.locked()
+	available := []Utxo{}
+	for _, utxo := range utxos {
+		if !locked[utxoKey(utxo.TxId.Bytes(), utxo.Index)] {
+			available = append(available, utxo)
+		}
+	}
+	if l.chaining {
+		for _, reservation := range l.reservations {
+			for _, utxo := range reservation.Change {
+				key := utxoKey(utxo.TxId.Bytes(), utxo.Index)
+				if !locked[key] && !unspent[key] {
+					available = append(available, utxo)
+				}
+			}
+		}
+	}
+	return available, nil
+}
+
+// Reserve locks the inputs of the transaction until it's confirmed or
+// expired, change being the outputs it pays to the wallet. It returns
+// ErrUtxoLocked if an input is already reserved.
+func (l *UtxoLocker) Reserve(tx *Transaction, change []Utxo) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	locked := l.locked()
+	for _, input := range tx.Body.Inputs {
+		if locked[utxoKey(input.ID, input.Index)] {
+			return ErrUtxoLocked
+		}
+	}
+	l.reservations = append(l.reservations, utxoReservation{
+		TxID:   tx.ID(),
+		Inputs: tx.Body.Inputs,
+		Change: change,
+		TTL:    tx.Body.Ttl,
+	})
+	return l.save()
+}
+
+// Release drops the reservation of a transaction, after it failed to be
+// submitted.
+func (l *UtxoLocker) Release(txId TransactionID) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for i, reservation := range l.reservations {
+		if reservation.TxID == txId {
+			l.reservations = append(l.reservations[:i], l.reservations[i+1:]...)
+			return l.save()
+		}
+	}
+	return nil
+}
+
+// Pending returns the ids of the transactions holding reservations.
+func (l *UtxoLocker) Pending() []TransactionID {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ids := make([]TransactionID, len(l.reservations))
+	for i, reservation := range l.reservations {
+		ids[i] = reservation.TxID
+	}
+	return ids
+}
+
+func (l *UtxoLocker) locked() map[string]bool {
+	locked := map[string]bool{}
+	for _, reservation := range l.reservations {
+		for _, input := range reservation.Inputs {
+			locked[utxoKey(input.ID, input.Index)] = true
+		}
+	}
+	return locked
+}
+
+func (l *UtxoLocker) save() error {
+	data, err := json.Marshal(l.reservations)
+	if err != nil {
+		return err
+	}
+	return l.store.SaveUtxoLocks(l.walletID, data)
+}
@@ -0,0 +1,248 @@
+package cardano
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/qredo/cardano-go/crypto"
+)
+
+func testLockerUtxo(seed byte, index uint64, amount uint64) Utxo {
+	return Utxo{TxId: TransactionID(hex.EncodeToString(append(make([]byte, 31), seed))), Index: index, Amount: amount}
+}
+
+func testLockerTx(ttl uint64, inputs ...Utxo) *Transaction {
+	tx := &Transaction{Body: TransactionBody{Ttl: ttl}}
+	for _, input := range inputs {
+		tx.Body.Inputs = append(tx.Body.Inputs, TransactionInput{ID: input.TxId.Bytes(), Index: input.Index})
+	}
+	return tx
+}
+
+func TestUtxoLocker(t *testing.T) {
+	u0, u1 := testLockerUtxo(1, 0, 10), testLockerUtxo(2, 0, 20)
+	txA := testLockerTx(100, u0)
+	changeA := Utxo{TxId: txA.ID(), Index: 1, Amount: 5}
+	txB := testLockerTx(100, changeA)
+
+	tests := []struct {
+		name     string
+		chaining bool
+		reserve  []*Transaction
+		utxos    []Utxo
+		slot     uint64
+		want     []Utxo
+		wantErr  error
+		pending  int
+	}{
+		{
+			name:    "reserved inputs excluded",
+			reserve: []*Transaction{txA},
+			utxos:   []Utxo{u0, u1},
+			want:    []Utxo{u1},
+			pending: 1,
+		},
+		{
+			name:    "double reservation",
+			reserve: []*Transaction{txA, testLockerTx(100, u0, u1)},
+			wantErr: ErrUtxoLocked,
+		},
+		{
+			name:     "chaining",
+			chaining: true,
+			reserve:  []*Transaction{txA},
+			utxos:    []Utxo{u0, u1},
+			want:     []Utxo{u1, changeA},
+			pending:  1,
+		},
+		{
+			name:     "chained reservation",
+			chaining: true,
+			reserve:  []*Transaction{txA, txB},
+			utxos:    []Utxo{u0, u1},
+			want:     []Utxo{u1},
+			pending:  2,
+		},
+		{
+			name:    "confirmed",
+			reserve: []*Transaction{txA},
+			utxos:   []Utxo{u1, changeA},
+			want:    []Utxo{u1, changeA},
+		},
+		{
+			name:     "confirmed with chained pending",
+			chaining: true,
+			reserve:  []*Transaction{txA, txB},
+			utxos:    []Utxo{u1, changeA},
+			want:     []Utxo{u1},
+			pending:  1,
+		},
+		{
+			name:    "expired",
+			reserve: []*Transaction{txA},
+			utxos:   []Utxo{u0, u1},
+			slot:    100,
+			want:    []Utxo{u0, u1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			locker, err := NewUtxoLocker("wallet", &memoryUtxoLocks{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			locker.SetChaining(tt.chaining)
+			for _, tx := range tt.reserve {
+				var change []Utxo
+				if tx == txA {
+					change = []Utxo{changeA}
+				}
+				if err = locker.Reserve(tx, change); err != nil {
+					break
+				}
+			}
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("got error %v want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := locker.Available(tt.utxos, tt.slot)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got available %v want %v", got, tt.want)
+			}
+			if got, want := len(locker.Pending()), tt.pending; got != want {
+				t.Errorf("got %v pending want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestUtxoLockerPersistence(t *testing.T) {
+	u0, u1 := testLockerUtxo(1, 0, 10), testLockerUtxo(2, 0, 20)
+	store := &memoryUtxoLocks{}
+	locker, err := NewUtxoLocker("wallet", store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := testLockerTx(100, u0)
+	if err := locker.Reserve(tx, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := NewUtxoLocker("wallet", store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := restored.Available([]Utxo{u0, u1}, 0); err != nil || !reflect.DeepEqual(got, []Utxo{u1}) {
+		t.Errorf("got available %v, %v want %v", got, err, []Utxo{u1})
+	}
+
+	if err := restored.Release(tx.ID()); err != nil {
+		t.Fatal(err)
+	}
+	if got := restored.Pending(); len(got) != 0 {
+		t.Errorf("got pending %v want none", got)
+	}
+}
+
+// recordingBackend is a fakeBackend recording the submitted transactions
+// without applying them.
+type recordingBackend struct {
+	*fakeBackend
+	mu  sync.Mutex
+	txs []Transaction
+}
+
+func (r *recordingBackend) SubmitTx(ctx context.Context, tx Transaction) (TransactionID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.txs = append(r.txs, tx)
+	return tx.ID(), nil
+}
+
+func TestWalletConcurrentTransfers(t *testing.T) {
+	client := NewClient(WithDB(&MockDB{}))
+	defer client.Close()
+	w, _, err := client.CreateWallet("test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.SetNetwork(Testnet)
+	addr := w.Addresses()[0]
+
+	amount := 10 * ShelleyProtocol.MinimumUtxoValue
+	backend := &recordingBackend{fakeBackend: &fakeBackend{
+		tip: NodeTip{Slot: 1000},
+		utxos: []Utxo{
+			{Address: addr, TxId: testLockerUtxo(1, 0, 0).TxId, Amount: 2 * amount},
+			{Address: addr, TxId: testLockerUtxo(2, 0, 0).TxId, Amount: 2 * amount},
+		},
+	}}
+	w.node = backend
+
+	receiverKey := crypto.NewExtendedSigningKey([]byte("receiver"), "")
+	receiver := NewEnterpriseAddress(receiverKey.ExtendedVerificationKey(), Testnet)
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = w.Transfer(receiver, amount)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got, want := len(backend.txs), 2; got != want {
+		t.Fatalf("got %v transactions want %v", got, want)
+	}
+	spent := map[string]bool{}
+	for _, tx := range backend.txs {
+		for _, input := range tx.Body.Inputs {
+			key := utxoKey(input.ID, input.Index)
+			if spent[key] {
+				t.Errorf("input %v spent twice", key)
+			}
+			spent[key] = true
+		}
+	}
+}
+
+// memoryUtxoLocks is a UtxoLockStore that keeps the reservations in memory.
+type memoryUtxoLocks struct {
+	mu    sync.Mutex
+	locks map[string][]byte
+}
+
+func (m *memoryUtxoLocks) UtxoLocks(walletID string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.locks[walletID], nil
+}
+
+func (m *memoryUtxoLocks) SaveUtxoLocks(walletID string, locks []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.locks == nil {
+		m.locks = map[string][]byte{}
+	}
+	m.locks[walletID] = locks
+	return nil
+}
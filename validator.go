@@ -0,0 +1,237 @@
+package cardano
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/echovl/ed25519"
+	"golang.org/x/crypto/blake2b"
+)
+
+// ValidationErrors holds every failure found while validating a transaction.
+// errors.As and errors.Is match any of them.
+type ValidationErrors []error
+
+func (errs ValidationErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return "invalid transaction: " + strings.Join(msgs, "; ")
+}
+
+func (errs ValidationErrors) As(target interface{}) bool {
+	for _, err := range errs {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (errs ValidationErrors) Is(target error) bool {
+	for _, err := range errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateTx runs the phase-1 ledger checks of a transaction against the UTxO
+// set it spends from, the protocol parameters and the current slot. It
+// returns nil or ValidationErrors.
+func ValidateTx(tx *Transaction, utxos []Utxo, protocol ProtocolParams, slot uint64) error {
+	var errs ValidationErrors
+
+	body := &tx.Body
+	spent, err := resolveInputs(body.Inputs, utxos)
+	if err != nil {
+		return ValidationErrors{err}
+	}
+
+	if err := validateValue(body, spent, protocol); err != nil {
+		errs = append(errs, err)
+	}
+
+	minFee := CalculateFee(tx, protocol) + scriptFee(tx.WitnessSet.Redeemers, protocol)
+	if body.Fee < minFee {
+		errs = append(errs, &FeeTooSmallError{Fee: body.Fee, MinFee: minFee})
+	}
+
+	size := uint64(len(tx.Bytes()))
+	if protocol.MaxTxSize > 0 && size > protocol.MaxTxSize {
+		errs = append(errs, &MaxTxSizeExceededError{Size: size, MaxSize: protocol.MaxTxSize})
+	}
+
+	for i, output := range body.Outputs {
+		if len(output.Address) < 1+addressHashSize {
+			errs = append(errs, &MalformedOutputAddressError{Index: i, Address: output.Address})
+		}
+		minAmount := minUtxoValue(output, protocol)
+		if output.Amount < minAmount {
+			errs = append(errs, &OutputTooSmallError{Index: i, Amount: output.Amount, MinAmount: minAmount})
+		}
+	}
+
+	if slot < body.ValidityIntervalStart || (body.Ttl > 0 && slot >= body.Ttl) {
+		errs = append(errs, &OutsideValidityIntervalError{Slot: slot, ValidFrom: body.ValidityIntervalStart, TTL: body.Ttl})
+	}
+
+	errs = append(errs, validateWitnesses(tx, spent)...)
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func resolveInputs(inputs []TransactionInput, utxos []Utxo) ([]Utxo, error) {
+	set := make(map[string]Utxo, len(utxos))
+	for _, utxo := range utxos {
+		set[utxoKey(utxo.TxId.Bytes(), utxo.Index)] = utxo
+	}
+
+	var spent []Utxo
+	var bad []TransactionInput
+	for _, input := range inputs {
+		utxo, ok := set[utxoKey(input.ID, input.Index)]
+		if !ok {
+			bad = append(bad, input)
+			continue
+		}
+		spent = append(spent, utxo)
+	}
+	if len(bad) > 0 {
+		return nil, &BadInputsError{Inputs: bad}
+	}
+	return spent, nil
+}
+
+// validateValue checks that inputs + withdrawals + refunds = outputs + fee + deposits.
+func validateValue(body *TransactionBody, spent []Utxo, protocol ProtocolParams) error {
+	var consumed, produced Value
+	for _, utxo := range spent {
+		consumed = consumed.Add(Value{Coin: utxo.Amount, Assets: utxo.Assets})
+	}
+	for _, amount := range body.Withdrawals {
+		consumed.Coin += amount
+	}
+	for _, output := range body.Outputs {
+		produced = produced.Add(output.Value())
+	}
+	produced.Coin += body.Fee
+
+	for _, cert := range body.Certificates {
+		switch cert.Type {
+		case StakeRegistration:
+			produced.Coin += protocol.KeyDeposit
+		case StakeDeregistration:
+			consumed.Coin += protocol.KeyDeposit
+		}
+	}
+
+	if !consumed.Equal(produced) {
+		return &ValueNotConservedError{Consumed: consumed, Produced: produced}
+	}
+	return nil
+}
+
+func validateWitnesses(tx *Transaction, spent []Utxo) []error {
+	var errs []error
+
+	txHash := blake2b.Sum256(tx.Body.Bytes())
+	witnessed := map[string]bool{}
+	for _, witness := range tx.WitnessSet.VKeyWitnessSet {
+		if len(witness.VKey) != ed25519.PublicKeySize || !ed25519.Verify(witness.VKey, txHash[:], witness.Signature) {
+			errs = append(errs, &InvalidSignatureError{VKey: witness.VKey})
+			continue
+		}
+		witnessed[hex.EncodeToString(keyHash(witness.VKey))] = true
+	}
+
+	var missing [][]byte
+	for _, hash := range requiredKeyHashes(&tx.Body, spent) {
+		if !witnessed[hex.EncodeToString(hash)] {
+			missing = append(missing, hash)
+		}
+	}
+	if len(missing) > 0 {
+		errs = append(errs, &MissingVKeyWitnessesError{KeyHashes: missing})
+	}
+	return errs
+}
+
+// requiredKeyHashes returns the key hashes that must witness the transaction:
+// owners of the spent inputs, withdrawals and certificates along with the
+// required signers.
+func requiredKeyHashes(body *TransactionBody, spent []Utxo) [][]byte {
+	var hashes [][]byte
+	add := func(hash []byte) {
+		for _, h := range hashes {
+			if bytes.Equal(h, hash) {
+				return
+			}
+		}
+		hashes = append(hashes, hash)
+	}
+
+	for _, utxo := range spent {
+		if hash, ok := paymentKeyHash(utxo.Address.Bytes()); ok {
+			add(hash)
+		}
+	}
+	for addr := range body.Withdrawals {
+		if addrBytes, err := hex.DecodeString(addr); err == nil {
+			if hash, ok := paymentKeyHash(addrBytes); ok {
+				add(hash)
+			}
+		}
+	}
+	for _, cert := range body.Certificates {
+		if cert.Type != StakeRegistration && cert.StakeCredential.Type == KeyStakeCredential {
+			add(cert.StakeCredential.Hash)
+		}
+	}
+	for _, signer := range body.RequiredSigners {
+		add(signer)
+	}
+	return hashes
+}
+
+// paymentKeyHash returns the key hash of the first credential of a shelley
+// address if that credential is a key.
+func paymentKeyHash(addr []byte) ([]byte, bool) {
+	if len(addr) < 29 {
+		return nil, false
+	}
+	addrType := addr[0] >> 4
+	isKey := addrType&0x1 == 0
+	switch {
+	case addrType <= 7 && isKey, addrType == 14:
+		return addr[1:29], true
+	}
+	return nil, false
+}
+
+// minUtxoValue returns the minimum amount of lovelace an output must hold.
+func minUtxoValue(output TransactionOutput, protocol ProtocolParams) uint64 {
+	minValue := protocol.MinimumUtxoValue
+	if protocol.CoinsPerUTxOByte > 0 {
+		outputBytes, err := output.MarshalCBOR()
+		if err != nil {
+			panic(err)
+		}
+		if value := (160 + uint64(len(outputBytes))) * protocol.CoinsPerUTxOByte; value > minValue {
+			minValue = value
+		}
+	}
+	return minValue
+}
+
+func utxoKey(txId []byte, index uint64) string {
+	return fmt.Sprintf("%x#%v", txId, index)
+}
@@ -0,0 +1,149 @@
+package cardano
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/qredo/cardano-go/crypto"
+)
+
+func TestValidateTx(t *testing.T) {
+	key := crypto.NewExtendedSigningKey([]byte("sender"), "foo")
+	sender := NewEnterpriseAddress(key.ExtendedVerificationKey(), Testnet)
+	other := crypto.NewExtendedSigningKey([]byte("other"), "foo")
+	receiver := NewEnterpriseAddress(other.ExtendedVerificationKey(), Testnet)
+
+	protocol := ShelleyProtocol
+	protocol.MaxTxSize = 16384
+	txId := TransactionID(hex.EncodeToString(make([]byte, 32)))
+	utxos := []Utxo{{Address: sender, TxId: txId, Index: 0, Amount: 10 * protocol.MinimumUtxoValue}}
+
+	newTx := func(modify func(builder *TXBuilder)) *Transaction {
+		builder := NewTxBuilder(protocol)
+		builder.AddInput(key.ExtendedVerificationKey(), txId, 0, utxos[0].Amount)
+		builder.AddOutput(receiver, 2*protocol.MinimumUtxoValue)
+		builder.SetTtl(1000)
+		if modify != nil {
+			modify(builder)
+		}
+		if err := builder.AddFee(sender); err != nil {
+			t.Fatal(err)
+		}
+		builder.Sign(key)
+		tx := builder.Build()
+		return &tx
+	}
+
+	tests := []struct {
+		name      string
+		tx        *Transaction
+		slot      uint64
+		maxTxSize uint64
+		wantErr   interface{}
+	}{
+		{
+			name: "valid",
+			tx:   newTx(nil),
+			slot: 500,
+		},
+		{
+			name:    "bad inputs",
+			tx:      newTx(func(b *TXBuilder) { b.AddInputWithoutSig(txId, 1, 0) }),
+			slot:    500,
+			wantErr: new(*BadInputsError),
+		},
+		{
+			name: "value not conserved",
+			tx: func() *Transaction {
+				tx := newTx(nil)
+				tx.Body.Outputs[0].Amount++
+				return tx
+			}(),
+			slot:    500,
+			wantErr: new(*ValueNotConservedError),
+		},
+		{
+			name: "fee too small",
+			tx: func() *Transaction {
+				tx := newTx(nil)
+				tx.Body.Fee -= 100
+				tx.Body.Outputs[0].Amount += 100
+				return tx
+			}(),
+			slot:    500,
+			wantErr: new(*FeeTooSmallError),
+		},
+		{
+			name:    "output too small",
+			tx:      newTx(func(b *TXBuilder) { b.AddOutput(receiver, 1) }),
+			slot:    500,
+			wantErr: new(*OutputTooSmallError),
+		},
+		{
+			name: "malformed output address",
+			tx: newTx(func(b *TXBuilder) {
+				b.outputs = append(b.outputs, TransactionOutput{Amount: protocol.MinimumUtxoValue})
+			}),
+			slot:    500,
+			wantErr: new(*MalformedOutputAddressError),
+		},
+		{
+			name:    "expired",
+			tx:      newTx(nil),
+			slot:    1000,
+			wantErr: new(*OutsideValidityIntervalError),
+		},
+		{
+			name: "missing witness",
+			tx: func() *Transaction {
+				tx := newTx(nil)
+				tx.WitnessSet.VKeyWitnessSet = nil
+				return tx
+			}(),
+			slot:    500,
+			wantErr: new(*MissingVKeyWitnessesError),
+		},
+		{
+			name:    "missing required signer",
+			tx:      newTx(func(b *TXBuilder) { b.signers = append(b.signers, keyHash(other.ExtendedVerificationKey())) }),
+			slot:    500,
+			wantErr: new(*MissingVKeyWitnessesError),
+		},
+		{
+			name: "invalid signature",
+			tx: func() *Transaction {
+				tx := newTx(nil)
+				tx.WitnessSet.VKeyWitnessSet[0].Signature = other.Sign(tx.Body.Bytes())
+				return tx
+			}(),
+			slot:    500,
+			wantErr: new(*InvalidSignatureError),
+		},
+		{
+			name:      "max tx size exceeded",
+			tx:        newTx(nil),
+			slot:      500,
+			maxTxSize: 200,
+			wantErr:   new(*MaxTxSizeExceededError),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			protocol := protocol
+			if tt.maxTxSize > 0 {
+				protocol.MaxTxSize = tt.maxTxSize
+			}
+			err := ValidateTx(tt.tx, utxos, protocol, tt.slot)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("ValidateTx() error = %v", err)
+				}
+				return
+			}
+			if !errors.As(err, tt.wantErr) {
+				t.Errorf("ValidateTx() error = %v, want %T", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,9 +1,13 @@
 package cardano
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
+	"sync"
+	"time"
 
 	gonanoid "github.com/matoous/go-nanoid/v2"
 	"github.com/qredo/cardano-go/crypto"
@@ -16,100 +20,454 @@ const (
 	coinTypeIndex      uint32 = 1815 + 0x80000000
 	accountIndex       uint32 = 0x80000000
 	externalChainIndex uint32 = 0x0
+	internalChainIndex uint32 = 0x1
+	stakingChainIndex  uint32 = 0x2
 	walleIDAlphabet           = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	// accountKeyHrp is the bech32 prefix of account extended verification
+	// keys, as defined by CIP-5.
+	accountKeyHrp = "acct_xvk"
+	// transferAttempts is how many times a transfer is built again when its
+	// inputs got reserved by a concurrent one.
+	transferAttempts = 3
+	// defaultSigningTimeout is how long transfers wait for the signatures of
+	// their transaction, unless set otherwise by SetSigningTimeout.
+	defaultSigningTimeout = 5 * time.Minute
+	// transferAllFeeRounds is how many times TransferAll computes its fee
+	// again before only accepting a larger one.
+	transferAllFeeRounds = 4
+)
+
+var (
+	// ErrKeyNotDerived is returned when a wallet saved by an older version
+	// lacks the key of an operation. Restoring it from its mnemonic derives it.
+	ErrKeyNotDerived = errors.New("key not derived by the wallet, restore it from its mnemonic")
+	// ErrWatchOnly is returned when a watch-only wallet is asked to derive an
+	// account, or to sign a transaction without a signer set by SetSigner.
+	ErrWatchOnly = errors.New("watch-only wallet holds no signing key")
+	// ErrNetworkMismatch is returned when a wallet is restored on a network
+	// other than the one of the Client's backend.
+	ErrNetworkMismatch = errors.New("wallet network doesn't match the backend network")
 )
 
 type Wallet struct {
-	ID      string
-	Name    string
-	skeys   []crypto.ExtendedSigningKey
+	ID   string
+	Name string
+	// Account is the first account of the wallet, whose methods are promoted
+	// to the Wallet.
+	*Account
 	pkeys   []crypto.ExtendedVerificationKey
-	rootKey crypto.ExtendedSigningKey
-	node    cardanoNode
+	node    ChainBackend
 	network Network
+	indexer *WalletIndexer
+	locker  *UtxoLocker
+	signer  Signer
+	// signingTimeout overrides defaultSigningTimeout if positive.
+	signingTimeout time.Duration
+	// save runs an update of the wallet's keys and saves the wallet, for
+	// wallets of a Client. See Client.updateWallet.
+	save func(w *Wallet, update func()) error
+
+	// coinKey is the key of the m/1852'/1815' path, which derives the
+	// accounts. It's empty for wallets saved before accounts were introduced.
+	coinKey    crypto.ExtendedSigningKey
+	accounts   []*Account
+	accountsMu sync.Mutex
+}
+
+// Account is a CIP-1852 account of a wallet, with its own addresses, balance
+// and stake key. Its outputs are only spent by its own transfers.
+type Account struct {
+	// Index is the account index, hardened in the derivation path.
+	Index  uint32
+	wallet *Wallet
+
+	skeys   []crypto.ExtendedSigningKey
+	rootKey crypto.ExtendedSigningKey
+	// internalKey is the key of the internal chain, which derives the keys
+	// of the change addresses. It's empty for wallets saved before change
+	// addresses were introduced.
+	internalKey crypto.ExtendedSigningKey
+	changeKeys  []crypto.ExtendedSigningKey
+	stakeKey    crypto.ExtendedSigningKey
+
+	// accountXvk is the account verification key of watch-only accounts,
+	// which hold the verification keys of their addresses instead of the
+	// signing keys.
+	accountXvk   crypto.ExtendedVerificationKey
+	xvkeys       []crypto.ExtendedVerificationKey
+	changeXvkeys []crypto.ExtendedVerificationKey
+	keysMu       sync.Mutex
 }
 
 func (w *Wallet) SetNetwork(net Network) {
 	w.network = net
 }
 
-// Transfer sends an amount of lovelace to the receiver address
-//TODO: remove hardcoded protocol parameters, these parameters must be obtained using the cardano node
-func (w *Wallet) Transfer(receiver Address, amount uint64) error {
-	// Calculate if the account has enough balance
-	balance, err := w.Balance()
-	if err != nil {
-		return err
+// SetTxChaining sets whether transfers can spend the change of pending
+// transactions of the wallet, instead of waiting for their confirmation.
+func (w *Wallet) SetTxChaining(enabled bool) {
+	if w.locker != nil {
+		w.locker.SetChaining(enabled)
 	}
-	if amount > balance {
-		return fmt.Errorf("Not enough balance, %v > %v", amount, balance)
+}
+
+// SetSigner sets the signer of the wallet's transactions, instead of the keys
+// of its accounts. It lets watch-only wallets transfer with keys held by an
+// MPC protocol, an HSM or a remote signing service.
+func (w *Wallet) SetSigner(signer Signer) {
+	w.signer = signer
+}
+
+// SetSigningTimeout sets how long transfers wait for the signatures of their
+// transaction before failing, 5 minutes by default. It bounds the wait for a
+// remote signer that doesn't answer.
+func (w *Wallet) SetSigningTimeout(timeout time.Duration) {
+	w.signingTimeout = timeout
+}
+
+// Accounts returns the accounts of the wallet, ordered by index.
+func (w *Wallet) Accounts() []*Account {
+	w.accountsMu.Lock()
+	defer w.accountsMu.Unlock()
+	return append([]*Account{}, w.accounts...)
+}
+
+// AccountAt returns the account of the wallet with the given index.
+func (w *Wallet) AccountAt(index uint32) (*Account, error) {
+	w.accountsMu.Lock()
+	defer w.accountsMu.Unlock()
+	if int(index) >= len(w.accounts) {
+		return nil, fmt.Errorf("account %v not found", index)
 	}
+	return w.accounts[index], nil
+}
 
-	// Find utxos that cover the amount to transfer
-	pickedUtxos := []Utxo{}
-	utxos, err := w.findUtxos()
-	pickedUtxosAmount := uint64(0)
-	for _, utxo := range utxos {
-		if pickedUtxosAmount > amount {
-			break
-		}
-		pickedUtxos = append(pickedUtxos, utxo)
-		pickedUtxosAmount += utxo.Amount
+// AddAccount derives the next account of the wallet and adds it to the
+// wallet, which must be saved to keep it.
+func (w *Wallet) AddAccount() (*Account, error) {
+	w.accountsMu.Lock()
+	defer w.accountsMu.Unlock()
+	if w.Account.WatchOnly() {
+		return nil, ErrWatchOnly
 	}
+	if len(w.coinKey) == 0 {
+		return nil, ErrKeyNotDerived
+	}
+	account := newAccount(w, w.coinKey, uint32(len(w.accounts)))
+	w.accounts = append(w.accounts, account)
+	return account, nil
+}
 
-	builder := NewTxBuilder(ProtocolParams{
-		MinimumUtxoValue: 1000000,
-		MinFeeA:          44,
-		MinFeeB:          155381,
-	})
+// removeLastAccount drops the last account of the wallet, after discovery
+// found it unused.
+func (w *Wallet) removeLastAccount() {
+	w.accountsMu.Lock()
+	defer w.accountsMu.Unlock()
+	if len(w.accounts) > 1 {
+		w.accounts = w.accounts[:len(w.accounts)-1]
+	}
+}
 
-	keys := make(map[int]crypto.ExtendedSigningKey)
-	for i, utxo := range pickedUtxos {
-		for _, key := range w.skeys {
-			vkey := key.ExtendedVerificationKey()
-			address := NewEnterpriseAddress(vkey, w.network)
-			if address == utxo.Address {
-				keys[i] = key
+// update runs fn, which derives keys of the wallet, and saves the wallet if
+// it belongs to a Client, so that the keys are kept before being used.
+func (w *Wallet) update(fn func()) error {
+	if w.save == nil {
+		fn()
+		return nil
+	}
+	return w.save(w, fn)
+}
+
+// catchUp adds the accounts and address keys of other, another instance of
+// the wallet, that the wallet lacks.
+func (w *Wallet) catchUp(other *Wallet) {
+	others := other.Accounts()
+	w.accountsMu.Lock()
+	for i := len(w.accounts); i < len(others); i++ {
+		others[i].wallet = w
+		w.accounts = append(w.accounts, others[i])
+	}
+	accounts := append([]*Account{}, w.accounts...)
+	w.accountsMu.Unlock()
+
+	for i, account := range accounts[:len(others)] {
+		account.keysMu.Lock()
+		for _, chain := range []uint32{externalChainIndex, internalChainIndex} {
+			for account.chainLength(chain) < others[i].chainLength(chain) {
+				account.appendKey(chain)
 			}
 		}
+		account.keysMu.Unlock()
 	}
+}
 
-	if len(keys) != len(pickedUtxos) {
-		panic("not enough keys")
+// allAddresses returns the receive and change addresses of every account of
+// the wallet.
+func (w *Wallet) allAddresses() []Address {
+	addresses := []Address{}
+	for _, account := range w.Accounts() {
+		addresses = append(addresses, account.allAddresses()...)
 	}
+	return addresses
+}
 
-	for i, utxo := range pickedUtxos {
-		skey := keys[i]
-		vkey := skey.ExtendedVerificationKey()
-		builder.AddInput(vkey, utxo.TxId, utxo.Index, utxo.Amount)
+// Transfer sends an amount of lovelace to the receiver address and returns
+// the id of the submitted transaction.
+func (a *Account) Transfer(receiver Address, amount uint64) (TransactionID, error) {
+	return a.TransferContext(context.Background(), receiver, amount)
+}
+
+// TransferContext is like Transfer, with a context bounding the queries to
+// the node, the signature and the submission.
+func (a *Account) TransferContext(ctx context.Context, receiver Address, amount uint64) (TransactionID, error) {
+	changeAddress, err := a.nextChangeAddress()
+	if err != nil {
+		return "", err
 	}
-	builder.AddOutput(receiver, amount)
+	txId, err := a.submit(ctx, func(utxos []Utxo, tip NodeTip) (*Transaction, error) {
+		protocol, err := a.wallet.node.QueryProtocolParams(ctx)
+		if err != nil {
+			return nil, err
+		}
+		picked, body, err := transferBody(receiver, amount, utxos, changeAddress, tip, protocol)
+		if err != nil {
+			return nil, err
+		}
+		return a.sign(ctx, body, nil, picked, protocol)
+	})
+	if err != nil {
+		a.releaseChangeAddress(changeAddress)
+	}
+	return txId, err
+}
+
+// BuildTransfer builds the transaction of Transfer without signing nor
+// submitting it, so that the keys of a watch-only account can sign it
+// elsewhere. Its change address is kept by the account, and saved along with
+// the wallet if it belongs to a Client.
+func (a *Account) BuildTransfer(receiver Address, amount uint64) (*Transaction, error) {
+	return a.BuildTransferContext(context.Background(), receiver, amount)
+}
 
-	// Calculate and set ttl
-	tip, err := w.node.QueryTip()
+// BuildTransferContext is like BuildTransfer, with a context bounding the
+// queries to the node.
+func (a *Account) BuildTransferContext(ctx context.Context, receiver Address, amount uint64) (*Transaction, error) {
+	tip, err := a.wallet.node.QueryTip(ctx)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	protocol, err := a.wallet.node.QueryProtocolParams(ctx)
+	if err != nil {
+		return nil, err
+	}
+	utxos, err := a.availableUtxos(ctx, tip.Slot)
+	if err != nil {
+		return nil, err
 	}
-	builder.SetTtl(tip.Slot + 1200)
 
-	changeAddress := pickedUtxos[0].Address
-	err = builder.AddFee(changeAddress)
+	changeAddress, err := a.nextChangeAddress()
 	if err != nil {
-		return err
+		return nil, err
+	}
+	_, body, err := transferBody(receiver, amount, utxos, changeAddress, tip, protocol)
+	if err != nil {
+		a.releaseChangeAddress(changeAddress)
+		return nil, err
+	}
+	return &Transaction{Body: body}, nil
+}
+
+// transferBody returns the body paying the amount to the receiver out of the
+// utxos, along with the spent ones.
+func transferBody(receiver Address, amount uint64, utxos []Utxo, changeAddress Address, tip NodeTip, protocol ProtocolParams) ([]Utxo, TransactionBody, error) {
+	body := TransactionBody{
+		Outputs: []TransactionOutput{{Address: receiver.Bytes(), Amount: amount}},
+		Ttl:     tip.Slot + 1200,
+	}
+	return selectCoins(body, nil, utxos, changeAddress, protocol, nil)
+}
+
+// TransferAll sends the whole balance of the account, native assets included,
+// to the receiver address, minus the fee. It returns the id of the submitted
+// transaction.
+//
+// All the outputs of the account are spent by a single transaction, so it
+// fails with MaxTxSizeExceededError, before signing, when they are too many
+// for the transaction size limit. They must then be drained in several
+// transfers.
+func (a *Account) TransferAll(receiver Address) (TransactionID, error) {
+	return a.TransferAllContext(context.Background(), receiver)
+}
+
+// TransferAllContext is like TransferAll, with a context bounding the queries
+// to the node, the signature and the submission.
+func (a *Account) TransferAllContext(ctx context.Context, receiver Address) (TransactionID, error) {
+	return a.submit(ctx, func(utxos []Utxo, tip NodeTip) (*Transaction, error) {
+		if len(utxos) == 0 {
+			return nil, fmt.Errorf("no balance to transfer")
+		}
+		protocol, err := a.wallet.node.QueryProtocolParams(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		body := TransactionBody{Ttl: tip.Slot + 1200}
+		balance := Value{}
+		for _, utxo := range utxos {
+			body.Inputs = append(body.Inputs, TransactionInput{ID: utxo.TxId.Bytes(), Index: utxo.Index})
+			balance = balance.Add(Value{Coin: utxo.Amount, Assets: utxo.Assets})
+		}
+		output := TransactionOutput{Address: receiver.Bytes(), Amount: balance.Coin, Assets: balance.Assets}
+		body.Outputs = []TransactionOutput{output}
+
+		// The fee depends on the size of the output amount and of the fee
+		// itself, so it's computed again until it settles. Should it keep
+		// alternating across an encoding size boundary, the larger fee is
+		// kept, as it's enough for the smaller amount.
+		witnesses := witnessCount(utxos)
+		for i := 0; ; i++ {
+			fee := body.calculateMinFee(protocol, TransactionWitnessSet{}, witnesses, nil)
+			if fee > balance.Coin {
+				return nil, fmt.Errorf("not enough balance to pay the fee, %v > %v", fee, balance.Coin)
+			}
+			if fee == body.Fee || (i >= transferAllFeeRounds && fee < body.Fee) {
+				break
+			}
+			body.Fee = fee
+			body.Outputs[0].Amount = balance.Coin - fee
+		}
+		if min := minUtxoValue(body.Outputs[0], protocol); body.Outputs[0].Amount < min {
+			return nil, &OutputTooSmallError{Index: 0, Amount: body.Outputs[0].Amount, MinAmount: min}
+		}
+		size := uint64(len(body.estimatedTx(TransactionWitnessSet{}, witnesses, nil).Bytes()))
+		if protocol.MaxTxSize > 0 && size > protocol.MaxTxSize {
+			return nil, &MaxTxSizeExceededError{Size: size, MaxSize: protocol.MaxTxSize}
+		}
+		return a.sign(ctx, body, nil, utxos, protocol)
+	})
+}
+
+// sign builds the transaction of the body and the metadata, signed with the
+// keys of the spent utxos by the wallet's signer, or the account's keys if
+// none is set. The signing timeout applies on top of the context.
+func (a *Account) sign(ctx context.Context, body TransactionBody, metadata transactionMetadata, spent []Utxo, protocol ProtocolParams) (*Transaction, error) {
+	signer := a.wallet.signer
+	if signer == nil {
+		if a.WatchOnly() {
+			return nil, ErrWatchOnly
+		}
+		signer = NewKeySigner(a.keys()...)
+	}
+	a.keysMu.Lock()
+	external, internal := a.verificationKeys()
+	a.keysMu.Unlock()
+	stakeXvk := a.stakeVerificationKey()
+	keys := map[Address]crypto.ExtendedVerificationKey{}
+	for _, xvk := range append(external, internal...) {
+		keys[a.address(xvk, stakeXvk)] = xvk
+		keys[NewEnterpriseAddress(xvk, a.wallet.network)] = xvk
+	}
+
+	builder := NewTxBuilder(protocol)
+	builder.SetSigner(signer)
+	for _, utxo := range spent {
+		xvk, ok := keys[utxo.Address]
+		if !ok {
+			return nil, fmt.Errorf("missing key of address %v", utxo.Address)
+		}
+		builder.AddInput(xvk, utxo.TxId, utxo.Index, utxo.Amount)
+	}
+	builder.outputs = body.Outputs
+	for label, value := range metadata {
+		builder.AddMetadata(label, value)
+	}
+	builder.SetTtl(body.Ttl)
+	builder.SetFee(body.Fee)
+	timeout := a.wallet.signingTimeout
+	if timeout <= 0 {
+		timeout = defaultSigningTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	tx, err := builder.BuildContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
+// submit builds a transaction from the account's available outputs and
+// submits it. The inputs of the transaction are reserved until it's
+// confirmed, so that concurrent transfers don't spend them.
+func (a *Account) submit(ctx context.Context, build func(utxos []Utxo, tip NodeTip) (*Transaction, error)) (TransactionID, error) {
+	for attempt := 1; ; attempt++ {
+		tip, err := a.wallet.node.QueryTip(ctx)
+		if err != nil {
+			return "", err
+		}
+		utxos, err := a.availableUtxos(ctx, tip.Slot)
+		if err != nil {
+			return "", err
+		}
+
+		tx, err := build(utxos, tip)
+		if err != nil {
+			return "", err
+		}
+		if a.wallet.locker == nil {
+			return a.wallet.node.SubmitTx(ctx, *tx)
+		}
+
+		err = a.wallet.locker.Reserve(tx, a.change(tx))
+		if errors.Is(err, ErrUtxoLocked) && attempt < transferAttempts {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		txId, err := a.wallet.node.SubmitTx(ctx, *tx)
+		if err != nil {
+			a.wallet.locker.Release(tx.ID())
+			return "", err
+		}
+		return txId, nil
+	}
+}
+
+// change returns the outputs of the transaction paid to the account.
+func (a *Account) change(tx *Transaction) []Utxo {
+	addresses := map[string]Address{}
+	for _, addr := range a.allAddresses() {
+		addresses[string(addr.Bytes())] = addr
 	}
-	for _, key := range keys {
-		builder.Sign(key)
+	txId := tx.ID()
+	change := []Utxo{}
+	for i, output := range tx.Body.Outputs {
+		if addr, ok := addresses[string(output.Address)]; ok {
+			change = append(change, Utxo{
+				Address: addr,
+				TxId:    txId,
+				Index:   uint64(i),
+				Amount:  output.Amount,
+				Assets:  output.Assets,
+			})
+		}
 	}
-	tx := builder.Build()
-	return w.node.SubmitTx(tx)
+	return change
+}
+
+// Balance returns the total lovelace amount of the account.
+func (a *Account) Balance() (uint64, error) {
+	return a.BalanceContext(context.Background())
 }
 
-// Balance returns the total lovelace amount of the wallet.
-func (w *Wallet) Balance() (uint64, error) {
+// BalanceContext is like Balance, with a context bounding the queries to the
+// node.
+func (a *Account) BalanceContext(ctx context.Context) (uint64, error) {
 	var balance uint64
-	utxos, err := w.findUtxos()
+	utxos, err := a.findUtxos(ctx)
 	if err != nil {
-		return 0, nil
+		return 0, err
 	}
 	for _, utxo := range utxos {
 		balance += utxo.Amount
@@ -117,11 +475,66 @@ func (w *Wallet) Balance() (uint64, error) {
 	return balance, nil
 }
 
-func (w *Wallet) findUtxos() ([]Utxo, error) {
-	addresses := w.Addresses()
+// History returns the transactions of the wallet, the most recent first. It
+// returns ErrNotIndexed if no WalletIndexer tracks the wallet.
+func (w *Wallet) History() ([]TxRecord, error) {
+	if w.indexer == nil {
+		return nil, ErrNotIndexed
+	}
+	return w.indexer.History(), nil
+}
+
+func (a *Account) findUtxos(ctx context.Context) ([]Utxo, error) {
+	return a.wallet.utxosAt(ctx, a.allAddresses())
+}
+
+// availableUtxos returns the outputs of the account not reserved by pending
+// transactions at the slot. The wallet's locker is shared by its accounts, so
+// it's given the outputs of the whole wallet, lest it takes the reservations
+// of the other accounts for confirmed ones.
+func (a *Account) availableUtxos(ctx context.Context, slot uint64) ([]Utxo, error) {
+	if a.wallet.locker == nil {
+		return a.findUtxos(ctx)
+	}
+	utxos, err := a.wallet.utxosAt(ctx, a.wallet.allAddresses())
+	if err != nil {
+		return nil, err
+	}
+	if utxos, err = a.wallet.locker.Available(utxos, slot); err != nil {
+		return nil, err
+	}
+	owned := map[Address]bool{}
+	for _, addr := range a.allAddresses() {
+		owned[addr] = true
+	}
+	available := []Utxo{}
+	for _, utxo := range utxos {
+		if owned[utxo.Address] {
+			available = append(available, utxo)
+		}
+	}
+	return available, nil
+}
+
+// utxosAt returns the unspent outputs of the addresses, from the wallet's
+// indexer if it has one.
+func (w *Wallet) utxosAt(ctx context.Context, addresses []Address) ([]Utxo, error) {
+	if w.indexer != nil {
+		owned := map[Address]bool{}
+		for _, addr := range addresses {
+			owned[addr] = true
+		}
+		utxos := []Utxo{}
+		for _, utxo := range w.indexer.Utxos() {
+			if owned[utxo.Address] {
+				utxos = append(utxos, utxo)
+			}
+		}
+		return utxos, nil
+	}
 	walletUtxos := []Utxo{}
 	for _, addr := range addresses {
-		addrUtxos, err := w.node.QueryUtxos(addr)
+		addrUtxos, err := w.node.QueryUtxos(ctx, addr)
 		if err != nil {
 			return nil, err
 		}
@@ -130,23 +543,229 @@ func (w *Wallet) findUtxos() ([]Utxo, error) {
 	return walletUtxos, nil
 }
 
-// AddAddress generates a new payment address and adds it to the wallet.
-func (w *Wallet) AddAddress() Address {
-	index := uint32(len(w.skeys))
-	newKey := crypto.DeriveSigningKey(w.rootKey, index)
-	w.skeys = append(w.skeys, newKey)
-	return NewEnterpriseAddress(newKey.ExtendedVerificationKey(), w.network)
+// AddAddress generates a new payment address and adds it to the account.
+func (a *Account) AddAddress() Address {
+	a.keysMu.Lock()
+	defer a.keysMu.Unlock()
+	return a.address(a.appendKey(externalChainIndex), a.stakeVerificationKey())
+}
+
+// Addresses returns all account's receive addresses, on the external chain.
+func (a *Account) Addresses() []Address {
+	a.keysMu.Lock()
+	defer a.keysMu.Unlock()
+	external, _ := a.verificationKeys()
+	return a.addresses(external)
 }
 
-// Addresses returns all wallet's addresss.
-func (w *Wallet) Addresses() []Address {
-	addresses := make([]Address, len(w.skeys))
-	for i, key := range w.skeys {
-		addresses[i] = NewEnterpriseAddress(key.ExtendedVerificationKey(), w.network)
+// ChangeAddresses returns the account's change addresses, on the internal
+// chain.
+func (a *Account) ChangeAddresses() []Address {
+	a.keysMu.Lock()
+	defer a.keysMu.Unlock()
+	_, internal := a.verificationKeys()
+	return a.addresses(internal)
+}
+
+// allAddresses returns the receive and change addresses of the account,
+// along with the enterprise addresses of their keys, which older versions
+// handed out and may still hold funds.
+func (a *Account) allAddresses() []Address {
+	a.keysMu.Lock()
+	defer a.keysMu.Unlock()
+	external, internal := a.verificationKeys()
+	keys := append(append([]crypto.ExtendedVerificationKey{}, external...), internal...)
+	addresses := a.addresses(keys)
+	if a.stakeVerificationKey() != nil {
+		for _, key := range keys {
+			addresses = append(addresses, NewEnterpriseAddress(key, a.wallet.network))
+		}
+	}
+	return addresses
+}
+
+func (a *Account) addresses(keys []crypto.ExtendedVerificationKey) []Address {
+	stakeXvk := a.stakeVerificationKey()
+	addresses := make([]Address, len(keys))
+	for i, key := range keys {
+		addresses[i] = a.address(key, stakeXvk)
 	}
 	return addresses
 }
 
+// address returns the base address of the payment key, staked with the
+// account's stake key, or its enterprise address if the account has none.
+func (a *Account) address(xvk, stakeXvk crypto.ExtendedVerificationKey) Address {
+	if stakeXvk == nil {
+		return NewEnterpriseAddress(xvk, a.wallet.network)
+	}
+	return NewBaseAddress(xvk, stakeXvk, a.wallet.network)
+}
+
+// stakeVerificationKey returns the verification key of the account's stake
+// key, or nil for accounts saved before it was derived.
+func (a *Account) stakeVerificationKey() crypto.ExtendedVerificationKey {
+	if a.WatchOnly() {
+		stakeXvk, err := deriveAddressKey(a.accountXvk, stakingChainIndex, 0)
+		if err != nil {
+			return nil
+		}
+		return stakeXvk
+	}
+	if len(a.stakeKey) == 0 {
+		return nil
+	}
+	return a.stakeKey.ExtendedVerificationKey()
+}
+
+// WatchOnly returns whether the account holds only its account verification
+// key. It tracks its addresses and builds unsigned transactions, but can't
+// sign them.
+func (a *Account) WatchOnly() bool {
+	return len(a.accountXvk) > 0
+}
+
+// verificationKeys returns the verification keys of the receive and change
+// addresses. The caller holds keysMu.
+func (a *Account) verificationKeys() (external, internal []crypto.ExtendedVerificationKey) {
+	if a.WatchOnly() {
+		return a.xvkeys, a.changeXvkeys
+	}
+	external = make([]crypto.ExtendedVerificationKey, len(a.skeys))
+	for i, key := range a.skeys {
+		external[i] = key.ExtendedVerificationKey()
+	}
+	internal = make([]crypto.ExtendedVerificationKey, len(a.changeKeys))
+	for i, key := range a.changeKeys {
+		internal[i] = key.ExtendedVerificationKey()
+	}
+	return external, internal
+}
+
+// addressKey returns the verification key of the address of the chain at the
+// index.
+func (a *Account) addressKey(chain, index uint32) (crypto.ExtendedVerificationKey, error) {
+	if a.WatchOnly() {
+		return deriveAddressKey(a.accountXvk, chain, index)
+	}
+	chainKey := a.rootKey
+	if chain == internalChainIndex {
+		chainKey = a.internalKey
+	}
+	key := crypto.DeriveSigningKey(chainKey, index)
+	return key.ExtendedVerificationKey(), nil
+}
+
+// chainLength returns how many addresses of the chain the account holds. The
+// caller holds keysMu.
+func (a *Account) chainLength(chain uint32) int {
+	external, internal := len(a.skeys), len(a.changeKeys)
+	if a.WatchOnly() {
+		external, internal = len(a.xvkeys), len(a.changeXvkeys)
+	}
+	if chain == internalChainIndex {
+		return internal
+	}
+	return external
+}
+
+// appendKey derives the key of the next address of the chain, adds it to the
+// account and returns its verification key. The caller holds keysMu.
+func (a *Account) appendKey(chain uint32) crypto.ExtendedVerificationKey {
+	index := uint32(a.chainLength(chain))
+	if a.WatchOnly() {
+		xvk, err := deriveAddressKey(a.accountXvk, chain, index)
+		if err != nil {
+			// The account key was checked when deriving the first address
+			panic(err)
+		}
+		if chain == internalChainIndex {
+			a.changeXvkeys = append(a.changeXvkeys, xvk)
+		} else {
+			a.xvkeys = append(a.xvkeys, xvk)
+		}
+		return xvk
+	}
+	if chain == internalChainIndex {
+		key := crypto.DeriveSigningKey(a.internalKey, index)
+		a.changeKeys = append(a.changeKeys, key)
+		return key.ExtendedVerificationKey()
+	}
+	key := crypto.DeriveSigningKey(a.rootKey, index)
+	a.skeys = append(a.skeys, key)
+	return key.ExtendedVerificationKey()
+}
+
+// keys returns the signing keys of the receive and change addresses.
+func (a *Account) keys() []crypto.ExtendedSigningKey {
+	a.keysMu.Lock()
+	defer a.keysMu.Unlock()
+	return append(append([]crypto.ExtendedSigningKey{}, a.skeys...), a.changeKeys...)
+}
+
+// nextChangeAddress derives a new address on the internal chain to receive
+// the change of a transaction, saving the wallet if it belongs to a Client.
+// Accounts without an internal chain key receive their change on their first
+// address.
+func (a *Account) nextChangeAddress() (Address, error) {
+	var addr Address
+	err := a.wallet.update(func() {
+		a.keysMu.Lock()
+		defer a.keysMu.Unlock()
+		if len(a.internalKey) == 0 && !a.WatchOnly() {
+			addr = a.address(a.skeys[0].ExtendedVerificationKey(), a.stakeVerificationKey())
+			return
+		}
+		addr = a.address(a.appendKey(internalChainIndex), a.stakeVerificationKey())
+	})
+	if err != nil {
+		a.dropChangeAddress(addr)
+		return "", err
+	}
+	return addr, nil
+}
+
+// StakeAddress returns the reward address of the account's stake key.
+func (a *Account) StakeAddress() (Address, error) {
+	if a.WatchOnly() {
+		stakeXvk, err := deriveAddressKey(a.accountXvk, stakingChainIndex, 0)
+		if err != nil {
+			return "", err
+		}
+		return NewStakeAddress(stakeXvk, a.wallet.network), nil
+	}
+	if len(a.stakeKey) == 0 {
+		return "", ErrKeyNotDerived
+	}
+	return NewStakeAddress(a.stakeKey.ExtendedVerificationKey(), a.wallet.network), nil
+}
+
+// releaseChangeAddress drops the change address of a failed transaction if
+// it's the last one derived, so that the internal chain has no gaps. Failing
+// to save the wallet only leaves an unused change address, so it's ignored.
+func (a *Account) releaseChangeAddress(addr Address) {
+	_ = a.wallet.update(func() {
+		a.dropChangeAddress(addr)
+	})
+}
+
+// dropChangeAddress drops the change address if it's the last one derived.
+func (a *Account) dropChangeAddress(addr Address) {
+	a.keysMu.Lock()
+	defer a.keysMu.Unlock()
+	last := a.chainLength(internalChainIndex) - 1
+	if last < 0 {
+		return
+	}
+	if a.WatchOnly() {
+		if a.address(a.changeXvkeys[last], a.stakeVerificationKey()) == addr {
+			a.changeXvkeys = a.changeXvkeys[:last]
+		}
+	} else if a.address(a.changeKeys[last].ExtendedVerificationKey(), a.stakeVerificationKey()) == addr {
+		a.changeKeys = a.changeKeys[:last]
+	}
+}
+
 func newWalletID() string {
 	id, _ := gonanoid.Generate(walleIDAlphabet, 10)
 	return "wallet_" + id
@@ -156,28 +775,106 @@ func newWallet(name, password string, entropy []byte) *Wallet {
 	wallet := &Wallet{Name: name, ID: newWalletID()}
 	rootKey := crypto.NewExtendedSigningKey(entropy, password)
 	purposeKey := crypto.DeriveSigningKey(rootKey, purposeIndex)
-	coinKey := crypto.DeriveSigningKey(purposeKey, coinTypeIndex)
-	accountKey := crypto.DeriveSigningKey(coinKey, accountIndex)
-	chainKey := crypto.DeriveSigningKey(accountKey, externalChainIndex)
-	addr0Key := crypto.DeriveSigningKey(chainKey, 0)
-	wallet.rootKey = chainKey
-	wallet.skeys = []crypto.ExtendedSigningKey{addr0Key}
+	wallet.coinKey = crypto.DeriveSigningKey(purposeKey, coinTypeIndex)
+	wallet.Account = newAccount(wallet, wallet.coinKey, 0)
+	wallet.accounts = []*Account{wallet.Account}
 	return wallet
 }
 
+// newAccount derives the account of the given index, with its first address.
+func newAccount(wallet *Wallet, coinKey crypto.ExtendedSigningKey, index uint32) *Account {
+	accountKey := crypto.DeriveSigningKey(coinKey, accountIndex+index)
+	chainKey := crypto.DeriveSigningKey(accountKey, externalChainIndex)
+	stakeChainKey := crypto.DeriveSigningKey(accountKey, stakingChainIndex)
+	return &Account{
+		Index:       index,
+		wallet:      wallet,
+		rootKey:     chainKey,
+		skeys:       []crypto.ExtendedSigningKey{crypto.DeriveSigningKey(chainKey, 0)},
+		internalKey: crypto.DeriveSigningKey(accountKey, internalChainIndex),
+		stakeKey:    crypto.DeriveSigningKey(stakeChainKey, 0),
+	}
+}
+
+// newWatchOnlyWallet returns a wallet with the watch-only account of the
+// account verification key, with its first address.
+func newWatchOnlyWallet(name string, accountXvk crypto.ExtendedVerificationKey) (*Wallet, error) {
+	if len(accountXvk) != 64 {
+		return nil, fmt.Errorf("invalid account verification key length %v", len(accountXvk))
+	}
+	addr0Key, err := deriveAddressKey(accountXvk, externalChainIndex, 0)
+	if err != nil {
+		return nil, err
+	}
+	wallet := &Wallet{Name: name, ID: newWalletID()}
+	wallet.Account = &Account{
+		wallet:     wallet,
+		accountXvk: accountXvk,
+		xvkeys:     []crypto.ExtendedVerificationKey{addr0Key},
+	}
+	wallet.accounts = []*Account{wallet.Account}
+	return wallet, nil
+}
+
+// deriveAddressKey derives the verification key of the address of the chain
+// at the index from the account verification key.
+func deriveAddressKey(accountXvk crypto.ExtendedVerificationKey, chain, index uint32) (crypto.ExtendedVerificationKey, error) {
+	chainXvk, err := crypto.DeriveVerificationKey(accountXvk, chain)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.DeriveVerificationKey(chainXvk, index)
+}
+
 type walletDump struct {
-	ID      string
-	Name    string
-	Keys    []crypto.ExtendedSigningKey
-	RootKey crypto.ExtendedSigningKey
+	ID       string
+	Name     string
+	CoinKey  crypto.ExtendedSigningKey `json:",omitempty"`
+	Accounts []accountDump             `json:",omitempty"`
+
+	// Keys of the single account of wallets saved before accounts were
+	// introduced
+	Keys        []crypto.ExtendedSigningKey `json:",omitempty"`
+	RootKey     crypto.ExtendedSigningKey   `json:",omitempty"`
+	InternalKey crypto.ExtendedSigningKey   `json:",omitempty"`
+	ChangeKeys  []crypto.ExtendedSigningKey `json:",omitempty"`
+}
+
+type accountDump struct {
+	Index       uint32
+	Keys        []crypto.ExtendedSigningKey `json:",omitempty"`
+	RootKey     crypto.ExtendedSigningKey   `json:",omitempty"`
+	InternalKey crypto.ExtendedSigningKey   `json:",omitempty"`
+	ChangeKeys  []crypto.ExtendedSigningKey `json:",omitempty"`
+	StakeKey    crypto.ExtendedSigningKey   `json:",omitempty"`
+
+	// Keys of watch-only accounts
+	AccountVerificationKey crypto.ExtendedVerificationKey   `json:",omitempty"`
+	VerificationKeys       []crypto.ExtendedVerificationKey `json:",omitempty"`
+	ChangeVerificationKeys []crypto.ExtendedVerificationKey `json:",omitempty"`
 }
 
 func (w *Wallet) marshal() ([]byte, error) {
 	wd := &walletDump{
 		ID:      w.ID,
 		Name:    w.Name,
-		Keys:    w.skeys,
-		RootKey: w.rootKey,
+		CoinKey: w.coinKey,
+	}
+	for _, account := range w.Accounts() {
+		account.keysMu.Lock()
+		wd.Accounts = append(wd.Accounts, accountDump{
+			Index:       account.Index,
+			Keys:        account.skeys,
+			RootKey:     account.rootKey,
+			InternalKey: account.internalKey,
+			ChangeKeys:  account.changeKeys,
+			StakeKey:    account.stakeKey,
+
+			AccountVerificationKey: account.accountXvk,
+			VerificationKeys:       account.xvkeys,
+			ChangeVerificationKeys: account.changeXvkeys,
+		})
+		account.keysMu.Unlock()
 	}
 	bytes, err := json.Marshal(wd)
 	if err != nil {
@@ -194,8 +891,32 @@ func (w *Wallet) unmarshal(bytes []byte) error {
 	}
 	w.ID = wd.ID
 	w.Name = wd.Name
-	w.skeys = wd.Keys
-	w.rootKey = wd.RootKey
+	w.coinKey = wd.CoinKey
+	if len(wd.Accounts) == 0 {
+		wd.Accounts = []accountDump{{
+			Keys:        wd.Keys,
+			RootKey:     wd.RootKey,
+			InternalKey: wd.InternalKey,
+			ChangeKeys:  wd.ChangeKeys,
+		}}
+	}
+	w.accounts = nil
+	for _, ad := range wd.Accounts {
+		w.accounts = append(w.accounts, &Account{
+			Index:       ad.Index,
+			wallet:      w,
+			skeys:       ad.Keys,
+			rootKey:     ad.RootKey,
+			internalKey: ad.InternalKey,
+			changeKeys:  ad.ChangeKeys,
+			stakeKey:    ad.StakeKey,
+
+			accountXvk:   ad.AccountVerificationKey,
+			xvkeys:       ad.VerificationKeys,
+			changeXvkeys: ad.ChangeVerificationKeys,
+		})
+	}
+	w.Account = w.accounts[0]
 	return nil
 }
 
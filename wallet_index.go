@@ -0,0 +1,278 @@
+package cardano
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+)
+
+// ErrNotIndexed is returned by Wallet.History when no WalletIndexer tracks
+// the wallet.
+var ErrNotIndexed = errors.New("wallet is not indexed")
+
+// indexerStabilityWindow is the number of slots after which a block can't be
+// rolled back anymore. Spent outputs are kept that long to be restored on a
+// rollback.
+const indexerStabilityWindow = 129600
+
+// TxDirection tells how a transaction moved funds of a wallet.
+type TxDirection int
+
+const (
+	// TxIncoming transactions spend no output of the wallet.
+	TxIncoming TxDirection = iota
+	// TxOutgoing transactions spend outputs of the wallet and pay to other
+	// addresses.
+	TxOutgoing
+	// TxSelf transactions only pay to the wallet from its own outputs.
+	TxSelf
+)
+
+func (d TxDirection) String() string {
+	switch d {
+	case TxIncoming:
+		return "incoming"
+	case TxOutgoing:
+		return "outgoing"
+	case TxSelf:
+		return "self"
+	default:
+		return "unknown"
+	}
+}
+
+// TxRecord is a transaction of a wallet's history.
+type TxRecord struct {
+	ID        TransactionID
+	Direction TxDirection
+	// Amount is the value received by the wallet if incoming, or paid to
+	// other addresses if outgoing, fees excluded. The assets an outgoing
+	// transaction receives in exchange are left out.
+	Amount        Value
+	Fee           uint64
+	Block         uint64
+	Slot          uint64
+	Confirmations uint64
+}
+
+// WalletIndexStore persists the state of WalletIndexers.
+type WalletIndexStore interface {
+	WalletIndex(walletID string) ([]byte, error)
+	SaveWalletIndex(walletID string, index []byte) error
+}
+
+// indexedUtxo is an output of the wallet, spent ones being kept until they
+// can't be rolled back.
+type indexedUtxo struct {
+	Utxo
+	Slot      uint64
+	Spent     bool
+	SpentSlot uint64
+}
+
+type walletIndexState struct {
+	Utxos    []indexedUtxo
+	Txs      []TxRecord
+	TipBlock uint64
+}
+
+// WalletIndexer tracks the outputs and transactions of a wallet from the
+// events of a ChainFollower, and serves the wallet's balance and history.
+type WalletIndexer struct {
+	wallet *Wallet
+	store  WalletIndexStore
+
+	mu       sync.Mutex
+	utxos    map[string]*indexedUtxo
+	txs      []TxRecord
+	tipBlock uint64
+}
+
+// NewWalletIndexer creates an indexer of the wallet persisting its state in
+// store. The wallet serves its balance and history from the indexer from
+// then on.
+func NewWalletIndexer(w *Wallet, store WalletIndexStore) (*WalletIndexer, error) {
+	ix := &WalletIndexer{wallet: w, store: store, utxos: map[string]*indexedUtxo{}}
+	data, err := store.WalletIndex(w.ID)
+	if err != nil {
+		return nil, err
+	}
+	if data != nil {
+		state := walletIndexState{}
+		if err := json.Unmarshal(data, &state); err != nil {
+			return nil, err
+		}
+		for i := range state.Utxos {
+			utxo := state.Utxos[i]
+			ix.utxos[utxoKey(utxo.TxId.Bytes(), utxo.Index)] = &utxo
+		}
+		ix.txs = state.Txs
+		ix.tipBlock = state.TipBlock
+	}
+	w.indexer = ix
+	return ix, nil
+}
+
+// HandleEvent applies a chain event to the index. It's meant to be the
+// handler of a ChainFollower.
+func (ix *WalletIndexer) HandleEvent(event ChainEvent) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	switch event.Type {
+	case RollForward:
+		ix.rollForward(event.Block)
+	case RollBackward:
+		ix.rollBackward(event.Point.Slot)
+	}
+	if event.TipBlock > ix.tipBlock || event.Type == RollBackward {
+		ix.tipBlock = event.TipBlock
+	}
+	return ix.save()
+}
+
+func (ix *WalletIndexer) rollForward(block *Block) {
+	addresses := map[string]Address{}
+	for _, addr := range ix.wallet.allAddresses() {
+		addresses[hex.EncodeToString(addr.Bytes())] = addr
+	}
+
+	for i, tx := range block.Transactions {
+		txId := block.TxIDs[i]
+		sent, received := Value{}, Value{}
+		spends, foreign := false, false
+
+		for _, input := range tx.Body.Inputs {
+			utxo, ok := ix.utxos[utxoKey(input.ID, input.Index)]
+			if !ok || utxo.Spent {
+				continue
+			}
+			utxo.Spent, utxo.SpentSlot = true, block.Point.Slot
+			sent = sent.Add(Value{Coin: utxo.Amount, Assets: utxo.Assets})
+			spends = true
+		}
+		for j, output := range tx.Body.Outputs {
+			addr, ok := addresses[hex.EncodeToString(output.Address)]
+			if !ok {
+				foreign = true
+				continue
+			}
+			ix.utxos[utxoKey(txId.Bytes(), uint64(j))] = &indexedUtxo{
+				Utxo: Utxo{
+					Address: addr,
+					TxId:    txId,
+					Index:   uint64(j),
+					Amount:  output.Amount,
+					Assets:  output.Assets,
+				},
+				Slot: block.Point.Slot,
+			}
+			received = received.Add(output.Value())
+		}
+
+		if !spends && received.Coin == 0 && received.Assets.IsEmpty() {
+			continue
+		}
+		record := TxRecord{ID: txId, Fee: tx.Body.Fee, Block: block.Number, Slot: block.Point.Slot}
+		switch {
+		case !spends:
+			record.Direction, record.Amount = TxIncoming, received
+		case !foreign:
+			record.Direction = TxSelf
+		default:
+			record.Direction, record.Amount = TxOutgoing, netPaid(sent, received, tx.Body.Fee)
+		}
+		ix.txs = append(ix.txs, record)
+	}
+
+	if block.Number > ix.tipBlock {
+		ix.tipBlock = block.Number
+	}
+	for key, utxo := range ix.utxos {
+		if utxo.Spent && utxo.SpentSlot+indexerStabilityWindow < block.Point.Slot {
+			delete(ix.utxos, key)
+		}
+	}
+}
+
+// netPaid returns the value of the spent outputs not paid back to the wallet
+// nor to the fee. The lovelace and each asset are netted separately, as a
+// swap pays some and receives others.
+func netPaid(sent, received Value, fee uint64) Value {
+	paid := Value{Assets: MultiAsset{}}
+	if sent.Coin > received.Coin+fee {
+		paid.Coin = sent.Coin - received.Coin - fee
+	}
+	for policyID, assets := range sent.Assets {
+		for assetName, quantity := range assets {
+			if back := received.Assets.Quantity(policyID, assetName); quantity > back {
+				paid.Assets.set(policyID, assetName, quantity-back)
+			}
+		}
+	}
+	return paid
+}
+
+// rollBackward reverts the blocks after the slot.
+func (ix *WalletIndexer) rollBackward(slot uint64) {
+	for key, utxo := range ix.utxos {
+		switch {
+		case utxo.Slot > slot:
+			delete(ix.utxos, key)
+		case utxo.Spent && utxo.SpentSlot > slot:
+			utxo.Spent, utxo.SpentSlot = false, 0
+		}
+	}
+	for len(ix.txs) > 0 && ix.txs[len(ix.txs)-1].Slot > slot {
+		ix.txs = ix.txs[:len(ix.txs)-1]
+	}
+}
+
+func (ix *WalletIndexer) save() error {
+	state := walletIndexState{Txs: ix.txs, TipBlock: ix.tipBlock}
+	for _, utxo := range ix.utxos {
+		state.Utxos = append(state.Utxos, *utxo)
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return ix.store.SaveWalletIndex(ix.wallet.ID, data)
+}
+
+// Utxos returns the unspent outputs of the wallet.
+func (ix *WalletIndexer) Utxos() []Utxo {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	utxos := []Utxo{}
+	for _, utxo := range ix.utxos {
+		if !utxo.Spent {
+			utxos = append(utxos, utxo.Utxo)
+		}
+	}
+	sort.Slice(utxos, func(i, j int) bool {
+		if utxos[i].TxId != utxos[j].TxId {
+			return utxos[i].TxId < utxos[j].TxId
+		}
+		return utxos[i].Index < utxos[j].Index
+	})
+	return utxos
+}
+
+// History returns the transactions of the wallet, the most recent first.
+func (ix *WalletIndexer) History() []TxRecord {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	history := make([]TxRecord, len(ix.txs))
+	for i, record := range ix.txs {
+		if ix.tipBlock >= record.Block {
+			record.Confirmations = ix.tipBlock - record.Block + 1
+		}
+		history[len(ix.txs)-1-i] = record
+	}
+	return history
+}
@@ -0,0 +1,145 @@
+package cardano
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func testIndexBlock(number, slot uint64, txs ...Transaction) ChainEvent {
+	block := &Block{Number: number, Point: testChainPoint(slot, 0), Transactions: txs}
+	for i := range txs {
+		block.TxIDs = append(block.TxIDs, TransactionID(fmt.Sprintf("%062x%02x", slot, i)))
+	}
+	return ChainEvent{Type: RollForward, Block: block, Point: block.Point, TipBlock: number + 1}
+}
+
+func TestWalletIndexer(t *testing.T) {
+	w := newWallet("test", "", make([]byte, 20))
+	w.SetNetwork(Testnet)
+	walletAddr := w.Addresses()[0]
+	foreignAddr := make([]byte, 29)
+	foreignAddr[0] = 0x60
+
+	store := &memoryWalletIndex{}
+	ix, err := NewWalletIndexer(w, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Receive 5 ADA then send 2 ADA from it
+	incoming := Transaction{Body: TransactionBody{
+		Inputs:  []TransactionInput{{ID: make([]byte, 32)}},
+		Outputs: []TransactionOutput{{Address: walletAddr.Bytes(), Amount: 5000000}, {Address: foreignAddr, Amount: 1000000}},
+		Fee:     170000,
+	}}
+	events := []ChainEvent{testIndexBlock(1, 10, incoming)}
+	outgoing := Transaction{Body: TransactionBody{
+		Inputs:  []TransactionInput{{ID: events[0].Block.TxIDs[0].Bytes(), Index: 0}},
+		Outputs: []TransactionOutput{{Address: foreignAddr, Amount: 2000000}, {Address: walletAddr.Bytes(), Amount: 2800000}},
+		Fee:     200000,
+	}}
+	events = append(events, testIndexBlock(2, 20, outgoing))
+	for _, event := range events {
+		if err := ix.HandleEvent(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got, err := w.Balance(); err != nil || got != 2800000 {
+		t.Errorf("got balance %v want %v", got, 2800000)
+	}
+	history, err := w.History()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []TxRecord{
+		{ID: events[1].Block.TxIDs[0], Direction: TxOutgoing, Amount: Value{Coin: 2000000}, Fee: 200000, Block: 2, Slot: 20, Confirmations: 2},
+		{ID: events[0].Block.TxIDs[0], Direction: TxIncoming, Amount: Value{Coin: 5000000}, Fee: 170000, Block: 1, Slot: 10, Confirmations: 3},
+	}
+	if len(history) != len(want) {
+		t.Fatalf("got history %+v want %+v", history, want)
+	}
+	for i := range want {
+		if got := history[i]; got.ID != want[i].ID || got.Direction != want[i].Direction || !got.Amount.Equal(want[i].Amount) ||
+			got.Fee != want[i].Fee || got.Block != want[i].Block || got.Confirmations != want[i].Confirmations {
+			t.Errorf("got record %+v want %+v", got, want[i])
+		}
+	}
+
+	// A restarted indexer restores its state, and reverts the second block
+	// on a rollback
+	id := w.ID
+	w = newWallet("test", "", make([]byte, 20))
+	w.ID = id
+	w.SetNetwork(Testnet)
+	ix, err = NewWalletIndexer(w, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := w.Balance(); got != 2800000 {
+		t.Errorf("got restored balance %v want %v", got, 2800000)
+	}
+	if err := ix.HandleEvent(ChainEvent{Type: RollBackward, Point: events[0].Point, TipBlock: 1}); err != nil {
+		t.Fatal(err)
+	}
+	utxos := ix.Utxos()
+	if len(utxos) != 1 || utxos[0].TxId != events[0].Block.TxIDs[0] || utxos[0].Amount != 5000000 {
+		t.Errorf("got utxos %+v want the received output", utxos)
+	}
+	if history, _ := w.History(); len(history) != 1 || history[0].Confirmations != 1 {
+		t.Errorf("got history %+v want the incoming transaction", history)
+	}
+
+	// Spending to the wallet only
+	self := Transaction{Body: TransactionBody{
+		Inputs:  []TransactionInput{{ID: events[0].Block.TxIDs[0].Bytes(), Index: 0}},
+		Outputs: []TransactionOutput{{Address: walletAddr.Bytes(), Amount: 4800000}},
+		Fee:     200000,
+	}}
+	if err := ix.HandleEvent(testIndexBlock(2, 21, self)); err != nil {
+		t.Fatal(err)
+	}
+	if history, _ := w.History(); history[0].Direction != TxSelf || !history[0].Amount.Equal(Value{}) {
+		t.Errorf("got record %+v want self transaction", history[0])
+	}
+
+	// Swapping lovelace for a token of a foreign input
+	token := NewMultiAsset("00", "74657374", 10)
+	swap := Transaction{Body: TransactionBody{
+		Inputs: []TransactionInput{{ID: TransactionID(fmt.Sprintf("%062x%02x", 21, 0)).Bytes(), Index: 0}, {ID: make([]byte, 32), Index: 1}},
+		Outputs: []TransactionOutput{
+			{Address: foreignAddr, Amount: 3100000},
+			{Address: walletAddr.Bytes(), Amount: 1500000, Assets: token},
+		},
+		Fee: 200000,
+	}}
+	if err := ix.HandleEvent(testIndexBlock(3, 30, swap)); err != nil {
+		t.Fatal(err)
+	}
+	if history, _ := w.History(); history[0].Direction != TxOutgoing || !history[0].Amount.Equal(Value{Coin: 3100000}) {
+		t.Errorf("got record %+v want 3100000 lovelace paid", history[0])
+	}
+}
+
+// memoryWalletIndex is a WalletIndexStore that keeps the indexes in memory.
+type memoryWalletIndex struct {
+	mu      sync.Mutex
+	indexes map[string][]byte
+}
+
+func (m *memoryWalletIndex) WalletIndex(walletID string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.indexes[walletID], nil
+}
+
+func (m *memoryWalletIndex) SaveWalletIndex(walletID string, index []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.indexes == nil {
+		m.indexes = map[string][]byte{}
+	}
+	m.indexes[walletID] = index
+	return nil
+}
@@ -1,9 +1,15 @@
 package cardano
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/echovl/bech32"
+	"github.com/qredo/cardano-go/crypto"
 	"github.com/tyler-smith/go-bip39"
 )
 
@@ -39,8 +45,13 @@ func TestGenerateAddress(t *testing.T) {
 			t.Errorf("invalid addrXvk1 :\ngot: %v\nwant: %v", addrXvk1, testVector.addrXvk1)
 		}
 
-		if paymentAddr1 != testVector.paymentAddr1 {
-			t.Errorf("invalid paymentAddr1:\ngot: %v\nwant: %v", paymentAddr1, testVector.paymentAddr1)
+		xvk1 := w.skeys[1].ExtendedVerificationKey()
+		if enterpriseAddr1 := NewEnterpriseAddress(xvk1, Testnet); enterpriseAddr1 != testVector.paymentAddr1 {
+			t.Errorf("invalid paymentAddr1:\ngot: %v\nwant: %v", enterpriseAddr1, testVector.paymentAddr1)
+		}
+
+		if want := NewBaseAddress(xvk1, w.stakeKey.ExtendedVerificationKey(), Testnet); paymentAddr1 != want {
+			t.Errorf("invalid address 1:\ngot: %v\nwant: %v", paymentAddr1, want)
 		}
 	}
 }
@@ -49,24 +60,48 @@ type MockNode struct {
 	utxos []Utxo
 }
 
-func (prov *MockNode) QueryUtxos(addr Address) ([]Utxo, error) {
-	return prov.utxos, nil
+func (prov *MockNode) QueryUtxos(ctx context.Context, addr Address) ([]Utxo, error) {
+	utxos := []Utxo{}
+	for _, utxo := range prov.utxos {
+		if utxo.Address == addr {
+			utxos = append(utxos, utxo)
+		}
+	}
+	return utxos, nil
 }
 
-func (prov *MockNode) QueryTip() (NodeTip, error) {
+func (prov *MockNode) QueryTip(ctx context.Context) (NodeTip, error) {
 	return NodeTip{}, nil
 }
 
-func (prov *MockNode) SubmitTx(tx Transaction) error {
-	return nil
+func (prov *MockNode) QueryProtocolParams(ctx context.Context) (ProtocolParams, error) {
+	return ShelleyProtocol, nil
+}
+
+func (prov *MockNode) QueryTxStatus(ctx context.Context, txId TransactionID) (TxStatus, error) {
+	return TxStatus{}, nil
+}
+
+func (prov *MockNode) QueryRewardAccount(ctx context.Context, stakeAddr Address) (RewardAccount, error) {
+	return RewardAccount{Address: stakeAddr}, nil
+}
+
+func (prov *MockNode) SubmitTx(ctx context.Context, tx Transaction) (TransactionID, error) {
+	return tx.ID(), nil
 }
 
 func TestWalletBalance(t *testing.T) {
-	client := NewClient(WithDB(&MockDB{}))
-	client.node = &MockNode{utxos: []Utxo{{Amount: 100}, {Amount: 33}}}
+	node := &MockNode{}
+	client := NewClient(WithDB(&MockDB{}), WithNode(node))
 	w, _, err := client.CreateWallet("test", "")
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
+	}
+	// Funds at the enterprise address of a key count towards the balance
+	legacyAddr := NewEnterpriseAddress(w.skeys[0].ExtendedVerificationKey(), w.network)
+	node.utxos = []Utxo{
+		{Address: w.Addresses()[0], Amount: 100},
+		{Address: legacyAddr, Amount: 33},
 	}
 
 	got, err := w.Balance()
@@ -84,3 +119,549 @@ func bech32From(hrp string, bytes []byte) string {
 	enc, _ := bech32.EncodeFromBase256(hrp, bytes)
 	return enc
 }
+
+func TestWalletTransferAll(t *testing.T) {
+	client := NewClient(WithDB(&MockDB{}))
+	defer client.Close()
+	w, _, err := client.CreateWallet("test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.SetNetwork(Testnet)
+	addr := w.Addresses()[0]
+	// Older versions handed out the enterprise address of the same key
+	legacyAddr := NewEnterpriseAddress(w.skeys[0].ExtendedVerificationKey(), Testnet)
+	emulator := NewEmulator(ShelleyProtocol, map[Address]uint64{addr: 5 * ShelleyProtocol.MinimumUtxoValue})
+	token := NewMultiAsset("00", "74657374", 10)
+	tokenUtxo := Utxo{Address: legacyAddr, TxId: testLockerUtxo(1, 0, 0).TxId, Amount: 2 * ShelleyProtocol.MinimumUtxoValue, Assets: token}
+	emulator.utxos[utxoKey(tokenUtxo.TxId.Bytes(), 0)] = tokenUtxo
+	w.node = emulator
+
+	receiverKey := crypto.NewExtendedSigningKey([]byte("receiver"), "")
+	receiver := NewEnterpriseAddress(receiverKey.ExtendedVerificationKey(), Testnet)
+	txId, err := w.TransferAll(receiver)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := emulator.Transaction(txId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Both outputs are locked by the same key
+	if got, want := len(tx.WitnessSet.VKeyWitnessSet), 1; got != want {
+		t.Errorf("got %v witnesses want %v", got, want)
+	}
+	if got, want := tx.Body.Fee, tx.Body.calculateMinFee(ShelleyProtocol, TransactionWitnessSet{}, len(tx.WitnessSet.VKeyWitnessSet), nil); got != want {
+		t.Errorf("got fee %v want %v", got, want)
+	}
+	utxos, err := emulator.QueryUtxos(context.Background(), receiver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Value{Coin: 7*ShelleyProtocol.MinimumUtxoValue - tx.Body.Fee, Assets: token}
+	if len(utxos) != 1 || !(Value{Coin: utxos[0].Amount, Assets: utxos[0].Assets}).Equal(want) {
+		t.Errorf("got receiver utxos %+v want %v", utxos, want)
+	}
+	if balance, err := w.Balance(); err != nil || balance != 0 {
+		t.Errorf("got balance %v, %v want 0", balance, err)
+	}
+}
+
+func TestWalletTransferAllMaxTxSize(t *testing.T) {
+	protocol := ShelleyProtocol
+	protocol.MaxTxSize = 1000
+	client := NewClient(WithDB(&MockDB{}))
+	defer client.Close()
+	w, _, err := client.CreateWallet("test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.SetNetwork(Testnet)
+	addr := w.Addresses()[0]
+	emulator := NewEmulator(protocol, nil)
+	for i := 0; i < 30; i++ {
+		utxo := testLockerUtxo(byte(i), 0, 2*protocol.MinimumUtxoValue)
+		utxo.Address = addr
+		emulator.utxos[utxoKey(utxo.TxId.Bytes(), 0)] = utxo
+	}
+	w.node = emulator
+	signer := &remoteSigner{keys: NewKeySigner()}
+	w.SetSigner(signer)
+
+	receiverKey := crypto.NewExtendedSigningKey([]byte("receiver"), "")
+	receiver := NewEnterpriseAddress(receiverKey.ExtendedVerificationKey(), Testnet)
+	var sizeErr *MaxTxSizeExceededError
+	if _, err := w.TransferAll(receiver); !errors.As(err, &sizeErr) {
+		t.Fatalf("got error %v want %T", err, sizeErr)
+	}
+	if signer.calls != 0 {
+		t.Errorf("got %v signatures want none", signer.calls)
+	}
+}
+
+func TestWalletTransferAllFee(t *testing.T) {
+	for _, balance := range []uint64{5 * ShelleyProtocol.MinimumUtxoValue, 1<<32 + 1000, 1<<32 + 1<<20} {
+		client := NewClient(WithDB(&MockDB{}))
+		w, _, err := client.CreateWallet("test", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.SetNetwork(Testnet)
+		emulator := NewEmulator(ShelleyProtocol, map[Address]uint64{w.Addresses()[0]: balance})
+		w.node = emulator
+
+		receiverKey := crypto.NewExtendedSigningKey([]byte("receiver"), "")
+		receiver := NewEnterpriseAddress(receiverKey.ExtendedVerificationKey(), Testnet)
+		txId, err := w.TransferAll(receiver)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx, err := emulator.Transaction(txId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := tx.Body.Fee, tx.Body.calculateMinFee(ShelleyProtocol, TransactionWitnessSet{}, len(tx.WitnessSet.VKeyWitnessSet), nil); got != want {
+			t.Errorf("balance %v: got fee %v want %v", balance, got, want)
+		}
+		if got, want := tx.Body.Outputs[0].Amount+tx.Body.Fee, balance; got != want {
+			t.Errorf("balance %v: got output and fee %v want %v", balance, got, want)
+		}
+		client.Close()
+	}
+}
+
+func TestWalletChangeAddresses(t *testing.T) {
+	client := NewClient(WithDB(&MockDB{}))
+	defer client.Close()
+	w, _, err := client.CreateWallet("test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.SetNetwork(Testnet)
+	emulator := NewEmulator(ShelleyProtocol, map[Address]uint64{w.Addresses()[0]: 100 * ShelleyProtocol.MinimumUtxoValue})
+	w.node = emulator
+
+	receiverKey := crypto.NewExtendedSigningKey([]byte("receiver"), "")
+	receiver := NewEnterpriseAddress(receiverKey.ExtendedVerificationKey(), Testnet)
+	for i := 0; i < 2; i++ {
+		txId, err := w.Transfer(receiver, 10*ShelleyProtocol.MinimumUtxoValue)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx, err := emulator.Transaction(txId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		changeAddresses := w.ChangeAddresses()
+		if got, want := len(changeAddresses), i+1; got != want {
+			t.Fatalf("got %v change addresses want %v", got, want)
+		}
+		if got, want := tx.Body.Outputs[1].Address, changeAddresses[i].Bytes(); !bytes.Equal(got, want) {
+			t.Errorf("got change output to %x want %x", got, want)
+		}
+	}
+	if balance, err := w.Balance(); err != nil || balance == 0 {
+		t.Errorf("got balance %v, %v want the change", balance, err)
+	}
+
+	// Change keys are kept by the wallet dump
+	data, err := w.marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := &Wallet{}
+	if err := restored.unmarshal(data); err != nil {
+		t.Fatal(err)
+	}
+	restored.SetNetwork(Testnet)
+	if got, want := restored.ChangeAddresses(), w.ChangeAddresses(); len(got) != 2 || got[1] != want[1] {
+		t.Errorf("got change addresses %v want %v", got, want)
+	}
+
+	// Wallets without an internal chain key keep their change on their first
+	// address
+	restored.internalKey = nil
+	if got, err := restored.nextChangeAddress(); err != nil || got != restored.Addresses()[0] {
+		t.Errorf("got change address %v, %v want %v", got, err, restored.Addresses()[0])
+	}
+}
+
+func TestWalletAccounts(t *testing.T) {
+	client := NewClient(WithDB(&MockDB{}))
+	defer client.Close()
+	w, _, err := client.CreateWallet("test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.SetNetwork(Testnet)
+	account, err := w.AddAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := account.Index, uint32(1); got != want {
+		t.Fatalf("got account %v want %v", got, want)
+	}
+	if got, err := w.AccountAt(1); err != nil || got != account {
+		t.Fatalf("got account %v, %v want %v", got, err, account)
+	}
+	if _, err := w.AccountAt(2); err == nil {
+		t.Error("got account 2 want error")
+	}
+	if w.Addresses()[0] == account.Addresses()[0] {
+		t.Errorf("got the same address %v for both accounts", account.Addresses()[0])
+	}
+	stake0, err := w.StakeAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stake1, err := account.StakeAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stake0 == stake1 {
+		t.Errorf("got the same stake address %v for both accounts", stake0)
+	}
+
+	emulator := NewEmulator(ShelleyProtocol, map[Address]uint64{
+		w.Addresses()[0]:       10 * ShelleyProtocol.MinimumUtxoValue,
+		account.Addresses()[0]: 20 * ShelleyProtocol.MinimumUtxoValue,
+	})
+	w.node = emulator
+	if balance, err := account.Balance(); err != nil || balance != 20*ShelleyProtocol.MinimumUtxoValue {
+		t.Errorf("got account balance %v, %v want %v", balance, err, 20*ShelleyProtocol.MinimumUtxoValue)
+	}
+
+	// Transfers only spend the outputs of their account
+	receiverKey := crypto.NewExtendedSigningKey([]byte("receiver"), "")
+	receiver := NewEnterpriseAddress(receiverKey.ExtendedVerificationKey(), Testnet)
+	if _, err := account.TransferAll(receiver); err != nil {
+		t.Fatal(err)
+	}
+	if balance, err := account.Balance(); err != nil || balance != 0 {
+		t.Errorf("got account balance %v, %v want 0", balance, err)
+	}
+	if balance, err := w.Balance(); err != nil || balance != 10*ShelleyProtocol.MinimumUtxoValue {
+		t.Errorf("got first account balance %v, %v want %v", balance, err, 10*ShelleyProtocol.MinimumUtxoValue)
+	}
+
+	// Accounts are kept by the wallet dump
+	data, err := w.marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := &Wallet{}
+	if err := restored.unmarshal(data); err != nil {
+		t.Fatal(err)
+	}
+	restored.SetNetwork(Testnet)
+	accounts := restored.Accounts()
+	if len(accounts) != 2 || accounts[1].Addresses()[0] != account.Addresses()[0] {
+		t.Fatalf("got accounts %v want 2", len(accounts))
+	}
+	if got, err := accounts[1].StakeAddress(); err != nil || got != stake1 {
+		t.Errorf("got stake address %v, %v want %v", got, err, stake1)
+	}
+	if got, err := restored.AddAccount(); err != nil || got.Index != 2 {
+		t.Errorf("got account %v, %v want 2", got, err)
+	}
+}
+
+// mempoolBackend is an Emulator keeping the submitted transactions pending.
+type mempoolBackend struct {
+	*Emulator
+	submitted []Transaction
+}
+
+func (m *mempoolBackend) SubmitTx(ctx context.Context, tx Transaction) (TransactionID, error) {
+	m.submitted = append(m.submitted, tx)
+	return tx.ID(), nil
+}
+
+// contextBackend is an Emulator failing the requests of done contexts.
+type contextBackend struct {
+	*Emulator
+}
+
+func (c contextBackend) QueryUtxos(ctx context.Context, addr Address) ([]Utxo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.Emulator.QueryUtxos(ctx, addr)
+}
+
+func (c contextBackend) QueryTip(ctx context.Context) (NodeTip, error) {
+	if err := ctx.Err(); err != nil {
+		return NodeTip{}, err
+	}
+	return c.Emulator.QueryTip(ctx)
+}
+
+func (c contextBackend) QueryProtocolParams(ctx context.Context) (ProtocolParams, error) {
+	if err := ctx.Err(); err != nil {
+		return ProtocolParams{}, err
+	}
+	return c.Emulator.QueryProtocolParams(ctx)
+}
+
+func TestWalletContext(t *testing.T) {
+	client := NewClient(WithDB(&MockDB{}))
+	defer client.Close()
+	w, _, err := client.CreateWallet("test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.SetNetwork(Testnet)
+	w.node = contextBackend{NewEmulator(ShelleyProtocol, map[Address]uint64{w.Addresses()[0]: 20 * ShelleyProtocol.MinimumUtxoValue})}
+	receiverKey := crypto.NewExtendedSigningKey([]byte("receiver"), "")
+	receiver := NewEnterpriseAddress(receiverKey.ExtendedVerificationKey(), Testnet)
+	amount := 5 * ShelleyProtocol.MinimumUtxoValue
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	calls := map[string]func() error{
+		"transfer": func() error {
+			_, err := w.TransferContext(ctx, receiver, amount)
+			return err
+		},
+		"build transfer": func() error {
+			_, err := w.BuildTransferContext(ctx, receiver, amount)
+			return err
+		},
+		"transfer all": func() error {
+			_, err := w.TransferAllContext(ctx, receiver)
+			return err
+		},
+		"transfer many": func() error {
+			_, err := w.TransferManyContext(ctx, []Payment{{Receiver: receiver, Amount: amount}})
+			return err
+		},
+		"balance": func() error {
+			_, err := w.BalanceContext(ctx)
+			return err
+		},
+	}
+	for name, call := range calls {
+		if err := call(); !errors.Is(err, context.Canceled) {
+			t.Errorf("%v: got error %v want %v", name, err, context.Canceled)
+		}
+	}
+
+	if _, err := w.Transfer(receiver, amount); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := w.Balance(); err != nil || got == 0 {
+		t.Errorf("got balance %v, %v want change", got, err)
+	}
+}
+
+func TestWalletAccountsReservations(t *testing.T) {
+	client := NewClient(WithDB(&MockDB{}))
+	defer client.Close()
+	w, _, err := client.CreateWallet("test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.SetNetwork(Testnet)
+	account, err := w.AddAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := &mempoolBackend{Emulator: NewEmulator(ShelleyProtocol, map[Address]uint64{
+		w.Addresses()[0]:       20 * ShelleyProtocol.MinimumUtxoValue,
+		account.Addresses()[0]: 20 * ShelleyProtocol.MinimumUtxoValue,
+	})}
+	w.node = node
+
+	receiverKey := crypto.NewExtendedSigningKey([]byte("receiver"), "")
+	receiver := NewEnterpriseAddress(receiverKey.ExtendedVerificationKey(), Testnet)
+	if _, err := w.Transfer(receiver, 5*ShelleyProtocol.MinimumUtxoValue); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := account.Transfer(receiver, 5*ShelleyProtocol.MinimumUtxoValue); err != nil {
+		t.Fatal(err)
+	}
+
+	// The pending transfer of the other account keeps the first account's
+	// output reserved
+	if _, err := w.Transfer(receiver, 5*ShelleyProtocol.MinimumUtxoValue); err == nil {
+		t.Errorf("got a transfer spending the reserved output, %v transactions submitted", len(node.submitted))
+	}
+	if got, want := len(node.submitted), 2; got != want {
+		t.Errorf("got %v transactions submitted want %v", got, want)
+	}
+}
+
+func TestWalletLegacyDump(t *testing.T) {
+	entropy, err := bip39.EntropyFromMnemonic(testVectors[0].mnemonic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := newWallet("test", "", entropy)
+	data, err := json.Marshal(&walletDump{ID: w.ID, Name: w.Name, Keys: w.skeys, RootKey: w.rootKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := &Wallet{}
+	if err := restored.unmarshal(data); err != nil {
+		t.Fatal(err)
+	}
+	// Accounts without a stake key keep their enterprise addresses
+	want := NewEnterpriseAddress(w.skeys[0].ExtendedVerificationKey(), w.network)
+	if got := restored.Addresses(); len(got) != 1 || got[0] != want {
+		t.Errorf("got addresses %v want [%v]", got, want)
+	}
+	if _, err := restored.AddAccount(); err != ErrKeyNotDerived {
+		t.Errorf("got error %v want %v", err, ErrKeyNotDerived)
+	}
+	if _, err := restored.StakeAddress(); err != ErrKeyNotDerived {
+		t.Errorf("got error %v want %v", err, ErrKeyNotDerived)
+	}
+}
+
+func TestWatchWallet(t *testing.T) {
+	entropy, err := bip39.EntropyFromMnemonic(testVectors[0].mnemonic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	full := newWallet("full", "", entropy)
+	full.SetNetwork(Testnet)
+	rootKey := crypto.NewExtendedSigningKey(entropy, "")
+	accountKey := crypto.DeriveSigningKey(crypto.DeriveSigningKey(crypto.DeriveSigningKey(rootKey, purposeIndex), coinTypeIndex), accountIndex)
+	accountXvk := bech32From("acct_xvk", accountKey.ExtendedVerificationKey())
+
+	internalKey := crypto.DeriveSigningKey(full.internalKey, 2)
+	emulator := NewEmulator(ShelleyProtocol, map[Address]uint64{
+		full.Addresses()[0]: 10 * ShelleyProtocol.MinimumUtxoValue,
+		NewEnterpriseAddress(internalKey.ExtendedVerificationKey(), Testnet): 5 * ShelleyProtocol.MinimumUtxoValue,
+	})
+	client := NewClient(WithDB(&MockDB{}), WithNode(emulator))
+	defer client.Close()
+
+	if _, err := client.WatchWallet("watch", bech32From("addr_test", accountKey.ExtendedVerificationKey())); err == nil {
+		t.Error("got wallet from an address prefix want error")
+	}
+	w, err := client.WatchWallet("watch", accountXvk, WithRestoreNetwork(Testnet))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !w.WatchOnly() {
+		t.Error("got a wallet with signing keys want watch-only")
+	}
+	if got, want := w.Addresses(), full.Addresses(); len(got) != 1 || got[0] != want[0] {
+		t.Errorf("got addresses %v want %v", got, want)
+	}
+	if got, want := len(w.ChangeAddresses()), 3; got != want {
+		t.Errorf("got %v change addresses want %v", got, want)
+	}
+	if got, want := w.AddAddress(), full.AddAddress(); got != want {
+		t.Errorf("got address %v want %v", got, want)
+	}
+	if got, err := w.StakeAddress(); err != nil {
+		t.Error(err)
+	} else if want, _ := full.StakeAddress(); got != want {
+		t.Errorf("got stake address %v want %v", got, want)
+	}
+	if balance, err := w.Balance(); err != nil || balance != 15*ShelleyProtocol.MinimumUtxoValue {
+		t.Errorf("got balance %v, %v want %v", balance, err, 15*ShelleyProtocol.MinimumUtxoValue)
+	}
+
+	receiverKey := crypto.NewExtendedSigningKey([]byte("receiver"), "")
+	receiver := NewEnterpriseAddress(receiverKey.ExtendedVerificationKey(), Testnet)
+	tx, err := w.BuildTransfer(receiver, 12*ShelleyProtocol.MinimumUtxoValue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tx.WitnessSet.VKeyWitnessSet) != 0 {
+		t.Errorf("got %v witnesses want an unsigned transaction", len(tx.WitnessSet.VKeyWitnessSet))
+	}
+	// Both inputs are spent from addresses of different keys
+	if got, want := tx.Body.Fee, tx.Body.calculateMinFee(ShelleyProtocol, TransactionWitnessSet{}, len(tx.Body.Inputs), nil); got != want {
+		t.Errorf("got fee %v want %v", got, want)
+	}
+	if got, want := tx.Body.Outputs[1].Address, w.ChangeAddresses()[3].Bytes(); !bytes.Equal(got, want) {
+		t.Errorf("got change output to %x want %x", got, want)
+	}
+
+	if _, err := w.Transfer(receiver, ShelleyProtocol.MinimumUtxoValue); err != ErrWatchOnly {
+		t.Errorf("got error %v want %v", err, ErrWatchOnly)
+	}
+	if got, want := len(w.ChangeAddresses()), 4; got != want {
+		t.Errorf("got %v change addresses after a failed transfer want %v", got, want)
+	}
+	if _, err := w.AddAccount(); err != ErrWatchOnly {
+		t.Errorf("got error %v want %v", err, ErrWatchOnly)
+	}
+
+	// Watch-only keys are kept by the wallet dump
+	data, err := w.marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := &Wallet{}
+	if err := restored.unmarshal(data); err != nil {
+		t.Fatal(err)
+	}
+	restored.SetNetwork(Testnet)
+	if got, want := restored.allAddresses(), w.allAddresses(); !restored.WatchOnly() || len(got) != 12 || got[5] != want[5] {
+		t.Errorf("got addresses %v want %v", got, want)
+	}
+}
+
+func TestWatchWalletSigner(t *testing.T) {
+	entropy, err := bip39.EntropyFromMnemonic(testVectors[0].mnemonic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	full := newWallet("full", "", entropy)
+	full.SetNetwork(Testnet)
+	full.AddAddress()
+	rootKey := crypto.NewExtendedSigningKey(entropy, "")
+	accountKey := crypto.DeriveSigningKey(crypto.DeriveSigningKey(crypto.DeriveSigningKey(rootKey, purposeIndex), coinTypeIndex), accountIndex)
+
+	emulator := NewEmulator(ShelleyProtocol, map[Address]uint64{
+		full.Addresses()[0]: 5 * ShelleyProtocol.MinimumUtxoValue,
+		full.Addresses()[1]: 5 * ShelleyProtocol.MinimumUtxoValue,
+	})
+	client := NewClient(WithDB(&MockDB{}), WithNode(emulator))
+	defer client.Close()
+	w, err := client.WatchWallet("watch", bech32From("acct_xvk", accountKey.ExtendedVerificationKey()), WithRestoreNetwork(Testnet))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The keys of the full wallet sign the transfers of the watch-only one
+	signer := &remoteSigner{keys: NewKeySigner(full.keys()...)}
+	w.SetSigner(signer)
+	receiverKey := crypto.NewExtendedSigningKey([]byte("receiver"), "")
+	receiver := NewEnterpriseAddress(receiverKey.ExtendedVerificationKey(), Testnet)
+	txId, err := w.Transfer(receiver, 8*ShelleyProtocol.MinimumUtxoValue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := emulator.Transaction(txId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(tx.WitnessSet.VKeyWitnessSet), 2; got != want {
+		t.Errorf("got %v witnesses want %v", got, want)
+	}
+	if got, want := signer.calls, 2; got != want {
+		t.Errorf("got %v signatures want %v", got, want)
+	}
+
+	// Signer errors fail the transfer
+	w.SetSigner(NewKeySigner())
+	if _, err := w.Transfer(receiver, ShelleyProtocol.MinimumUtxoValue); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("got error %v want %v", err, ErrUnknownKey)
+	}
+
+	// Signers not answering fail the transfer after the signing timeout
+	release := make(chan struct{})
+	defer close(release)
+	w.SetSigner(hangingSigner{release})
+	w.SetSigningTimeout(20 * time.Millisecond)
+	if _, err := w.Transfer(receiver, ShelleyProtocol.MinimumUtxoValue); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v want %v", err, context.DeadlineExceeded)
+	}
+}